@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"catRock/pkg/core"
 	"catRock/pkg/dsl"
 	"catRock/pkg/dsl/ast"
 	"catRock/pkg/score"
@@ -60,7 +61,7 @@ func runDebug(filename string, opts *debugOpts) error {
     // 1. 词法分析
     if opts.ShowTokens {
         yellow.Println("\n🔤 词法分析结果:")
-        lexer := dsl.NewLexer(string(content))
+        lexer := dsl.NewFileLexer(string(content), filename)
         for {
             token := lexer.NextToken()
             if token.Type == dsl.EOF {
@@ -72,7 +73,7 @@ func runDebug(filename string, opts *debugOpts) error {
     }
     
     // 2. 语法分析
-    lexer := dsl.NewLexer(string(content))
+    lexer := dsl.NewFileLexer(string(content), filename)
     parser := dsl.NewParser(lexer)
     ast := parser.ParseScore()
     
@@ -110,7 +111,7 @@ func runDebug(filename string, opts *debugOpts) error {
         }
         
         yellow.Println("\n🎹 生成的事件:")
-        showDetailedEvents(events)
+        showDetailedEvents(events, scoreObj.Time)
     }
     
     return nil
@@ -125,7 +126,7 @@ func printDetailedAST(scoreNode *ast.ScoreNode) {
     // 直接使用详细字符串方法
     fmt.Print(scoreNode.DetailedString("   "))
 }
-func showDetailedEvents(events []score.Event) {
+func showDetailedEvents(events []score.Event, meter core.TimeSignature) {
     if len(events) == 0 {
         fmt.Println("   (没有生成事件)")
         return
@@ -155,13 +156,16 @@ func showDetailedEvents(events []score.Event) {
         case score.VOLUME_CHANGE:
             eventColor = cyanColor
             actionName = "VOLUME_CHANGE"
+        case score.EXPRESSION_CHANGE:
+            eventColor = cyanColor
+            actionName = "EXPRESSION_CHANGE"
         default:
             eventColor = color.New(color.FgWhite)
             actionName = fmt.Sprintf("UNKNOWN_%d", event.Action)
         }
 
         // 显示基本事件信息
-        eventColor.Printf("   [%6.3fs] %s", event.Time, actionName)
+        eventColor.Printf("   [%8s] %s", meter.Position(event.Time), actionName)
         fmt.Printf(" Ch:%d", event.Channel)
         
         // 根据事件类型显示详细数据
@@ -178,6 +182,9 @@ func showDetailedEvents(events []score.Event) {
             
         case score.VOLUME_CHANGE:
             fmt.Printf(" Volume:%v", event.Data)
+
+        case score.EXPRESSION_CHANGE:
+            fmt.Printf(" Expression:%v", event.Data)
         }
         
         if event.Duration > 0 {
@@ -218,6 +225,8 @@ func showEventStatistics(events []score.Event) {
             actionName = "乐器切换"
         case score.VOLUME_CHANGE:
             actionName = "音量变化"
+        case score.EXPRESSION_CHANGE:
+            actionName = "表情变化"
         default:
             actionName = fmt.Sprintf("未知(%d)", action)
         }
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"catRock/pkg/dsl"
+	"catRock/pkg/score"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+type exportOpts struct {
+	Output string
+	Format string
+	PPQ    int
+}
+
+func newExportCmd() *cobra.Command {
+	var opts exportOpts
+
+	exportCmd := &cobra.Command{
+		Use:   "export <file.crock>",
+		Short: "📤 导出CatRock音乐文件",
+		Long: `将.crock音乐文件导出为其他格式。
+
+支持的格式：
+- midi  标准MIDI文件 (Type 1)
+- json  CatRock乐谱JSON (可用 catrock play 直接播放)
+- xml   MusicXML 4.0 乐谱 (可用 MuseScore 等打谱软件打开)
+
+未指定 --format 时根据输出文件扩展名推断。`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(args[0], &opts)
+		},
+	}
+
+	exportCmd.Flags().StringVarP(&opts.Output, "output", "o", "", "输出文件路径 (默认与输入同名)")
+	exportCmd.Flags().StringVar(&opts.Format, "format", "", "导出格式: midi, json, xml")
+	exportCmd.Flags().IntVar(&opts.PPQ, "ppq", score.DefaultPPQ, "MIDI分辨率 (每四分音符tick数)")
+
+	return exportCmd
+}
+
+func runExport(filename string, opts *exportOpts) error {
+	green := color.New(color.FgGreen, color.Bold)
+	red := color.New(color.FgRed, color.Bold)
+	yellow := color.New(color.FgYellow)
+
+	format, err := resolveExportFormat(opts.Format, opts.Output)
+	if err != nil {
+		red.Printf("❌ %v\n", err)
+		return err
+	}
+
+	output := opts.Output
+	if output == "" {
+		output = strings.TrimSuffix(filename, filepath.Ext(filename)) + exportExtensions[format]
+	}
+
+	yellow.Printf("🔍 正在解析: %s\n", filepath.Base(filename))
+	scoreObj, err := loadScore(filename)
+	if err != nil {
+		red.Printf("❌ %v\n", err)
+		return err
+	}
+
+	yellow.Printf("📤 正在导出: %s\n", output)
+	err = scoreObj.Export(score.ExportOptions{
+		Format:   format,
+		FileName: output,
+		Options: map[string]interface{}{
+			"ppq": opts.PPQ,
+		},
+	})
+	if err != nil {
+		red.Printf("❌ 导出失败: %v\n", err)
+		return err
+	}
+
+	green.Printf("✅ 导出完成: %s\n", output)
+	return nil
+}
+
+// 导出格式对应的默认扩展名
+var exportExtensions = map[score.ExportFormat]string{
+	score.MIDI: ".mid",
+	score.JSON: ".json",
+	score.XML:  ".musicxml",
+}
+
+// 解析导出格式：优先使用 --format，否则按输出扩展名推断
+func resolveExportFormat(format string, output string) (score.ExportFormat, error) {
+	name := strings.ToLower(format)
+	if name == "" {
+		name = strings.TrimPrefix(strings.ToLower(filepath.Ext(output)), ".")
+	}
+
+	switch name {
+	case "", "mid", "midi", "smf":
+		return score.MIDI, nil
+	case "json":
+		return score.JSON, nil
+	case "xml", "musicxml":
+		return score.XML, nil
+	default:
+		return 0, fmt.Errorf("不支持的导出格式: %s", name)
+	}
+}
+
+// 读取并解析.crock或.json文件，生成Score
+func loadScore(filename string) (*score.Score, error) {
+	if err := validateFile(filename); err != nil {
+		return nil, fmt.Errorf("文件错误: %v", err)
+	}
+
+	if isJSONScore(filename) {
+		return score.LoadScoreJSON(filename)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取失败: %v", err)
+	}
+
+	lexer := dsl.NewFileLexer(string(content), filename)
+	parser := dsl.NewParser(lexer)
+	ast := parser.ParseScore()
+
+	if len(parser.Errors()) > 0 {
+		return nil, fmt.Errorf("解析错误:\n   %s", strings.Join(parser.Errors(), "\n   "))
+	}
+
+	generator := dsl.NewGenerator()
+	scoreObj, err := generator.GenerateScore(ast)
+	if err != nil {
+		return nil, fmt.Errorf("生成失败: %v", err)
+	}
+
+	return scoreObj, nil
+}
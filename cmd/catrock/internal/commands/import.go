@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"catRock/pkg/dsl"
+	"catRock/pkg/io/midifile"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+type importOpts struct {
+	Output string
+	Grid   int
+}
+
+func newImportCmd() *cobra.Command {
+	var opts importOpts
+
+	importCmd := &cobra.Command{
+		Use:   "import <file.mid>",
+		Short: "📥 从MIDI文件导入",
+		Long: `将标准MIDI文件转换为可编辑的.crock源码。
+
+转换规则：
+- 音符起止时间按 --grid 量化 (默认十六分音符)
+- 每个MIDI通道生成一个轨道，并设置 channel 和 instrument
+- 同时开始、同时结束的音符合并为和弦
+- 时值写作 /4、附点 /4. 或比例 /5:16，力度不是 100 时写作 ~力度
+- 音符之间的空隙用休止符补齐
+- 通道内重叠的音符拆分为多个段落 (声部)
+- 只保留第一个速度；速度变化、量化和八度移动等有损转换会给出警告`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImport(args[0], &opts)
+		},
+	}
+
+	importCmd.Flags().StringVarP(&opts.Output, "output", "o", "", "输出文件路径 (默认与输入同名)")
+	importCmd.Flags().IntVar(&opts.Grid, "grid", midifile.DefaultGrid, "量化网格: 1, 2, 4, 8, 16 (1/N 全音符)")
+
+	return importCmd
+}
+
+func runImport(filename string, opts *importOpts) error {
+	green := color.New(color.FgGreen, color.Bold)
+	red := color.New(color.FgRed, color.Bold)
+	yellow := color.New(color.FgYellow)
+
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		err = fmt.Errorf("文件不存在: %s", filename)
+		red.Printf("❌ %v\n", err)
+		return err
+	}
+
+	output := opts.Output
+	if output == "" {
+		output = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".crock"
+	}
+
+	yellow.Printf("📥 正在导入: %s\n", filepath.Base(filename))
+	result, err := midifile.ImportFile(filename, midifile.ImportOptions{Grid: opts.Grid})
+	if err != nil {
+		red.Printf("❌ 导入失败: %v\n", err)
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		yellow.Printf("⚠️  %s\n", warning)
+	}
+
+	// 确认生成的源码能被解析器接受
+	parser := dsl.NewParser(dsl.NewLexer(result.Source))
+	parser.ParseScore()
+	if len(parser.Errors()) > 0 {
+		err = fmt.Errorf("生成的源码无法解析:\n   %s", strings.Join(parser.Errors(), "\n   "))
+		red.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if err := os.WriteFile(output, []byte(result.Source), 0644); err != nil {
+		red.Printf("❌ 写入失败: %v\n", err)
+		return err
+	}
+
+	green.Printf("✅ 导入完成: %s\n", output)
+	return nil
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// 播放时的键盘指令
+type keyCommand int
+
+const (
+	keyTogglePause keyCommand = iota // 空格：暂停/继续
+	keySeekBack                      // ←：后退一小节
+	keySeekForward                   // →：前进一小节
+	keyRestart                       // Home / 0：回到开头
+	keyToggleLoop                    // l：开关循环
+	keyQuit                          // q / Esc / Ctrl+C：停止
+)
+
+// 播放快捷键说明
+const keyboardHelp = "空格 暂停/继续 · ←/→ 后退/前进一小节 · 0 回到开头 · l 循环 · q 停止"
+
+// 把终端切换为原始模式并读取快捷键。
+// 标准输入不是终端时返回 nil 通道；restore 用于恢复终端状态，可重复调用。
+func startKeyboard() (<-chan keyCommand, func()) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, func() {}
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, func() {}
+	}
+
+	restored := false
+	restore := func() {
+		if !restored {
+			restored = true
+			term.Restore(fd, state)
+		}
+	}
+
+	commands := make(chan keyCommand, 8)
+	go func() {
+		buf := make([]byte, 8)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			if cmd, ok := parseKey(buf[:n]); ok {
+				commands <- cmd
+			}
+		}
+	}()
+
+	return commands, restore
+}
+
+func parseKey(input []byte) (keyCommand, bool) {
+	if len(input) == 0 {
+		return 0, false
+	}
+
+	// 方向键等转义序列：ESC [ X
+	if input[0] == 0x1b {
+		if len(input) == 1 {
+			return keyQuit, true
+		}
+		if len(input) >= 3 && input[1] == '[' {
+			switch input[2] {
+			case 'D':
+				return keySeekBack, true
+			case 'C':
+				return keySeekForward, true
+			case 'H':
+				return keyRestart, true
+			}
+		}
+		return 0, false
+	}
+
+	switch input[0] {
+	case ' ', 'p':
+		return keyTogglePause, true
+	case ',':
+		return keySeekBack, true
+	case '.':
+		return keySeekForward, true
+	case '0':
+		return keyRestart, true
+	case 'l':
+		return keyToggleLoop, true
+	case 'q', 3: // 3 = Ctrl+C（原始模式下不会产生信号）
+		return keyQuit, true
+	}
+	return 0, false
+}
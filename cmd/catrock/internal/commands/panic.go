@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"catRock/pkg/io"
+	"catRock/pkg/io/midi"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newPanicCmd() *cobra.Command {
+	var port string
+
+	panicCmd := &cobra.Command{
+		Use:   "panic",
+		Short: "🛑 释放MIDI设备上卡住的音符",
+		Long: `向MIDI设备的全部16个通道发送紧急静音消息。
+
+依次发送：
+- 所有音高的 NOTE_OFF
+- All Sound Off (CC120)
+- All Notes Off (CC123)
+- Reset All Controllers (CC121)
+
+适用于播放被强行终止后合成器仍有音符持续发声的情况。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPanic(port)
+		},
+	}
+
+	addPortFlag(panicCmd, &port)
+
+	return panicCmd
+}
+
+func runPanic(port string) error {
+	green := color.New(color.FgGreen, color.Bold)
+	red := color.New(color.FgRed, color.Bold)
+	yellow := color.New(color.FgYellow)
+
+	yellow.Println("🎹 正在连接MIDI设备...")
+
+	midiPlayer := midi.NewMIDIPlayer()
+	midiPlayer.Port = port
+	if _, err := midiPlayer.Connect(); err != nil {
+		red.Printf("❌ MIDI连接失败: %v\n", err)
+		return err
+	}
+	defer midiPlayer.Disconnect()
+
+	if err := io.Panic(midiPlayer); err != nil {
+		red.Printf("❌ 发送静音消息失败: %v\n", err)
+		return err
+	}
+
+	green.Println("✅ 已释放所有通道的音符")
+	return nil
+}
@@ -1,14 +1,22 @@
 package commands
 
 import (
+	"catRock/pkg/core"
 	"catRock/pkg/dsl"
 	"catRock/pkg/io"
 	"catRock/pkg/io/midi"
 	"catRock/pkg/score"
+	"catRock/pkg/sequencer"
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
@@ -20,6 +28,9 @@ type PlayOptions struct {
 	Tempo      float64
 	Volume     int
 	DryRun     bool
+	Loop       string
+	Timeout    time.Duration
+	Port       string
 	ShowAST    bool
 	ShowEvents bool
 }
@@ -32,8 +43,20 @@ func newPlayCmd() *cobra.Command {
 		Short: "🎵 播放CatRock音乐文件",
 		Long: `播放指定的.crock音乐文件。
 
-文件必须是.crock格式，包含有效的CatRock DSL语法。
-播放时会自动连接系统MIDI设备进行音频输出。`,
+文件必须是.crock格式，包含有效的CatRock DSL语法；
+也可以是 catrock export --format json 导出的.json乐谱。
+播放时会自动连接系统MIDI设备进行音频输出，
+可用 --port 选择输出端口 (可用端口见 catrock ports)。
+
+播放快捷键：
+  空格     暂停/继续
+  ←/→      后退/前进一小节
+  0        回到开头
+  l        开关循环 (未指定 --loop 时循环当前小节)
+  q        停止
+
+无论正常结束、按键停止、Ctrl+C/SIGTERM 还是超时退出，
+都会释放所有发声中的音符；若仍有音符卡住，可执行 catrock panic。`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runPlay(args[0], &opts)
@@ -44,6 +67,9 @@ func newPlayCmd() *cobra.Command {
 	playCmd.Flags().Float64Var(&opts.Tempo, "tempo", 0, "覆盖文件中的BPM设置")
 	playCmd.Flags().IntVar(&opts.Volume, "volume", 100, "播放音量 (0-127)")
 	playCmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "只解析验证，不实际播放")
+	playCmd.Flags().StringVar(&opts.Loop, "loop", "", "循环播放区间，单位为拍 (例如 8:16)")
+	playCmd.Flags().DurationVar(&opts.Timeout, "timeout", 0, "播放超过该时长后自动停止 (例如 30s)")
+	addPortFlag(playCmd, &opts.Port)
 
 	// 调试选项
 	playCmd.Flags().BoolVar(&opts.ShowAST, "show-ast", false, "显示抽象语法树")
@@ -80,36 +106,22 @@ func runPlay(filename string, opts *PlayOptions) error {
 	}
 
 	// 3. 解析过程
-	yellow.Println("🔍 正在解析...")
-
-	// 词法分析
-	lexer := dsl.NewLexer(string(content))
-	parser := dsl.NewParser(lexer)
-	ast := parser.ParseScore()
+	var scoreObj *score.Score
+	if isJSONScore(filename) {
+		yellow.Println("🔍 正在加载JSON乐谱...")
 
-	if len(parser.Errors()) > 0 {
-		red.Println("❌ 解析错误:")
-		for _, err := range parser.Errors() {
-			fmt.Printf("   %s\n", err)
+		scoreObj, err = score.ParseScoreJSON(content)
+		if err != nil {
+			red.Printf("❌ 加载失败: %v\n", err)
+			return err
 		}
-		return fmt.Errorf("解析失败")
-	}
 
-	green.Println("✅ 解析成功")
-
-	if opts.ShowAST {
-		white.Println("\n🌳 抽象语法树:")
-		fmt.Printf("   %s\n", ast)
-	}
-
-	// 4. 代码生成
-	yellow.Println("⚙️  正在生成音乐...")
-
-	generator := dsl.NewGenerator()
-	scoreObj, err := generator.GenerateScore(ast)
-	if err != nil {
-		red.Printf("❌ 生成失败: %v\n", err)
-		return err
+		green.Println("✅ 加载成功")
+	} else {
+		scoreObj, err = parseScoreSource(filename, content, opts)
+		if err != nil {
+			return err
+		}
 	}
 
 	// 应用选项
@@ -123,7 +135,7 @@ func runPlay(filename string, opts *PlayOptions) error {
 		cyan.Printf("🔊 音量设置为: %d\n", opts.Volume)
 	}
 
-	// 5. 生成事件
+	// 4. 生成事件
 	engine := score.NewPlayEngine(scoreObj)
 	events, err := engine.GenerateEvents()
 	if err != nil {
@@ -138,26 +150,73 @@ func runPlay(filename string, opts *PlayOptions) error {
 	fmt.Printf("   🎼 BPM: %.0f\n", scoreObj.BPM)
 	fmt.Printf("   ⏱️  时长: %.2f拍 (约%.1f秒)\n",
 		scoreObj.GetDuration(),
-		scoreObj.GetDuration()*60/scoreObj.BPM)
+		engine.TempoMap().BeatToTime(scoreObj.GetDuration()).Seconds())
 	fmt.Printf("   🎹 事件数: %d\n", len(events))
 
 	if opts.ShowEvents {
 		white.Println("\n🎹 MIDI事件:")
-		showEvents(events)
+		showEvents(events, scoreObj.Time)
 	}
 
-	// 6. 播放
+	// 5. 播放
 	if opts.DryRun {
 		yellow.Println("\n🚫 Dry-run模式，跳过播放")
 		return nil
 	}
 
-	return playMusic(scoreObj, events, engine)
+	return playMusic(scoreObj, events, engine, opts)
+}
+
+// 解析DSL源码并生成Score
+func parseScoreSource(filename string, content []byte, opts *PlayOptions) (*score.Score, error) {
+	green := color.New(color.FgGreen, color.Bold)
+	red := color.New(color.FgRed, color.Bold)
+	yellow := color.New(color.FgYellow)
+	white := color.New(color.FgWhite)
+
+	yellow.Println("🔍 正在解析...")
+
+	// 词法分析
+	lexer := dsl.NewFileLexer(string(content), filename)
+	parser := dsl.NewParser(lexer)
+	ast := parser.ParseScore()
+
+	if len(parser.Errors()) > 0 {
+		red.Println("❌ 解析错误:")
+		for _, err := range parser.Errors() {
+			fmt.Printf("   %s\n", err)
+		}
+		return nil, fmt.Errorf("解析失败")
+	}
+
+	green.Println("✅ 解析成功")
+
+	if opts.ShowAST {
+		white.Println("\n🌳 抽象语法树:")
+		fmt.Printf("   %s\n", ast)
+	}
+
+	// 代码生成
+	yellow.Println("⚙️  正在生成音乐...")
+
+	generator := dsl.NewGenerator()
+	scoreObj, err := generator.GenerateScore(ast)
+	if err != nil {
+		red.Printf("❌ 生成失败: %v\n", err)
+		return nil, err
+	}
+
+	return scoreObj, nil
+}
+
+// 是否为JSON格式的乐谱文件
+func isJSONScore(filename string) bool {
+	return strings.HasSuffix(strings.ToLower(filename), ".json")
 }
 
 func validateFile(filename string) error {
-	if !strings.HasSuffix(filename, ".crock") {
-		return fmt.Errorf("文件必须是.crock格式")
+	if !strings.HasSuffix(filename, ".crock") && !isJSONScore(filename) {
+		return fmt.Errorf("文件必须是.crock或.json格式")
 	}
 
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
@@ -167,19 +226,18 @@ func validateFile(filename string) error {
 	return nil
 }
 
-func showEvents(events []score.Event) {
+func showEvents(events []score.Event, meter core.TimeSignature) {
 	for i, event := range events {
 		if i >= 10 { // 只显示前10个事件
 			fmt.Printf("   ... 还有 %d 个事件\n", len(events)-10)
 			break
 		}
-		fmt.Printf("   [%.2f] %s Ch%d Data%v\n",
-			event.Time, event.Action, event.Channel, event.Data)
+		fmt.Printf("   [%s] %v Ch%d Data%v\n",
+			meter.Position(event.Time), event.Action, event.Channel, event.Data)
 	}
 }
 
-// 简化 playMusic 函数
-func playMusic(scoreObj *score.Score, events []score.Event, engine *score.PlayEngine) error {
+func playMusic(scoreObj *score.Score, events []score.Event, engine *score.PlayEngine, opts *PlayOptions) error {
 	green := color.New(color.FgGreen, color.Bold)
 	red := color.New(color.FgRed, color.Bold)
 	yellow := color.New(color.FgYellow)
@@ -188,6 +246,7 @@ func playMusic(scoreObj *score.Score, events []score.Event, engine *score.PlayEn
 	yellow.Println("\n🎹 正在连接MIDI设备...")
 
 	midiPlayer := midi.NewMIDIPlayer()
+	midiPlayer.Port = opts.Port
 	status, err := midiPlayer.Connect()
 	if err != nil {
 		red.Printf("❌ MIDI连接失败: %v\n", err)
@@ -202,8 +261,29 @@ func playMusic(scoreObj *score.Score, events []score.Event, engine *score.PlayEn
 	defer midiPlayer.Disconnect()
 	green.Println("✅ MIDI设备连接成功")
 
-	// 播放进度条设置
-	playDuration := scoreObj.GetDuration() * 60 / scoreObj.BPM
+	transport := engine.NewTransport(midiPlayer, events)
+	tempo := transport.TempoMap()
+
+	// 一小节的拍数（快捷键定位的步长）
+	meter := scoreObj.Time
+	beatsPerBar := meter.BarLength()
+
+	loopStart, loopEnd, hasLoop := 0.0, 0.0, false
+	if opts.Loop != "" {
+		loopStart, loopEnd, err = parseLoopRange(opts.Loop)
+		if err != nil {
+			red.Printf("❌ %v\n", err)
+			return err
+		}
+		hasLoop = true
+		if err := transport.SetLoop(loopStart, loopEnd); err != nil {
+			red.Printf("❌ %v\n", err)
+			return err
+		}
+	}
+
+	// 播放进度条设置（单位：0.1秒）
+	playDuration := tempo.BeatToTime(scoreObj.GetDuration()).Seconds()
 	bar := progressbar.NewOptions(int(playDuration*10),
 		progressbar.OptionSetDescription("🎵 播放中"),
 		progressbar.OptionSetTheme(progressbar.Theme{
@@ -218,54 +298,139 @@ func playMusic(scoreObj *score.Score, events []score.Event, engine *score.PlayEn
 		progressbar.OptionSetWidth(50),
 	)
 
-	yellow.Printf("\n🎵 开始播放... (按Ctrl+C停止)\n\n")
+	keys, restoreTerminal := startKeyboard()
+	defer restoreTerminal()
 
-	// *** 使用Score的播放方法 ***
-	errChan,err := engine.PlayEventsWithIOAsync(midiPlayer, events)
-	if err != nil {
-		red.Printf("❌ 播放准备失败: %v\n", err)
-		return err
+	if keys != nil {
+		yellow.Printf("\n🎵 开始播放... (%s)\r\n\r\n", keyboardHelp)
+	} else {
+		yellow.Printf("\n🎵 开始播放... (按Ctrl+C停止)\n\n")
+	}
+
+	// 收到中断信号或超时都会取消播放，由走带负责释放音符
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
 	}
 
-	// 启动进度条更新
-	done := make(chan bool)
+	errChan := make(chan error, 1)
+	start := time.Now()
 	go func() {
-		ticker := time.NewTicker(100 * time.Millisecond)
-		defer ticker.Stop()
-
-		start := time.Now()
-		for {
-			select {
-			case <-ticker.C:
-				elapsed := time.Since(start).Seconds()
-				if elapsed >= playDuration {
-					bar.Finish()
-					done <- true
-					return
+		errChan <- transport.Play(ctx)
+	}()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	// 按 q 停止时 Play 同样正常返回，需要单独记下；快捷键操作失败时停止播放并报告错误
+	stopped := false
+	var keyErr error
+	for {
+		select {
+		case err := <-errChan:
+			bar.Finish()
+			restoreTerminal()
+			if errors.Is(err, context.DeadlineExceeded) {
+				yellow.Printf("\n⏱️  已达到播放时限 %v，停止播放\n", opts.Timeout)
+				return nil
+			}
+			if errors.Is(err, context.Canceled) {
+				yellow.Println("\n⏹️  播放已中断")
+				return nil
+			}
+			if err == nil {
+				err = keyErr
+			}
+			if err != nil {
+				red.Printf("\n❌ 播放失败: %v\n", err)
+				return err
+			}
+			if stopped {
+				yellow.Println("\n⏹️  已停止播放")
+				return nil
+			}
+			green.Printf("\n✅ 播放完成! (用时: %v)\n", time.Since(start).Round(time.Millisecond))
+			return nil
+
+		case <-ticker.C:
+			position := transport.Position()
+			bar.Set(int(tempo.BeatToTime(position).Seconds() * 10))
+
+		case key := <-keys:
+			position := transport.Position()
+
+			var err error
+			switch key {
+			case keyTogglePause:
+				err = transport.TogglePause()
+				if transport.State() == sequencer.Paused {
+					bar.Describe("⏸️  已暂停")
+				} else {
+					bar.Describe("🎵 播放中")
+				}
+
+			case keySeekBack:
+				err = transport.Seek(math.Max(0, (math.Ceil(position/beatsPerBar)-1)*beatsPerBar))
+
+			case keySeekForward:
+				err = transport.Seek(math.Floor(position/beatsPerBar+1) * beatsPerBar)
+
+			case keyRestart:
+				err = transport.Seek(0)
+
+			case keyToggleLoop:
+				if _, _, looping := transport.Loop(); looping {
+					transport.ClearLoop()
+					bar.Describe("🎵 播放中")
+					break
+				}
+				// 未指定 --loop 时循环当前小节
+				if !hasLoop {
+					loopStart = math.Floor(position/beatsPerBar) * beatsPerBar
+					loopEnd = loopStart + beatsPerBar
 				}
-				bar.Set(int(elapsed * 10))
-			case <-done:
-				bar.Finish()
-				return
+				// 区间无效时只在状态栏提示，继续播放
+				if loopErr := transport.SetLoop(loopStart, loopEnd); loopErr != nil {
+					bar.Describe(fmt.Sprintf("❌ %v", loopErr))
+					break
+				}
+				bar.Describe(fmt.Sprintf("🔁 循环 %s-%s", meter.Position(loopStart), meter.Position(loopEnd)))
+
+			case keyQuit:
+				stopped = true
+				err = transport.Stop()
 			}
-		}
-	}()
 
-	// 等待播放完成或错误
-	start := time.Now()
-	select {
-	case err := <-errChan:
-		done <- true
-		if err != nil {
-			red.Printf("\n❌ 播放失败: %v\n", err)
-			return err
+			if err != nil && keyErr == nil {
+				keyErr = err
+				bar.Describe(fmt.Sprintf("❌ %v", err))
+				transport.Stop()
+			}
 		}
-	case <-time.After(time.Duration(playDuration*1000+1000) * time.Millisecond):
-		// 超时保护（多等1秒）
-		done <- true
 	}
+}
 
-	duration := time.Since(start)
-	green.Printf("\n✅ 播放完成! (用时: %v)\n", duration)
-	return nil
+// 解析循环区间 "起始拍:结束拍"
+func parseLoopRange(value string) (float64, float64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("循环区间格式应为 起始拍:结束拍，例如 8:16")
+	}
+
+	start, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("无效的循环起点: %s", parts[0])
+	}
+	end, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("无效的循环终点: %s", parts[1])
+	}
+	if start < 0 || end <= start {
+		return 0, 0, fmt.Errorf("无效的循环区间: %s", value)
+	}
+	return start, end, nil
 }
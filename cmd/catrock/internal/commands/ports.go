@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"catRock/pkg/io/midi"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func newPortsCmd() *cobra.Command {
+	portsCmd := &cobra.Command{
+		Use:   "ports",
+		Short: "🔌 列出可用的MIDI输出端口",
+		Long: `列出系统中可用的MIDI输出端口及其序号。
+
+播放时可用 --port 指定端口，取值为序号、完整名称或名称片段 (不区分大小写)；
+也可以设置环境变量 ` + midi.PortEnv + `。未指定时使用序号为0的端口。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPorts()
+		},
+	}
+
+	return portsCmd
+}
+
+func runPorts() error {
+	green := color.New(color.FgGreen, color.Bold)
+	yellow := color.New(color.FgYellow)
+	cyan := color.New(color.FgCyan)
+
+	ports := midi.ListOutPorts()
+	if len(ports) == 0 {
+		yellow.Println("⚠️  没有可用的MIDI输出端口")
+		return nil
+	}
+
+	// 标出当前会被选中的端口
+	spec := os.Getenv(midi.PortEnv)
+	selected, err := midi.SelectPort(ports, spec)
+	if err != nil {
+		yellow.Printf("⚠️  %s=%q: %v\n", midi.PortEnv, spec, err)
+	}
+
+	green.Println("🔌 MIDI输出端口:")
+	for _, port := range ports {
+		line := fmt.Sprintf("  [%d] %s", port.Index, port.Name)
+		if port.Index == selected {
+			cyan.Println(line + "  ← 默认")
+			continue
+		}
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// 添加 --port 选项，默认值取自环境变量
+func addPortFlag(cmd *cobra.Command, port *string) {
+	cmd.Flags().StringVar(port, "port", os.Getenv(midi.PortEnv),
+		"MIDI输出端口：序号、完整名称或名称片段 (也可用环境变量 "+midi.PortEnv+")")
+}
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"catRock/pkg/io"
+	"catRock/pkg/io/soundfont"
+	"catRock/pkg/io/synth"
+	"catRock/pkg/score"
+	"catRock/pkg/sequencer"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+type renderOpts struct {
+	Output     string
+	Engine     string
+	SoundFont  string
+	SampleRate int
+	BitDepth   int
+	Waveform   string
+	Attack     float64
+	Decay      float64
+	Sustain    float64
+	Release    float64
+}
+
+func newRenderCmd() *cobra.Command {
+	var opts renderOpts
+	envelope := synth.DefaultADSR()
+
+	renderCmd := &cobra.Command{
+		Use:   "render <file.crock>",
+		Short: "🔊 离线渲染为WAV音频",
+		Long: `将音乐文件离线渲染为WAV音频，无需MIDI设备。
+
+内置合成器 (builtin)：
+- 振荡器波形: sine, saw, square, triangle (auto 按乐器音色族选择)
+- 每个声部独立的ADSR包络
+- 力度与通道音量缩放，支持复音
+- 鼓组通道 (10) 使用噪声打击音色
+
+SoundFont (soundfont)：
+- 使用 --soundfont 指定 .sf2 音色库，按每个通道的乐器选择预设
+- 鼓组通道 (10) 使用第128号音色库
+- 指定 --soundfont 时自动使用该引擎`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRender(args[0], &opts)
+		},
+	}
+
+	renderCmd.Flags().StringVarP(&opts.Output, "output", "o", "", "输出文件路径 (默认与输入同名)")
+	renderCmd.Flags().StringVar(&opts.Engine, "engine", "", "音色引擎: builtin, soundfont (默认 builtin)")
+	renderCmd.Flags().StringVar(&opts.SoundFont, "soundfont", "", "SoundFont音色库 (.sf2)")
+	renderCmd.Flags().IntVar(&opts.SampleRate, "sample-rate", synth.DefaultSampleRate, "采样率 (Hz)")
+	renderCmd.Flags().IntVar(&opts.BitDepth, "bit-depth", synth.DefaultBitDepth, "位深: 16, 24")
+	renderCmd.Flags().StringVar(&opts.Waveform, "waveform", "auto", "波形: auto, sine, saw, square, triangle")
+	renderCmd.Flags().Float64Var(&opts.Attack, "attack", envelope.Attack, "包络起音时间 (秒)")
+	renderCmd.Flags().Float64Var(&opts.Decay, "decay", envelope.Decay, "包络衰减时间 (秒)")
+	renderCmd.Flags().Float64Var(&opts.Sustain, "sustain", envelope.Sustain, "包络延音电平 (0-1)")
+	renderCmd.Flags().Float64Var(&opts.Release, "release", envelope.Release, "包络释音时间 (秒)")
+
+	return renderCmd
+}
+
+func runRender(filename string, opts *renderOpts) error {
+	green := color.New(color.FgGreen, color.Bold)
+	red := color.New(color.FgRed, color.Bold)
+	yellow := color.New(color.FgYellow)
+
+	output := opts.Output
+	if output == "" {
+		output = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".wav"
+	}
+
+	if opts.Engine == "" {
+		opts.Engine = "builtin"
+		if opts.SoundFont != "" {
+			opts.Engine = "soundfont"
+		}
+	}
+	if opts.Engine == "soundfont" && opts.SoundFont == "" {
+		err := fmt.Errorf("soundfont 引擎需要 --soundfont 指定音色库")
+		red.Printf("❌ %v\n", err)
+		return err
+	}
+
+	if opts.BitDepth != 16 && opts.BitDepth != 24 {
+		err := fmt.Errorf("不支持的位深: %d (可选: 16, 24)", opts.BitDepth)
+		red.Printf("❌ %v\n", err)
+		return err
+	}
+
+	yellow.Printf("🔍 正在解析: %s\n", filepath.Base(filename))
+	scoreObj, err := loadScore(filename)
+	if err != nil {
+		red.Printf("❌ %v\n", err)
+		return err
+	}
+
+	engine := score.NewPlayEngine(scoreObj)
+	events, err := engine.GenerateEvents()
+	if err != nil {
+		red.Printf("❌ 事件生成失败: %v\n", err)
+		return err
+	}
+
+	yellow.Printf("🎛️  正在渲染: %d个事件 (%s, %dHz, %d-bit)\n", len(events), opts.Engine, opts.SampleRate, opts.BitDepth)
+	// 按速度表换算为秒后以每拍一秒渲染，段落和音轨的速度变化都会生效
+	samples, err := renderSamples(eventsInSeconds(score.ToIOEvents(events), engine.TempoMap()), 60, opts)
+	if err != nil {
+		red.Printf("❌ 渲染失败: %v\n", err)
+		return err
+	}
+
+	if err := synth.WriteWAVFile(output, samples, opts.SampleRate, opts.BitDepth); err != nil {
+		red.Printf("❌ 写入失败: %v\n", err)
+		return err
+	}
+
+	length := time.Duration(float64(len(samples)) / float64(opts.SampleRate) * float64(time.Second))
+	green.Printf("✅ 渲染完成: %s (%v)\n", output, length.Round(time.Millisecond))
+	return nil
+}
+
+// 把事件时间从拍数换算为秒
+func eventsInSeconds(events []io.Event, tempo *sequencer.TempoMap) []io.Event {
+	result := make([]io.Event, len(events))
+	for i, event := range events {
+		event.Time = tempo.BeatToTime(event.Time).Seconds()
+		result[i] = event
+	}
+	return result
+}
+
+// 按所选引擎渲染采样
+func renderSamples(events []io.Event, bpm float64, opts *renderOpts) ([]float64, error) {
+	switch opts.Engine {
+	case "builtin":
+		waveform, err := synth.ParseWaveform(opts.Waveform)
+		if err != nil {
+			return nil, err
+		}
+
+		options := synth.DefaultOptions()
+		options.SampleRate = opts.SampleRate
+		options.Waveform = waveform
+		options.Envelope = synth.ADSR{
+			Attack:  opts.Attack,
+			Decay:   opts.Decay,
+			Sustain: opts.Sustain,
+			Release: opts.Release,
+		}
+		return synth.RenderEvents(events, bpm, options)
+
+	case "soundfont":
+		font, err := soundfont.Load(opts.SoundFont)
+		if err != nil {
+			return nil, err
+		}
+		if opts.SampleRate <= 0 {
+			return nil, fmt.Errorf("无效的采样率: %d", opts.SampleRate)
+		}
+
+		renderer := soundfont.NewRenderer(font, opts.SampleRate)
+		return synth.RenderSequence(renderer, events, bpm, opts.SampleRate)
+
+	default:
+		return nil, fmt.Errorf("不支持的音色引擎: %s", opts.Engine)
+	}
+}
@@ -26,6 +26,11 @@ func Execute(version string) error {
     // 添加子命令
     rootCmd.AddCommand(newPlayCmd())
     rootCmd.AddCommand(newDebugCmd())
+    rootCmd.AddCommand(newExportCmd())
+    rootCmd.AddCommand(newImportCmd())
+    rootCmd.AddCommand(newRenderCmd())
+    rootCmd.AddCommand(newPanicCmd())
+    rootCmd.AddCommand(newPortsCmd())
     return rootCmd.Execute()
 }
 
@@ -66,6 +71,11 @@ func printUsage() {
     white.Println("\n基础命令:")
     blue.Println("  catrock                    # 显示此帮助信息")
     blue.Println("  catrock play <file.crock>  # 播放音乐文件")
+    blue.Println("  catrock export <file.crock> -o song.mid  # 导出标准MIDI文件")
+    blue.Println("  catrock import <file.mid> -o song.crock  # 从MIDI文件导入")
+    blue.Println("  catrock render <file.crock> -o song.wav  # 渲染为WAV音频")
+    blue.Println("  catrock panic              # 释放MIDI设备上卡住的音符")
+    blue.Println("  catrock ports              # 列出MIDI输出端口")
     blue.Println("  catrock --version          # 显示版本信息")
     blue.Println("  catrock --help             # 显示详细帮助")
     
@@ -73,6 +83,7 @@ func printUsage() {
     blue.Println("  catrock play song.crock -v        # 详细模式播放")
     blue.Println("  catrock play song.crock --tempo 140  # 自定义BPM")
     blue.Println("  catrock play song.crock --dry-run    # 只解析不播放")
+    blue.Println("  catrock play song.crock --port 1     # 指定MIDI输出端口")
     
     white.Println("\n示例文件格式 (.crock):")
     fmt.Println("  BPM: 120")
@@ -0,0 +1,81 @@
+package core
+
+import "fmt"
+
+// 演奏法：改变音符的力度和/或实际发声的长度
+type Articulation int
+
+const (
+	Accent        Articulation = iota + 1 // 重音 ^
+	Marcato                               // 强重音 ^^
+	Tenuto                                // 保持音 -
+	Staccato                              // 断音 *
+	Staccatissimo                         // 短断音 **
+	Fermata                               // 延长记号 !
+)
+
+type articulationInfo struct {
+	name     string
+	symbol   string  // DSL 中的写法
+	velocity float64 // 力度倍数
+	gate     float64 // 发声长度占时值的比例
+	hold     float64 // 演奏时间的倍数，由速度表实现，不改变拍数
+}
+
+var articulationInfos = map[Articulation]articulationInfo{
+	Accent:        {"accent", "^", 1.25, 1, 1},
+	Marcato:       {"marcato", "^^", 1.4, 0.75, 1},
+	Tenuto:        {"tenuto", "-", 1.1, 1, 1},
+	Staccato:      {"staccato", "*", 1, 0.5, 1},
+	Staccatissimo: {"staccatissimo", "**", 1, 0.25, 1},
+	Fermata:       {"fermata", "!", 1, 1, 2},
+}
+
+// 按名称解析演奏法，如 "staccato"
+func ParseArticulation(name string) (Articulation, error) {
+	for articulation, info := range articulationInfos {
+		if info.name == name {
+			return articulation, nil
+		}
+	}
+	return 0, fmt.Errorf("未知的演奏法: %s", name)
+}
+
+func (a Articulation) String() string {
+	if info, ok := articulationInfos[a]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("Articulation(%d)", int(a))
+}
+
+// DSL 中的写法
+func (a Articulation) Symbol() string {
+	return articulationInfos[a].symbol
+}
+
+// 多个演奏法的效果：力度倍数相乘，发声比例取最短的
+func ArticulationEffect(articulations []Articulation) (velocity float64, gate float64) {
+	velocity, gate = 1, 1
+	for _, articulation := range articulations {
+		info, ok := articulationInfos[articulation]
+		if !ok {
+			continue
+		}
+		velocity *= info.velocity
+		if info.gate < gate {
+			gate = info.gate
+		}
+	}
+	return velocity, gate
+}
+
+// 多个演奏法对演奏时间的延长倍数，取最大的
+func ArticulationHold(articulations []Articulation) float64 {
+	hold := 1.0
+	for _, articulation := range articulations {
+		if info, ok := articulationInfos[articulation]; ok && info.hold > hold {
+			hold = info.hold
+		}
+	}
+	return hold
+}
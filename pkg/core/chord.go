@@ -16,6 +16,12 @@ const (
     Dominant7
     Sus2
     Sus4
+    Sixth           // 6
+    Minor6          // m6
+    HalfDiminished7 // m7b5
+    Diminished7     // dim7
+    MinorMajor7     // mMaj7
+    Power           // 5
 )
 
 // NewChord 创建一个新的和弦
@@ -44,6 +50,18 @@ func getChordIntervals(quality ChordQuality) []int {
 		return []int{0, 2, 7}
 	case Sus4:
 		return []int{0, 5, 7}
+	case Sixth:
+		return []int{0, 4, 7, 9}
+	case Minor6:
+		return []int{0, 3, 7, 9}
+	case HalfDiminished7:
+		return []int{0, 3, 6, 10}
+	case Diminished7:
+		return []int{0, 3, 6, 9}
+	case MinorMajor7:
+		return []int{0, 3, 7, 11}
+	case Power:
+		return []int{0, 7}
 	default:
 		return []int{0, 4, 7} // 默认大三和弦
 	}
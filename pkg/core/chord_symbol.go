@@ -0,0 +1,297 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 和弦符号，如 Cmaj7、F#m7b5、G7/B、Bb13(#11)
+type ChordSymbol struct {
+	Symbol    string        // 原始写法
+	Root      BaseNoteName  // 根音
+	Quality   ChordQuality  // 基本性质
+	Intervals []int         // 相对根音的半音数，含扩展音和变化音，升序
+	Bass      *BaseNoteName // 斜线和弦指定的低音
+}
+
+// 根音字母对应的音名
+var chordRootLetters = map[byte]BaseNoteName{
+	'C': C, 'D': D, 'E': E, 'F': F, 'G': G, 'A': A, 'B': B,
+}
+
+// 扩展音数字对应的半音数
+var chordExtensionIntervals = map[int]int{9: 14, 11: 17, 13: 21}
+
+// 解析和弦符号。
+// 根音：A-G 加可选的 #(或 s) 或 b；
+// 性质：maj/M、m/min/-、dim/o、aug/+、m7b5/ø、mMaj、sus2/sus4、5、6、69、7、9、11、13；
+// 附加与变化：add9/add11/add13、b5/#5、b9/#9、#11、b13、omit3/omit5（可加括号或逗号分隔）；
+// 斜线低音：/B、/F#
+func ParseChordSymbol(symbol string) (ChordSymbol, error) {
+	text := strings.TrimSpace(symbol)
+	if text == "" {
+		return ChordSymbol{}, fmt.Errorf("和弦符号为空")
+	}
+
+	chord := ChordSymbol{Symbol: text}
+
+	// 斜线低音
+	if index := strings.LastIndex(text, "/"); index >= 0 {
+		bass, rest, err := parseChordRoot(text[index+1:])
+		if err != nil || rest != "" {
+			return ChordSymbol{}, fmt.Errorf("和弦 %s 的低音无效: %s", symbol, text[index+1:])
+		}
+		chord.Bass = &bass
+		text = text[:index]
+	}
+
+	root, rest, err := parseChordRoot(text)
+	if err != nil {
+		return ChordSymbol{}, fmt.Errorf("和弦 %s 的根音无效: %v", symbol, err)
+	}
+	chord.Root = root
+
+	intervals, quality, err := parseChordQuality(rest)
+	if err != nil {
+		return ChordSymbol{}, fmt.Errorf("无法识别的和弦 %s: %v", symbol, err)
+	}
+	chord.Quality = quality
+	chord.Intervals = intervals
+
+	return chord, nil
+}
+
+// 解析根音，返回音名和剩余部分
+func parseChordRoot(text string) (BaseNoteName, string, error) {
+	if text == "" {
+		return C, "", fmt.Errorf("缺少根音")
+	}
+
+	name, ok := chordRootLetters[text[0]]
+	if !ok {
+		return C, "", fmt.Errorf("%q 不是音名", text[:1])
+	}
+
+	pitchClass := int(name)
+	rest := text[1:]
+	switch {
+	case strings.HasPrefix(rest, "#"),
+		strings.HasPrefix(rest, "s") && !strings.HasPrefix(rest, "sus"):
+		// 与音符写法一致，Fs 等同于 F#
+		pitchClass++
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "b"):
+		pitchClass--
+		rest = rest[1:]
+	}
+
+	return BaseNoteName((pitchClass + 12) % 12), rest, nil
+}
+
+// 解析根音之后的部分，返回音程和基本性质
+func parseChordQuality(text string) ([]int, ChordQuality, error) {
+	rest := text
+	consume := func(prefixes ...string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(rest, prefix) {
+				rest = rest[len(prefix):]
+				return true
+			}
+		}
+		return false
+	}
+
+	// 性质前缀
+	minor, majorSeventh, fixed := false, false, false
+	quality := Major
+	switch {
+	case consume("m7b5", "ø7", "ø"):
+		quality, fixed = HalfDiminished7, true
+	case consume("dim7", "o7"):
+		quality, fixed = Diminished7, true
+	case consume("dim", "o"):
+		quality = Diminished
+	case consume("aug", "+"):
+		quality = Augmented
+	case consume("mMaj", "mmaj", "mM"):
+		minor, majorSeventh = true, true
+	case consume("maj", "Maj", "M"):
+		majorSeventh = true
+	case consume("min", "m", "-"):
+		minor = true
+		quality = Minor
+	}
+
+	// 数字：5、6、69、7、9、11、13
+	extensions := []int{}
+	if number, ok := leadingNumber(rest); ok && !fixed {
+		rest = rest[len(strconv.Itoa(number)):]
+
+		switch number {
+		case 5:
+			if minor || majorSeventh || quality != Major {
+				return nil, quality, fmt.Errorf("5 (强力和弦) 不能与其他性质同用")
+			}
+			quality = Power
+		case 6:
+			quality = Sixth
+			if minor {
+				quality = Minor6
+			}
+		case 69:
+			quality = Sixth
+			if minor {
+				quality = Minor6
+			}
+			extensions = append(extensions, 14)
+		case 7, 9, 11, 13:
+			switch {
+			case quality == Diminished:
+				quality = Diminished7
+			case quality == Augmented:
+				// 增七和弦：增三和弦加小七度
+				extensions = append(extensions, 10)
+			case minor && majorSeventh:
+				quality = MinorMajor7
+			case minor:
+				quality = Minor7
+			case majorSeventh:
+				quality = Major7
+			default:
+				quality = Dominant7
+			}
+			if number >= 9 {
+				extensions = append(extensions, 14)
+			}
+			if number == 11 || (number == 13 && minor) {
+				extensions = append(extensions, 17)
+			}
+			if number == 13 {
+				extensions = append(extensions, 21)
+			}
+		default:
+			return nil, quality, fmt.Errorf("不支持的和弦数字 %d", number)
+		}
+	} else if minor && majorSeventh {
+		return nil, quality, fmt.Errorf("mMaj 后需要 7、9、11 或 13")
+	}
+
+	intervals := map[int]bool{}
+	for _, interval := range getChordIntervals(quality) {
+		intervals[interval] = true
+	}
+	for _, interval := range extensions {
+		intervals[interval] = true
+	}
+
+	// 替换三音或五音
+	replace := func(from []int, to int) {
+		for _, interval := range from {
+			delete(intervals, interval)
+		}
+		if to >= 0 {
+			intervals[to] = true
+		}
+	}
+
+	// 挂留、附加音和变化音
+	for rest != "" {
+		switch {
+		case consume("(", ")", ",", " "):
+		case consume("sus2"):
+			replace([]int{3, 4}, 2)
+			if quality == Major {
+				quality = Sus2
+			}
+		case consume("sus4", "sus"):
+			replace([]int{3, 4}, 5)
+			if quality == Major {
+				quality = Sus4
+			}
+		case consume("add9", "add2"):
+			intervals[14] = true
+		case consume("add11", "add4"):
+			intervals[17] = true
+		case consume("add13", "add6"):
+			intervals[21] = true
+		case consume("omit3", "no3"):
+			replace([]int{2, 3, 4, 5}, -1)
+		case consume("omit5", "no5"):
+			replace([]int{6, 7, 8}, -1)
+		case consume("b5", "-5"):
+			replace([]int{7}, 6)
+		case consume("#5", "+5"):
+			replace([]int{7}, 8)
+		case consume("b9", "-9"):
+			intervals[13] = true
+		case consume("#9", "+9"):
+			intervals[15] = true
+		case consume("#11", "+11"):
+			intervals[18] = true
+		case consume("b13", "-13"):
+			intervals[20] = true
+		default:
+			// 括号中的自然扩展音，如 7(9,13)
+			number, ok := leadingNumber(rest)
+			interval, known := chordExtensionIntervals[number]
+			if !ok || !known {
+				return nil, quality, fmt.Errorf("无法识别 %q", rest)
+			}
+			rest = rest[len(strconv.Itoa(number)):]
+			intervals[interval] = true
+		}
+	}
+
+	result := make([]int, 0, len(intervals))
+	for interval := range intervals {
+		result = append(result, interval)
+	}
+	sort.Ints(result)
+	return result, quality, nil
+}
+
+// 读取开头的整数
+func leadingNumber(text string) (int, bool) {
+	end := 0
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, false
+	}
+	number, err := strconv.Atoi(text[:end])
+	return number, err == nil
+}
+
+// 以 octave 八度的根音为基准生成和弦音符（密集排列，斜线低音置于根音下方）
+func (cs ChordSymbol) Notes(octave int, beat BeatValue) []Note {
+	root := NewNote(NewNoteParams{Name: cs.Root, Octave: octave, Beat: beat})
+	base := int(root.MIDINote[0])
+
+	// 扩展音超出MIDI音域时整体下移八度
+	if len(cs.Intervals) > 0 {
+		for base > 0 && base+cs.Intervals[len(cs.Intervals)-1] > 127 {
+			base -= 12
+		}
+	}
+
+	notes := []Note{}
+	if cs.Bass != nil {
+		below := (int(cs.Root) - int(*cs.Bass) + 12) % 12
+		if below == 0 {
+			below = 12
+		}
+		notes = append(notes, NewNoteFromMIDI(base-below, beat))
+	}
+
+	for _, interval := range cs.Intervals {
+		notes = append(notes, NewNoteFromMIDI(base+interval, beat))
+	}
+	return notes
+}
+
+func (cs ChordSymbol) String() string {
+	return cs.Symbol
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChordSymbol(t *testing.T) {
+	tests := []struct {
+		symbol    string
+		root      BaseNoteName
+		quality   ChordQuality
+		intervals []int
+	}{
+		{"C", C, Major, []int{0, 4, 7}},
+		{"Am", A, Minor, []int{0, 3, 7}},
+		{"Amin", A, Minor, []int{0, 3, 7}},
+		{"F#dim", Fs, Diminished, []int{0, 3, 6}},
+		{"Fsdim", Fs, Diminished, []int{0, 3, 6}},
+		{"Bbaug", As, Augmented, []int{0, 4, 8}},
+		{"C+", C, Augmented, []int{0, 4, 8}},
+		{"Csus2", C, Sus2, []int{0, 2, 7}},
+		{"Csus4", C, Sus4, []int{0, 5, 7}},
+		{"Csus", C, Sus4, []int{0, 5, 7}},
+		{"C5", C, Power, []int{0, 7}},
+		{"C6", C, Sixth, []int{0, 4, 7, 9}},
+		{"Cm6", C, Minor6, []int{0, 3, 7, 9}},
+		{"C69", C, Sixth, []int{0, 4, 7, 9, 14}},
+		{"C7", C, Dominant7, []int{0, 4, 7, 10}},
+		{"Cmaj7", C, Major7, []int{0, 4, 7, 11}},
+		{"CM7", C, Major7, []int{0, 4, 7, 11}},
+		{"Cm7", C, Minor7, []int{0, 3, 7, 10}},
+		{"C-7", C, Minor7, []int{0, 3, 7, 10}},
+		{"CmMaj7", C, MinorMajor7, []int{0, 3, 7, 11}},
+		{"F#m7b5", Fs, HalfDiminished7, []int{0, 3, 6, 10}},
+		{"Cø", C, HalfDiminished7, []int{0, 3, 6, 10}},
+		{"Cdim7", C, Diminished7, []int{0, 3, 6, 9}},
+		{"Co7", C, Diminished7, []int{0, 3, 6, 9}},
+		{"Caug7", C, Augmented, []int{0, 4, 8, 10}},
+		{"C9", C, Dominant7, []int{0, 4, 7, 10, 14}},
+		{"Cmaj9", C, Major7, []int{0, 4, 7, 11, 14}},
+		{"C11", C, Dominant7, []int{0, 4, 7, 10, 14, 17}},
+		{"C13", C, Dominant7, []int{0, 4, 7, 10, 14, 21}},
+		{"Bb13", As, Dominant7, []int{0, 4, 7, 10, 14, 21}},
+		{"Cm13", C, Minor7, []int{0, 3, 7, 10, 14, 17, 21}},
+		{"Cadd9", C, Major, []int{0, 4, 7, 14}},
+		{"Cmadd11", C, Minor, []int{0, 3, 7, 17}},
+		{"C7sus4", C, Dominant7, []int{0, 5, 7, 10}},
+		{"C7b5", C, Dominant7, []int{0, 4, 6, 10}},
+		{"C7#5", C, Dominant7, []int{0, 4, 8, 10}},
+		{"C7(b9,#11)", C, Dominant7, []int{0, 4, 7, 10, 13, 18}},
+		{"C7#9", C, Dominant7, []int{0, 4, 7, 10, 15}},
+		{"C7b13", C, Dominant7, []int{0, 4, 7, 10, 20}},
+		{"C7(9,13)", C, Dominant7, []int{0, 4, 7, 10, 14, 21}},
+		{"Cmaj7omit3", C, Major7, []int{0, 7, 11}},
+		{"C7no5", C, Dominant7, []int{0, 4, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			chord, err := ParseChordSymbol(tt.symbol)
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if chord.Root != tt.root {
+				t.Errorf("根音 = %v，期望 %v", chord.Root, tt.root)
+			}
+			if chord.Quality != tt.quality {
+				t.Errorf("性质 = %v，期望 %v", chord.Quality, tt.quality)
+			}
+			if !reflect.DeepEqual(chord.Intervals, tt.intervals) {
+				t.Errorf("音程 = %v，期望 %v", chord.Intervals, tt.intervals)
+			}
+			if chord.Bass != nil {
+				t.Errorf("没有斜线的和弦不应有低音，得到 %v", *chord.Bass)
+			}
+		})
+	}
+}
+
+func TestParseSlashChord(t *testing.T) {
+	tests := []struct {
+		symbol string
+		root   BaseNoteName
+		bass   BaseNoteName
+	}{
+		{"G7/B", G, B},
+		{"C/Bb", C, As},
+		{"Am7/F#", A, Fs},
+		{"D/Fs", D, Fs},
+	}
+
+	for _, tt := range tests {
+		chord, err := ParseChordSymbol(tt.symbol)
+		if err != nil {
+			t.Errorf("%s 解析失败: %v", tt.symbol, err)
+			continue
+		}
+		if chord.Root != tt.root || chord.Bass == nil || *chord.Bass != tt.bass {
+			t.Errorf("%s: 根音 %v 低音 %v，期望 %v/%v", tt.symbol, chord.Root, chord.Bass, tt.root, tt.bass)
+		}
+	}
+}
+
+func TestParseChordSymbolErrors(t *testing.T) {
+	invalid := []string{
+		"",
+		"H7",
+		"c",
+		"Cm5",
+		"Cmaj5",
+		"CmMaj",
+		"C8",
+		"C7/H",
+		"C/",
+		"Cxyz",
+		"C7(15)",
+	}
+
+	for _, symbol := range invalid {
+		if chord, err := ParseChordSymbol(symbol); err == nil {
+			t.Errorf("%q 应解析失败，得到 %+v", symbol, chord)
+		}
+	}
+}
+
+func TestChordSymbolNotes(t *testing.T) {
+	midi := func(notes []Note) []int {
+		result := make([]int, len(notes))
+		for i, note := range notes {
+			result[i] = int(note.MIDINote[0])
+		}
+		return result
+	}
+
+	g7, _ := ParseChordSymbol("G7/B")
+	g4 := int(NewNote(NewNoteParams{Name: G, Octave: 4}).MIDINote[0])
+	// 低音 B 在根音 G 下方小六度
+	if got, want := midi(g7.Notes(4, Quarter)), []int{g4 - 8, g4, g4 + 4, g4 + 7, g4 + 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("G7/B = %v，期望 %v", got, want)
+	}
+
+	// 低音与根音同名时放在下方一个八度
+	c, _ := ParseChordSymbol("C/C")
+	c4 := int(NewNote(NewNoteParams{Name: C, Octave: 4}).MIDINote[0])
+	if got := midi(c.Notes(4, Quarter)); got[0] != c4-12 {
+		t.Errorf("C/C 的低音 = %d，期望 %d", got[0], c4-12)
+	}
+
+	// 超出MIDI音域时整体下移八度
+	c13, _ := ParseChordSymbol("C13")
+	notes := midi(c13.Notes(5, Quarter))
+	if top := notes[len(notes)-1]; top > 127 {
+		t.Errorf("C13 最高音 %d 超出音域", top)
+	}
+	if notes[0]%12 != int(C) {
+		t.Errorf("C13 下移后根音 = %d，不是 C", notes[0])
+	}
+
+	for _, note := range g7.Notes(4, Half) {
+		if note.Beat != Half {
+			t.Errorf("和弦音时值 = %v，期望 %v", note.Beat, Half)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 和弦排列方式
+type Voicing int
+
+const (
+	DefaultVoicing Voicing = iota // 保持原样
+	CloseVoicing                  // 密集排列：低音之上的音压缩到一个八度内
+	OpenVoicing                   // 开放排列：密集排列后把第二低的音升高八度
+	SpreadVoicing                 // 展开排列：开放排列后低音再降低八度
+)
+
+var voicingNames = map[string]Voicing{
+	"close":  CloseVoicing,
+	"open":   OpenVoicing,
+	"spread": SpreadVoicing,
+}
+
+// 解析排列方式名称 close、open、spread
+func ParseVoicing(name string) (Voicing, error) {
+	if voicing, ok := voicingNames[name]; ok {
+		return voicing, nil
+	}
+	return DefaultVoicing, fmt.Errorf("未知的和弦排列方式: %s (可选 close、open、spread)", name)
+}
+
+func (v Voicing) String() string {
+	for name, voicing := range voicingNames {
+		if voicing == v {
+			return name
+		}
+	}
+	return "default"
+}
+
+// 移动若干个八度，保留音名拼写
+func (n Note) ShiftOctave(octaves int) Note {
+	shifted := n
+	shifted.Octave += octaves
+	if len(n.MIDINote) > 0 {
+		shifted.MIDINote = []byte{byte(int(n.MIDINote[0]) + octaves*12)}
+	}
+	return shifted
+}
+
+// 按音高从低到高排序的副本
+func (c Chord) sorted() Chord {
+	notes := make([]Note, 0, len(c.Notes))
+	for _, note := range c.Notes {
+		if len(note.MIDINote) > 0 {
+			notes = append(notes, note)
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].MIDINote[0] < notes[j].MIDINote[0]
+	})
+	return Chord{Notes: notes}
+}
+
+// 转位：把最低音依次升高八度，inversion 为 1 时得到第一转位
+func (c Chord) Invert(inversion int) Chord {
+	chord := c.sorted()
+	for i := 0; i < inversion && len(chord.Notes) > 1; i++ {
+		lowest := chord.Notes[0]
+		top := int(chord.Notes[len(chord.Notes)-1].MIDINote[0])
+
+		// 升到最高音之上
+		octaves := 1
+		for int(lowest.MIDINote[0])+octaves*12 <= top {
+			octaves++
+		}
+		chord.Notes = append(chord.Notes[1:], lowest.ShiftOctave(octaves))
+	}
+	return chord
+}
+
+// Drop 排列：把从上往下数第 voice 个音降低八度，如 drop2、drop3
+func (c Chord) Drop(voice int) Chord {
+	chord := c.sorted()
+	index := len(chord.Notes) - voice
+	if voice < 1 || index < 0 {
+		return chord
+	}
+	chord.Notes[index] = chord.Notes[index].ShiftOctave(-1)
+	return chord.sorted()
+}
+
+// 按排列方式重新排列和弦音，最低音保持不动
+func (c Chord) Voice(voicing Voicing) Chord {
+	if voicing == DefaultVoicing {
+		return c
+	}
+
+	chord := c.sorted()
+	if len(chord.Notes) < 2 {
+		return chord
+	}
+
+	// 密集排列：其余的音移到最低音之上的一个八度内
+	bass := int(chord.Notes[0].MIDINote[0])
+	for i := 1; i < len(chord.Notes); i++ {
+		note := chord.Notes[i]
+		octaves := 0
+		for int(note.MIDINote[0])+octaves*12 > bass+12 {
+			octaves--
+		}
+		for int(note.MIDINote[0])+octaves*12 <= bass {
+			octaves++
+		}
+		chord.Notes[i] = note.ShiftOctave(octaves)
+	}
+	chord = chord.sorted()
+
+	if voicing == OpenVoicing || voicing == SpreadVoicing {
+		if len(chord.Notes) >= 3 {
+			chord.Notes[1] = chord.Notes[1].ShiftOctave(1)
+		}
+		if voicing == SpreadVoicing {
+			chord.Notes[0] = chord.Notes[0].ShiftOctave(-1)
+		}
+		chord = chord.sorted()
+	}
+
+	return chord
+}
@@ -0,0 +1,122 @@
+package core
+
+import "fmt"
+
+// 力度记号，从 ppp 到 fff；零值表示未标记
+type Dynamic int
+
+const (
+	DynamicPPP Dynamic = iota + 1
+	DynamicPP
+	DynamicP
+	DynamicMP
+	DynamicMF
+	DynamicF
+	DynamicFF
+	DynamicFFF
+)
+
+var dynamicNames = map[Dynamic]string{
+	DynamicPPP: "ppp",
+	DynamicPP:  "pp",
+	DynamicP:   "p",
+	DynamicMP:  "mp",
+	DynamicMF:  "mf",
+	DynamicF:   "f",
+	DynamicFF:  "ff",
+	DynamicFFF: "fff",
+}
+
+// 各力度记号对应的 MIDI 力度 (或表情控制器的值)
+var dynamicVelocities = map[Dynamic]uint8{
+	DynamicPPP: 16,
+	DynamicPP:  33,
+	DynamicP:   49,
+	DynamicMP:  64,
+	DynamicMF:  80,
+	DynamicF:   96,
+	DynamicFF:  112,
+	DynamicFFF: 127,
+}
+
+// 按名称解析力度记号，如 "mf"
+func ParseDynamic(name string) (Dynamic, error) {
+	for dynamic, dynamicName := range dynamicNames {
+		if dynamicName == name {
+			return dynamic, nil
+		}
+	}
+	return 0, fmt.Errorf("未知的力度记号: %s (可选 ppp、pp、p、mp、mf、f、ff、fff)", name)
+}
+
+func (d Dynamic) String() string {
+	if name, ok := dynamicNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("Dynamic(%d)", int(d))
+}
+
+func (d Dynamic) Velocity() uint8 {
+	return dynamicVelocities[d]
+}
+
+// 升高 (正) 或降低 (负) steps 级，限制在 ppp 到 fff 之间
+func (d Dynamic) Step(steps int) Dynamic {
+	result := d + Dynamic(steps)
+	if result < DynamicPPP {
+		return DynamicPPP
+	}
+	if result > DynamicFFF {
+		return DynamicFFF
+	}
+	return result
+}
+
+// 与 MIDI 力度最接近的力度记号
+func NearestDynamic(velocity int) Dynamic {
+	nearest := DynamicPPP
+	for dynamic := DynamicPPP; dynamic <= DynamicFFF; dynamic++ {
+		if abs(int(dynamic.Velocity())-velocity) < abs(int(nearest.Velocity())-velocity) {
+			nearest = dynamic
+		}
+	}
+	return nearest
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// 力度记号的演奏方式
+type DynamicsMode int
+
+const (
+	VelocityDynamics   DynamicsMode = iota // 改变音符力度 (默认)
+	ExpressionDynamics                     // 发送表情控制器 (CC11)，音符力度不变
+)
+
+// 按名称解析力度记号的演奏方式：velocity 或 expression
+func ParseDynamicsMode(name string) (DynamicsMode, error) {
+	switch name {
+	case "velocity":
+		return VelocityDynamics, nil
+	case "expression":
+		return ExpressionDynamics, nil
+	default:
+		return VelocityDynamics, fmt.Errorf("未知的力度模式: %s (可选 velocity、expression)", name)
+	}
+}
+
+func (m DynamicsMode) String() string {
+	switch m {
+	case VelocityDynamics:
+		return "velocity"
+	case ExpressionDynamics:
+		return "expression"
+	default:
+		return fmt.Sprintf("DynamicsMode(%d)", int(m))
+	}
+}
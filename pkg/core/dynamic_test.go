@@ -0,0 +1,40 @@
+package core
+
+import "testing"
+
+func TestNearestDynamic(t *testing.T) {
+	tests := []struct {
+		velocity int
+		want     Dynamic
+	}{
+		{0, DynamicPPP},
+		{100, DynamicF},
+		{72, DynamicMP},
+		{127, DynamicFFF},
+	}
+
+	for _, tt := range tests {
+		if got := NearestDynamic(tt.velocity); got != tt.want {
+			t.Errorf("NearestDynamic(%d) = %s，期望 %s", tt.velocity, got, tt.want)
+		}
+	}
+}
+
+func TestDynamicStep(t *testing.T) {
+	tests := []struct {
+		from  Dynamic
+		steps int
+		want  Dynamic
+	}{
+		{DynamicP, 2, DynamicMF},
+		{DynamicMF, -1, DynamicMP},
+		{DynamicFF, 3, DynamicFFF},
+		{DynamicPP, -4, DynamicPPP},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.Step(tt.steps); got != tt.want {
+			t.Errorf("%s.Step(%d) = %s，期望 %s", tt.from, tt.steps, got, tt.want)
+		}
+	}
+}
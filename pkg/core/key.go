@@ -0,0 +1,117 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 调号：Fifths 为升号 (正) 或降号 (负) 的个数，零值为 C 大调
+type Key struct {
+	Fifths int
+	Minor  bool
+}
+
+// 自然音在五度圈上的位置 (相对 C)
+var letterFifths = map[BaseNoteName]int{F: -1, C: 0, G: 1, D: 2, A: 3, E: 4, B: 5}
+
+// 升号依次加在 F C G D A E B 上，降号顺序相反
+var sharpOrder = []BaseNoteName{F, C, G, D, A, E, B}
+
+// 按升降号个数 -7..7 排列的主音写法
+var majorTonics = []string{"Cb", "Gb", "Db", "Ab", "Eb", "Bb", "F", "C", "G", "D", "A", "E", "B", "Fs", "Cs"}
+var minorTonics = []string{"Ab", "Eb", "Bb", "F", "C", "G", "D", "A", "E", "B", "Fs", "Cs", "Gs", "Ds", "As"}
+
+// 解析调名，如 Eb_major、fs_minor、C_major；主音用 s 表示升、b 表示降
+func ParseKey(name string) (Key, error) {
+	parts := strings.Split(name, "_")
+	if len(parts) != 2 || parts[0] == "" {
+		return Key{}, fmt.Errorf("无效的调号: %s (应为 主音_major 或 主音_minor，如 Eb_major)", name)
+	}
+
+	key := Key{}
+	switch strings.ToLower(parts[1]) {
+	case "major":
+	case "minor":
+		key.Minor = true
+	default:
+		return Key{}, fmt.Errorf("无效的调式: %s (可选 major、minor)", parts[1])
+	}
+
+	tonic := parts[0]
+	letter, ok := letterNames[strings.ToUpper(tonic[:1])]
+	if !ok {
+		return Key{}, fmt.Errorf("无效的主音: %s", tonic)
+	}
+	accidental := Natural
+	switch strings.ToLower(tonic[1:]) {
+	case "":
+	case "s", "#":
+		accidental = Sharp
+	case "b":
+		accidental = Flat
+	default:
+		return Key{}, fmt.Errorf("无效的主音: %s", tonic)
+	}
+
+	key.Fifths = letterFifths[letter] + 7*int(accidental)
+	if key.Minor {
+		key.Fifths -= 3 // 关系大调在小三度之上
+	}
+	if key.Fifths < -7 || key.Fifths > 7 {
+		return Key{}, fmt.Errorf("调号 %s 的升降号超过7个，请使用等音调", name)
+	}
+	return key, nil
+}
+
+var letterNames = map[string]BaseNoteName{"C": C, "D": D, "E": E, "F": F, "G": G, "A": A, "B": B}
+
+func (k Key) String() string {
+	if k.Fifths < -7 || k.Fifths > 7 {
+		return fmt.Sprintf("Key(%d)", k.Fifths)
+	}
+	if k.Minor {
+		return minorTonics[k.Fifths+7] + "_minor"
+	}
+	return majorTonics[k.Fifths+7] + "_major"
+}
+
+// 调号对某个自然音的升降
+func (k Key) AccidentalFor(letter BaseNoteName) Accidental {
+	for i, name := range sharpOrder {
+		if name != letter {
+			continue
+		}
+		if k.Fifths > i {
+			return Sharp
+		}
+		if -k.Fifths >= len(sharpOrder)-i {
+			return Flat
+		}
+	}
+	return Natural
+}
+
+// 对未写变音记号的音符应用调号，保留音名并改写变音与音高
+func (k Key) Apply(note Note) Note {
+	if !note.FollowKey || len(note.MIDINote) == 0 {
+		return note
+	}
+	accidental := k.AccidentalFor(note.Name)
+	if accidental == note.Accidental {
+		return note
+	}
+
+	applied := note
+	applied.Accidental = accidental
+	applied.MIDINote = []byte{byte(int(note.MIDINote[0]) + int(accidental-note.Accidental))}
+	return applied
+}
+
+// 对和弦中的每个音符应用调号
+func (k Key) ApplyChord(chord Chord) Chord {
+	notes := make([]Note, len(chord.Notes))
+	for i, note := range chord.Notes {
+		notes[i] = k.Apply(note)
+	}
+	return NewChord(notes)
+}
@@ -0,0 +1,118 @@
+package core
+
+import "testing"
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		fifths int
+		minor  bool
+		text   string
+	}{
+		{"C_major", 0, false, "C_major"},
+		{"G_major", 1, false, "G_major"},
+		{"Eb_major", -3, false, "Eb_major"},
+		{"fs_minor", 3, true, "Fs_minor"},
+		{"F#_minor", 3, true, "Fs_minor"},
+		{"A_minor", 0, true, "A_minor"},
+		{"Bb_minor", -5, true, "Bb_minor"},
+		{"cs_MAJOR", 7, false, "Cs_major"},
+		{"Cb_major", -7, false, "Cb_major"},
+		{"as_minor", 7, true, "As_minor"},
+	}
+
+	for _, tt := range tests {
+		key, err := ParseKey(tt.name)
+		if err != nil {
+			t.Errorf("%s 解析失败: %v", tt.name, err)
+			continue
+		}
+		if key.Fifths != tt.fifths || key.Minor != tt.minor {
+			t.Errorf("%s = %+v，期望 Fifths %d Minor %v", tt.name, key, tt.fifths, tt.minor)
+		}
+		if got := key.String(); got != tt.text {
+			t.Errorf("%s 写作 %s，期望 %s", tt.name, got, tt.text)
+		}
+	}
+
+	invalid := []string{"", "C", "C_dorian", "H_major", "Cx_major", "_major", "Fb_major", "Gs_major"}
+	for _, name := range invalid {
+		if key, err := ParseKey(name); err == nil {
+			t.Errorf("%q 应解析失败，得到 %+v", name, key)
+		}
+	}
+}
+
+func TestKeyAccidentals(t *testing.T) {
+	letters := []BaseNoteName{C, D, E, F, G, A, B}
+	tests := []struct {
+		key  string
+		want string // 依次为 C D E F G A B 的升降: # b 或 .
+	}{
+		{"C_major", "......."},
+		{"G_major", "...#..."},
+		{"D_major", "#..#..."},
+		{"Cs_major", "#######"},
+		{"F_major", "......b"},
+		{"Eb_major", "..b..bb"},
+		{"Ab_major", ".bb..bb"},
+		{"Cb_major", "bbbbbbb"},
+		{"fs_minor", "#..##.."},
+		{"d_minor", "......b"},
+	}
+
+	symbols := map[Accidental]string{Sharp: "#", Flat: "b", Natural: "."}
+	for _, tt := range tests {
+		key, err := ParseKey(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		for _, letter := range letters {
+			got += symbols[key.AccidentalFor(letter)]
+		}
+		if got != tt.want {
+			t.Errorf("%s: %s，期望 %s", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestKeyApply(t *testing.T) {
+	key, _ := ParseKey("Eb_major")
+	note := func(name BaseNoteName, accidental Accidental, followKey bool) Note {
+		n := NewNote(NewNoteParams{Name: name, Octave: 4, Accidental: accidental})
+		n.FollowKey = followKey
+		return n
+	}
+	midi := func(name BaseNoteName, accidental Accidental) byte {
+		return NewNote(NewNoteParams{Name: name, Octave: 4, Accidental: accidental}).MIDINote[0]
+	}
+
+	tests := []struct {
+		name       string
+		note       Note
+		accidental Accidental
+		midi       byte
+	}{
+		{"按调号降低", note(E, Natural, true), Flat, midi(E, Flat)},
+		{"调号外的音不变", note(C, Natural, true), Natural, midi(C, Natural)},
+		{"显式还原号不受影响", note(E, Natural, false), Natural, midi(E, Natural)},
+		{"显式升号不受影响", note(A, Sharp, false), Sharp, midi(A, Sharp)},
+	}
+
+	for _, tt := range tests {
+		applied := key.Apply(tt.note)
+		if applied.Name != tt.note.Name {
+			t.Errorf("%s: 音名变为 %v，应保留 %v", tt.name, applied.Name, tt.note.Name)
+		}
+		if applied.Accidental != tt.accidental || applied.MIDINote[0] != tt.midi {
+			t.Errorf("%s: 变音 %v 音高 %d，期望 %v %d", tt.name, applied.Accidental, applied.MIDINote[0], tt.accidental, tt.midi)
+		}
+	}
+
+	// 和弦中的音符逐个应用
+	chord := key.ApplyChord(NewChord([]Note{note(C, Natural, true), note(E, Natural, true), note(G, Natural, true)}))
+	if got := chord.Notes[1].MIDINote[0]; got != midi(E, Flat) {
+		t.Errorf("和弦三音 = %d，期望 %d", got, midi(E, Flat))
+	}
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 拍号：每小节 Beats 拍，以 1/BeatType 音符为一拍；零值视为 4/4
+type TimeSignature struct {
+	Beats    int
+	BeatType int
+}
+
+var CommonTime = TimeSignature{Beats: 4, BeatType: 4}
+
+// 解析拍号，如 3/4、6/8
+func ParseTimeSignature(text string) (TimeSignature, error) {
+	parts := strings.Split(text, "/")
+	if len(parts) != 2 {
+		return TimeSignature{}, fmt.Errorf("无效的拍号: %s (应为 拍数/音符时值，如 3/4)", text)
+	}
+
+	beats, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || beats < 1 || beats > 32 {
+		return TimeSignature{}, fmt.Errorf("无效的拍号: %s (每小节拍数应为 1-32)", text)
+	}
+	beatType, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || beatType < 1 || beatType > 64 || beatType&(beatType-1) != 0 {
+		return TimeSignature{}, fmt.Errorf("无效的拍号: %s (以几分音符为一拍应为 1、2、4、8、16、32、64)", text)
+	}
+
+	return TimeSignature{Beats: beats, BeatType: beatType}, nil
+}
+
+func (t TimeSignature) orDefault() TimeSignature {
+	if t.Beats == 0 || t.BeatType == 0 {
+		return CommonTime
+	}
+	return t
+}
+
+func (t TimeSignature) String() string {
+	t = t.orDefault()
+	return fmt.Sprintf("%d/%d", t.Beats, t.BeatType)
+}
+
+// 每个拍号拍的长度，以四分音符为一拍
+func (t TimeSignature) BeatLength() float64 {
+	return 4 / float64(t.orDefault().BeatType)
+}
+
+// 一小节的长度，以四分音符为一拍
+func (t TimeSignature) BarLength() float64 {
+	return float64(t.orDefault().Beats) * t.BeatLength()
+}
+
+// 把以四分音符为单位的时间换算为小节和小节内的拍 (都从1开始)，拍按拍号的单位计算
+func (t TimeSignature) BarBeat(beats float64) (int, float64) {
+	barLength := t.BarLength()
+	// 容忍浮点误差，避免小节线上的时间被算到上一小节
+	bar := int(math.Floor(beats/barLength + 1e-9))
+	offset := math.Max(0, beats-float64(bar)*barLength)
+	return bar + 1, offset/t.BeatLength() + 1
+}
+
+// 小节:拍 形式的位置，如 7:1、3:2.5
+func (t TimeSignature) Position(beats float64) string {
+	bar, beat := t.BarBeat(beats)
+	beat = math.Round(beat*1000) / 1000
+	return fmt.Sprintf("%d:%s", bar, strconv.FormatFloat(beat, 'f', -1, 64))
+}
@@ -0,0 +1,68 @@
+package core
+
+import "testing"
+
+func TestParseTimeSignature(t *testing.T) {
+	tests := []struct {
+		text      string
+		barLength float64
+		beat      float64
+	}{
+		{"4/4", 4, 1},
+		{"3/4", 3, 1},
+		{"6/8", 3, 0.5},
+		{"2/2", 4, 2},
+		{"7/16", 1.75, 0.25},
+		{" 5 / 4 ", 5, 1},
+	}
+
+	for _, tt := range tests {
+		meter, err := ParseTimeSignature(tt.text)
+		if err != nil {
+			t.Errorf("%q 解析失败: %v", tt.text, err)
+			continue
+		}
+		if got := meter.BarLength(); got != tt.barLength {
+			t.Errorf("%q 小节长度 = %v，期望 %v", tt.text, got, tt.barLength)
+		}
+		if got := meter.BeatLength(); got != tt.beat {
+			t.Errorf("%q 拍长度 = %v，期望 %v", tt.text, got, tt.beat)
+		}
+	}
+
+	invalid := []string{"", "4", "4/", "0/4", "33/4", "3/3", "3/0", "3/128", "a/4", "3/4/4"}
+	for _, text := range invalid {
+		if meter, err := ParseTimeSignature(text); err == nil {
+			t.Errorf("%q 应解析失败，得到 %v", text, meter)
+		}
+	}
+
+	var zero TimeSignature
+	if zero.String() != "4/4" || zero.BarLength() != 4 {
+		t.Errorf("零值拍号应视为 4/4，得到 %s 长度 %v", zero, zero.BarLength())
+	}
+}
+
+func TestTimeSignaturePosition(t *testing.T) {
+	tests := []struct {
+		meter string
+		beats float64
+		want  string
+	}{
+		{"4/4", 0, "1:1"},
+		{"4/4", 4, "2:1"},
+		{"4/4", 5.5, "2:2.5"},
+		{"3/4", 6, "3:1"},
+		{"6/8", 4.5, "2:4"},
+		{"6/8", 2.75, "1:6.5"},
+		{"4/4", 4 - 1e-12, "2:1"},
+		{"3/4", 1.0 / 3, "1:1.333"},
+	}
+
+	for _, tt := range tests {
+		meter, _ := ParseTimeSignature(tt.meter)
+		if got := meter.Position(tt.beats); got != tt.want {
+			t.Errorf("%s 拍号中第 %v 拍的位置 = %s，期望 %s", tt.meter, tt.beats, got, tt.want)
+		}
+	}
+}
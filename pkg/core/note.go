@@ -49,6 +49,7 @@ type Note struct {
 	Name       BaseNoteName
 	Octave     int // 八度
 	Accidental Accidental
+	FollowKey  bool // 未写变音记号，按调号升降
 	MIDINote   []byte
 	// 节拍数
 	Beat BeatValue
@@ -139,6 +140,50 @@ func NewNote(params NewNoteParams) Note {
 	return note
 }
 
+// 按MIDI音高创建音符，音名使用升号拼写
+func NewNoteFromMIDI(midiNote int, beat BeatValue) Note {
+	// C0 = 60，向下取整得到八度
+	octave := (midiNote+120)/12 - 15
+
+	note := NewNote(NewNoteParams{
+		Name:   BaseNoteName((midiNote%12 + 12) % 12),
+		Octave: octave,
+		Beat:   beat,
+	})
+	note.MIDINote = []byte{byte(midiNote)}
+	return note
+}
+
+// 移调若干半音；整八度移动时保留音名拼写，否则重新拼写：原本是降号拼写的用降号，其余用升号
+func (n Note) Transpose(semitones int) Note {
+	if semitones == 0 || len(n.MIDINote) == 0 {
+		return n
+	}
+	if semitones%12 == 0 {
+		return n.ShiftOctave(semitones / 12)
+	}
+
+	transposed := NewNoteFromMIDI(int(n.MIDINote[0])+semitones, n.Beat)
+	if n.Accidental < Natural {
+		transposed = transposed.flatSpelling()
+	}
+	transposed.TrackID = n.TrackID
+	transposed.Channel = n.Channel
+	transposed.Instrument = n.Instrument
+	transposed.Velocity = n.Velocity
+	return transposed
+}
+
+// 把升号拼写的黑键改写为上方音名的降号，如 C# 改为 Db
+func (n Note) flatSpelling() Note {
+	switch n.Name {
+	case Cs, Ds, Fs, Gs, As:
+		n.Name++
+		n.Accidental = Flat
+	}
+	return n
+}
+
 func (n Note) Duration(bpm float64) time.Duration {
 	// BPM 是每分钟的四分音符数
 	// 一个四分音符的时长 = 60秒 / BPM
@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"catRock/pkg/core"
 	"catRock/pkg/dsl/mytype"
 	"catRock/pkg/score"
 	"fmt"
@@ -195,7 +196,7 @@ func applyContainerParams(container interface{}, params map[string]interface{})
 	}
 
 	if c, ok := container.(interface{ SetChannel(int) }); ok {
-		if channel := getChannel(params); channel > 0 {
+		if channel := getChannel(params); channel >= 0 {
 			c.SetChannel(channel)
 		}
 	}
@@ -207,11 +208,141 @@ func applyContainerParams(container interface{}, params map[string]interface{})
 		}
 	}
 
+	if c, ok := container.(interface{ SetVoicing(core.Voicing) }); ok {
+		if voicing, ok := getVoicing(params); ok {
+			c.SetVoicing(voicing)
+		}
+	}
+
+	if c, ok := container.(interface{ SetKey(core.Key) }); ok {
+		if key, ok := getKey(params); ok {
+			c.SetKey(key)
+		}
+	}
+
+	if c, ok := container.(interface{ SetDynamicsMode(core.DynamicsMode) }); ok {
+		if mode, ok := getDynamicsMode(params); ok {
+			c.SetDynamicsMode(mode)
+		}
+	}
+
 	if c, ok := container.(interface{ SetBPM(float64) }); ok {
-		if bpm, ok := params["BPM"]; ok {
-			if bpmFloat, ok := bpm.(float64); ok {
-				c.SetBPM(bpmFloat)
-			}
+		if bpm, ok := getBPM(params); ok {
+			c.SetBPM(bpm)
+		}
+	}
+
+	if c, ok := container.(interface{ SetTempoRamp(from, to, beats float64) }); ok {
+		if ramp, ok := params["BPM"].(TempoRamp); ok {
+			c.SetTempoRamp(float64(ramp.From), float64(ramp.To), float64(ramp.Beats))
 		}
 	}
 }
+
+// Repeat节点 - 反复播放主体，可带跳房子结尾
+type RepeatNode struct {
+	Times    int
+	Elements []PlayableNode // 每一遍都播放的主体
+	Endings  []*EndingNode  // ending N { ... }
+	Position mytype.Position
+}
+
+// 跳房子结尾 - 第 Number 遍在主体之后播放
+type EndingNode struct {
+	Number   int
+	Elements []PlayableNode
+	Position mytype.Position
+}
+
+var _ PlayableNode = (*RepeatNode)(nil)
+
+func (r *RepeatNode) String() string {
+	return fmt.Sprintf("Repeat{Times: %d, Elements: %d, Endings: %d}",
+		r.Times, len(r.Elements), len(r.Endings))
+}
+
+func (r *RepeatNode) DetailedString(indent string) string {
+	result := fmt.Sprintf("RepeatNode x%d {\n", r.Times)
+	result += fmt.Sprintf("%s  位置: %s\n", indent, r.Position)
+
+	if len(r.Elements) > 0 {
+		result += fmt.Sprintf("%s  主体 (%d个):\n", indent, len(r.Elements))
+		for i, element := range r.Elements {
+			result += fmt.Sprintf("%s    [%d] %s", indent, i, element.DetailedString(indent+"      "))
+		}
+	}
+
+	for _, ending := range r.Endings {
+		result += fmt.Sprintf("%s  结尾 %d (%d个, 位置: %s):\n", indent, ending.Number, len(ending.Elements), ending.Position)
+		for i, element := range ending.Elements {
+			result += fmt.Sprintf("%s    [%d] %s", indent, i, element.DetailedString(indent+"      "))
+		}
+	}
+
+	result += fmt.Sprintf("%s}\n", indent)
+	return result
+}
+
+func (r *RepeatNode) ToPlayable() score.Playable {
+	repeat := score.NewRepeat(r.Times)
+
+	for _, element := range r.Elements {
+		repeat.AddElement(element.ToPlayable())
+	}
+
+	for _, ending := range r.Endings {
+		elements := make([]score.Playable, 0, len(ending.Elements))
+		for _, element := range ending.Elements {
+			elements = append(elements, element.ToPlayable())
+		}
+		repeat.AddEnding(ending.Number, elements)
+	}
+
+	return repeat
+}
+
+// 查找编号为 number 的结尾
+func (r *RepeatNode) Ending(number int) *EndingNode {
+	for _, ending := range r.Endings {
+		if ending.Number == number {
+			return ending
+		}
+	}
+	return nil
+}
+
+// 渐强/渐弱节点：cresc(n) { ... }、dim(n) { ... }、< ... >、> ... <
+type HairpinNode struct {
+	Steps    int // 正数为渐强，负数为渐弱
+	Elements []PlayableNode
+	Position mytype.Position
+}
+
+var _ PlayableNode = (*HairpinNode)(nil)
+
+func (h *HairpinNode) String() string {
+	return fmt.Sprintf("Hairpin{Steps: %d, Elements: %d}", h.Steps, len(h.Elements))
+}
+
+func (h *HairpinNode) DetailedString(indent string) string {
+	result := fmt.Sprintf("HairpinNode %+d {\n", h.Steps)
+	result += fmt.Sprintf("%s  位置: %s\n", indent, h.Position)
+
+	if len(h.Elements) > 0 {
+		result += fmt.Sprintf("%s  元素 (%d个):\n", indent, len(h.Elements))
+		for i, element := range h.Elements {
+			result += fmt.Sprintf("%s    [%d] %s", indent, i, element.DetailedString(indent+"      "))
+		}
+	}
+
+	result += fmt.Sprintf("%s}\n", indent)
+	return result
+}
+
+func (h *HairpinNode) ToPlayable() score.Playable {
+	hairpin := score.NewHairpin(h.Steps)
+	for _, element := range h.Elements {
+		hairpin.AddElement(element.ToPlayable())
+	}
+	return hairpin
+}
@@ -1,71 +1,90 @@
 package ast
 
-import "catRock/pkg/core"
-
-// 字符串转音符名称
-func stringToNoteName(name string) core.BaseNoteName {
-	switch name {
-	// 基本音符
-	case "C", "c":
-		return core.C
-	case "D", "d":
-		return core.D
-	case "E", "e":
-		return core.E
-	case "F", "f":
-		return core.F
-	case "G", "g":
-		return core.G
-	case "A", "a":
-		return core.A
-	case "B", "b":
-		return core.B
-
-	// 升号音符 (s后缀)
-	case "Cs", "cs":
-		return core.Cs
-	case "Ds", "ds":
-		return core.Ds
-	case "Fs", "fs":
-		return core.Fs
-	case "Gs", "gs":
-		return core.Gs
-	case "As", "as":
-		return core.As
-
-	// 降号音符 (b后缀) - 转换为对应的升号
-	case "Db", "db":
-		return core.Cs // Db = C#
-	case "Eb", "eb":
-		return core.Ds // Eb = D#
-	case "Gb", "gb":
-		return core.Fs // Gb = F#
-	case "Ab", "ab":
-		return core.Gs // Ab = G#
-	case "Bb", "bb":
-		return core.As // Bb = A#
+import (
+	"catRock/pkg/core"
+	"strconv"
+	"strings"
+)
 
+// 字符串转音符拼写：音名、变音以及是否显式写了变音记号。
+// 如 "Eb" 为 E 降、"Cs" 为 C 升、"En" 为还原的 E；只写字母的音符按调号升降
+func stringToNoteSpelling(name string) (core.BaseNoteName, core.Accidental, bool) {
+	if name == "" {
+		return core.C, core.Natural, false // 默认返回C
+	}
+
+	var letter core.BaseNoteName
+	switch strings.ToUpper(name[:1]) {
+	case "C":
+		letter = core.C
+	case "D":
+		letter = core.D
+	case "E":
+		letter = core.E
+	case "F":
+		letter = core.F
+	case "G":
+		letter = core.G
+	case "A":
+		letter = core.A
+	case "B":
+		letter = core.B
+	default:
+		return core.C, core.Natural, false
+	}
+
+	switch name[1:] {
+	case "s": // 升号 (s后缀)
+		return letter, core.Sharp, true
+	case "b": // 降号 (b后缀)
+		return letter, core.Flat, true
+	case "n": // 还原号 (n后缀)
+		return letter, core.Natural, true
 	default:
-		return core.C // 默认返回C
+		return letter, core.Natural, false
 	}
 }
 
-// 字符串转节拍值
+// 字符串转节拍值。
+// duration 为以全音符为单位的分数 (如 "1/4"、"3/8"，由解析器生成) 或时值名称
 func stringToBeatValue(duration string) core.BeatValue {
 	switch duration {
-	case "1/1":
+	case "whole":
 		return core.Whole
-	case "1/2":
+	case "half":
 		return core.Half
-	case "1/4":
+	case "quarter":
 		return core.Quarter
-	case "1/8":
+	case "eighth":
 		return core.Eighth
-	case "1/16":
+	case "sixteenth":
 		return core.Sixteenth
-	default:
+	}
+
+	numerator, denominator, ok := strings.Cut(duration, "/")
+	if !ok {
 		return core.Quarter
 	}
+	num, err := strconv.Atoi(numerator)
+	if err != nil || num <= 0 {
+		return core.Quarter
+	}
+	den, err := strconv.Atoi(denominator)
+	if err != nil || den <= 0 {
+		return core.Quarter
+	}
+
+	// 一拍为四分音符
+	return core.BeatValue(4 * float64(num) / float64(den))
+}
+
+// ~ 连接的时值之和
+func tiedBeatValue(duration string, tied []string) core.BeatValue {
+	total := stringToBeatValue(duration)
+	for _, d := range tied {
+		total += stringToBeatValue(d)
+	}
+	return total
 }
 
 // 从参数中提取乐器
@@ -98,18 +117,49 @@ func getVolume(params map[string]interface{}) int {
 	return 100 // 默认值
 }
 
+func getVoicing(params map[string]interface{}) (core.Voicing, bool) {
+	if name, ok := params["voicing"].(string); ok && name != "" {
+		if voicing, err := core.ParseVoicing(name); err == nil {
+			return voicing, true
+		}
+	}
+	return core.DefaultVoicing, false
+}
+
+func getKey(params map[string]interface{}) (core.Key, bool) {
+	if name, ok := params["key"].(string); ok && name != "" {
+		if key, err := core.ParseKey(name); err == nil {
+			return key, true
+		}
+	}
+	return core.Key{}, false
+}
+
+func getDynamicsMode(params map[string]interface{}) (core.DynamicsMode, bool) {
+	if name, ok := params["dynamics"].(string); ok && name != "" {
+		if mode, err := core.ParseDynamicsMode(name); err == nil {
+			return mode, true
+		}
+	}
+	return core.VelocityDynamics, false
+}
+
+func getBPM(params map[string]interface{}) (float64, bool) {
+	if bpm, ok := params["BPM"].(int); ok && bpm > 0 {
+		return float64(bpm), true
+	}
+	return 0, false
+}
+
 // 从和弦名创建和弦
-func createChordFromName(chordName string, duration string) core.Chord {
-	// 这里需要解析和弦名称，如 "Am", "Cmaj", "F#dim" 等
-	// 暂时简化实现
+func createChordFromName(chordName string, octave int, duration string) core.Chord {
 	beatValue := stringToBeatValue(duration)
 
-	// 创建一个简单的C大三和弦作为示例
-	notes := []core.Note{
-		core.NewNote(core.NewNoteParams{Name: core.C, Octave: 4, Beat: beatValue}),
-		core.NewNote(core.NewNoteParams{Name: core.E, Octave: 4, Beat: beatValue}),
-		core.NewNote(core.NewNoteParams{Name: core.G, Octave: 4, Beat: beatValue}),
+	symbol, err := core.ParseChordSymbol(chordName)
+	if err != nil {
+		// 解析阶段已报告错误，这里退回C大三和弦
+		symbol, _ = core.ParseChordSymbol("C")
 	}
 
-	return core.NewChord(notes)
+	return core.NewChord(symbol.Notes(octave, beatValue))
 }
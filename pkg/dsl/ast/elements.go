@@ -9,73 +9,147 @@ import (
 
 // 音符节点
 type NoteNode struct {
-    Name     string // C, D, E, F, G, A, B
-    Octave   int    // 0-9
-    Duration string // quarter, half, whole, eighth
-    Position mytype.Position
+    Name          string              // C, D, E, F, G, A, B
+    Octave        int                 // 0-9
+    Duration      string              // 以全音符为单位的分数，如 1/4、3/8，为空时使用默认时值
+    TiedDurations []string            // 用 ~ 连接的后续时值
+    Tie           bool                // 以延音线 _ 连到下一个同音高的音符
+    Velocity      int                 // ~120 指定的力度，0 表示未指定
+    Articulations []core.Articulation // 演奏法 ^ ^^ - * **
+    Position      mytype.Position
 }
 
 var _ ElementNode = (*NoteNode)(nil)
 
 func (n *NoteNode) String() string {
-    return fmt.Sprintf("Note{%s%d %s}", n.Name, n.Octave, n.Duration)
+    return fmt.Sprintf("Note{%s%d %s}", n.Name, n.Octave, n.formatDuration())
 }
 
 // 转换为Score系统的Playable
 func (n *NoteNode) ToPlayable() score.Playable {
-    // 转换音符名称
-    noteName := stringToNoteName(n.Name)
+    durations := append([]string{n.Duration}, n.TiedDurations...)
+    return tiedPlayable(durations, n.Tie, func(duration string, tie bool) score.Playable {
+        // 创建NoteElement
+        element := score.NewNoteElement(n.note(duration))
+        element.Tie = tie
+        element.Articulations = n.Articulations
+        if n.Velocity > 0 {
+            element.SetVolumeOverride(n.Velocity)
+        }
+        return element
+    })
+}
 
-    // 转换时值
-    beatValue := stringToBeatValue(n.Duration)
+func (n *NoteNode) formatDuration() string {
+    return formatTiedDuration(n.Duration, n.TiedDurations, n.Velocity, n.Articulations, n.Tie)
+}
 
-    // 创建core.Note
+// 按指定时值创建core.Note
+func (n *NoteNode) note(duration string) core.Note {
+    name, accidental, explicit := stringToNoteSpelling(n.Name)
     note := core.NewNote(core.NewNoteParams{
-        Name:       noteName,
+        Name:       name,
         Octave:     n.Octave,
-        Accidental: core.Natural,
-        Beat:       beatValue,
+        Accidental: accidental,
+        Beat:       stringToBeatValue(duration),
     })
-
-    // 创建NoteElement
-    return score.NewNoteElement(note)
+    note.FollowKey = !explicit
+    return note
 }
 
 // 和弦节点
 type ChordNode struct {
-    Content  interface{} // string(和弦名) 或 []*NoteNode(手动构建)
-    Duration string      // quarter, half, whole, eighth
-    Position mytype.Position
+    Content       interface{}         // string(和弦名) 或 []*NoteNode(手动构建)
+    Duration      string              // 以全音符为单位的分数，如 1/4、3/8，为空时使用各音符自身的时值
+    TiedDurations []string            // 用 ~ 连接的后续时值
+    Tie           bool                // 以延音线 _ 连到下一个和弦的相同音高
+    Octave        int                 // 和弦符号根音所在的八度
+    Inversion     int                 // 转位次数 (inv1、inv2)
+    Drop          int                 // drop 排列 (drop2、drop3)
+    Velocity      int                 // ~120 指定的力度，0 表示未指定
+    Articulations []core.Articulation // 演奏法 ^ ^^ - * **
+    Position      mytype.Position
 }
 
 var _ ElementNode = (*ChordNode)(nil)
 
 func (c *ChordNode) String() string {
-    return fmt.Sprintf("Chord{%v %s}", c.Content, c.Duration)
+    return fmt.Sprintf("Chord{%v %s}", c.Content, c.formatDuration())
 }
 
 func (c *ChordNode) ToPlayable() score.Playable {
+    durations := append([]string{c.Duration}, c.TiedDurations...)
+    return tiedPlayable(durations, c.Tie, func(duration string, tie bool) score.Playable {
+        element := score.NewChordElement(c.chord(duration))
+        element.Tie = tie
+        element.Inversion = c.Inversion
+        element.Drop = c.Drop
+        element.Articulations = c.Articulations
+        if c.Velocity > 0 {
+            element.SetVolumeOverride(c.Velocity)
+        }
+        return element
+    })
+}
+
+func (c *ChordNode) formatDuration() string {
+    return formatTiedDuration(c.Duration, c.TiedDurations, c.Velocity, c.Articulations, c.Tie)
+}
+
+// 按指定时值创建和弦，duration 为空时手动构建的和弦保留各音符的时值
+func (c *ChordNode) chord(duration string) core.Chord {
     // 根据Content类型创建和弦
-    var chord core.Chord
-    
     switch content := c.Content.(type) {
     case string:
-        // 从和弦名创建，如 "Am", "Cmaj"
-        chord = createChordFromName(content, c.Duration)
+        // 从和弦符号创建，如 "Am", "Cmaj7", "G7/B"
+        return createChordFromName(content, c.Octave, duration)
     case []*NoteNode:
         // 从音符列表手动构建
         notes := make([]core.Note, len(content))
         for i, noteNode := range content {
-            noteElement := noteNode.ToPlayable().(*score.NoteElement)
-            notes[i] = noteElement.Note
+            notes[i] = noteNode.note(noteNode.Duration)
+            notes[i].Beat = tiedBeatValue(noteNode.Duration, noteNode.TiedDurations)
+            if duration != "" {
+                notes[i].Beat = stringToBeatValue(duration)
+            }
         }
-        chord = core.NewChord(notes)
+        return core.NewChord(notes)
     default:
         // 默认创建C大三和弦
-        chord = createChordFromName("C", c.Duration)
+        return createChordFromName("C", c.Octave, duration)
+    }
+}
+
+// 按 ~ 连接的时值依次生成以延音线相连的元素，只有一个时值时直接返回该元素。
+// tie 表示最后一个元素是否再连到后面的元素
+func tiedPlayable(durations []string, tie bool, build func(duration string, tie bool) score.Playable) score.Playable {
+    if len(durations) == 1 {
+        return build(durations[0], tie)
     }
 
-    return score.NewChordElement(chord)
+    group := score.NewGroupElement()
+    for i, duration := range durations {
+        group.AddElement(build(duration, i < len(durations)-1 || tie))
+    }
+    return group
+}
+
+// 时值的书写形式，含 ~ 连接的时值、力度、演奏法和延音线
+func formatTiedDuration(duration string, tied []string, velocity int, articulations []core.Articulation, tie bool) string {
+    result := duration
+    for _, d := range tied {
+        result += "~" + d
+    }
+    if velocity > 0 {
+        result += fmt.Sprintf("~%d", velocity)
+    }
+    for _, articulation := range articulations {
+        result += articulation.Symbol()
+    }
+    if tie {
+        result += " _"
+    }
+    return result
 }
 
 // 休止符节点
@@ -88,13 +162,16 @@ var _ ElementNode = (*RestNode)(nil)
 
 func (n *NoteNode) DetailedString(indent string) string {
     return fmt.Sprintf("NoteNode { 音符:%s%d, 时值:%s, 位置:%s }\n", 
-        n.Name, n.Octave, n.Duration, n.Position)
+        n.Name, n.Octave, n.formatDuration(), n.Position)
 }
 
 func (c *ChordNode) DetailedString(indent string) string {
     result := fmt.Sprintf("ChordNode {\n")
     result += fmt.Sprintf("%s  内容: %v (%T)\n", indent, c.Content, c.Content)
-    result += fmt.Sprintf("%s  时值: %s\n", indent, c.Duration)
+    result += fmt.Sprintf("%s  时值: %s\n", indent, c.formatDuration())
+    if c.Inversion > 0 || c.Drop > 0 {
+        result += fmt.Sprintf("%s  排列: inv%d drop%d\n", indent, c.Inversion, c.Drop)
+    }
     result += fmt.Sprintf("%s  位置: %s\n", indent, c.Position)
     result += fmt.Sprintf("%s}\n", indent)
     return result
@@ -130,6 +207,45 @@ func (r *RestNode) ToPlayable() score.Playable {
     return score.NewRestElement(rest)
 }
 
+// 小节线节点
+type BarlineNode struct {
+    Position mytype.Position
+}
+
+var _ ElementNode = (*BarlineNode)(nil)
+
+func (b *BarlineNode) String() string {
+    return "Barline"
+}
+
+func (b *BarlineNode) DetailedString(indent string) string {
+    return fmt.Sprintf("BarlineNode { 位置:%s }\n", b.Position)
+}
+
+func (b *BarlineNode) ToPlayable() score.Playable {
+    return score.NewBarline(b.Position.String())
+}
+
+// 力度记号节点，如 mf
+type DynamicNode struct {
+    Dynamic  core.Dynamic
+    Position mytype.Position
+}
+
+var _ ElementNode = (*DynamicNode)(nil)
+
+func (d *DynamicNode) String() string {
+    return fmt.Sprintf("Dynamic{%s}", d.Dynamic)
+}
+
+func (d *DynamicNode) DetailedString(indent string) string {
+    return fmt.Sprintf("DynamicNode { 力度:%s 位置:%s }\n", d.Dynamic, d.Position)
+}
+
+func (d *DynamicNode) ToPlayable() score.Playable {
+    return score.NewDynamicMark(d.Dynamic)
+}
+
 type GroupNode struct {
     Elements []ElementNode // 包含的元素
     Duration string // 可选的持续时间
@@ -147,7 +263,7 @@ func (g *GroupNode) ToPlayable() score.Playable {
     // 创建 GroupElement
     var group *score.GroupElement
     
-    if g.Duration != "" && g.Duration != "1/4" { // 如果有指定时值
+    if g.Duration != "" { // 如果有指定时值
         duration := stringToBeatValue(g.Duration)
         group = score.NewGroupElementWithDuration(float64(duration))
     } else {
@@ -7,8 +7,10 @@ import (
 
 // 顶层Score节点
 type ScoreNode struct {
-    GlobalSets []*SetNode     // 全局设置
-    Elements   []PlayableNode // 顶层可播放元素
+    GlobalSets []*SetNode      // 全局设置
+    Imports    []*ImportNode   // 导入的文件
+    Templates  []*TemplateNode // 模板定义
+    Elements   []PlayableNode  // 顶层可播放元素
     Position   mytype.Position
 }
 
@@ -28,6 +30,20 @@ func (s *ScoreNode) DetailedString(indent string) string {
         }
     }
     
+    if len(s.Imports) > 0 {
+        result += fmt.Sprintf("%s  导入 (%d个):\n", indent, len(s.Imports))
+        for i, imported := range s.Imports {
+            result += fmt.Sprintf("%s    [%d] %s", indent, i, imported.DetailedString(indent+"      "))
+        }
+    }
+    
+    if len(s.Templates) > 0 {
+        result += fmt.Sprintf("%s  模板 (%d个):\n", indent, len(s.Templates))
+        for i, template := range s.Templates {
+            result += fmt.Sprintf("%s    [%d] %s", indent, i, template.DetailedString(indent+"      "))
+        }
+    }
+    
     if len(s.Elements) > 0 {
         result += fmt.Sprintf("%s  顶层元素 (%d个):\n", indent, len(s.Elements))
         for i, element := range s.Elements {
@@ -1,8 +1,10 @@
 package ast
 
 import (
+	"catRock/pkg/core"
 	"catRock/pkg/dsl/mytype"
 	"fmt"
+	"strings"
 )
 
 // 参数规范
@@ -12,6 +14,8 @@ type ParameterSpec struct {
     DefaultValue interface{}
     Required     bool
     Description  string
+    Values       []string                      // 字符串参数的可选值，为空时不限制
+    Validate     func(value interface{}) error // 额外的取值检查，可为空
 }
 
 type ParameterType int
@@ -21,8 +25,20 @@ const (
     ParamFloat
     ParamString
     ParamBool
+    ParamTempo // 整数速度或速度渐变
 )
 
+// 速度渐变的参数值，如 BPM: 120 -> 90 over 4
+type TempoRamp struct {
+    From  int
+    To    int
+    Beats int
+}
+
+func (r TempoRamp) String() string {
+    return fmt.Sprintf("%d -> %d over %d", r.From, r.To, r.Beats)
+}
+
 // 参数上下文
 type ParameterContext int
 
@@ -32,6 +48,26 @@ const (
     SectionContext
 )
 
+// 全局、音轨和段落通用的力度模式，未设置时沿用外层容器，最外层为 velocity
+var dynamicsParameter = ParameterSpec{
+	Name:         "dynamics",
+	Type:         ParamString,
+	DefaultValue: "",
+	Required:     false,
+	Description:  "力度记号的演奏方式：velocity 改变音符力度，expression 发送表情控制器",
+	Values:       []string{"velocity", "expression"},
+}
+
+// 全局、音轨和段落通用的八度写法，未设置时沿用外层容器，最外层为 absolute
+var octaveModeParameter = ParameterSpec{
+	Name:         "octave_mode",
+	Type:         ParamString,
+	DefaultValue: "",
+	Required:     false,
+	Description:  "八度写法：absolute 每个音符写出八度，relative 省略八度时取离上一个音符最近的八度",
+	Values:       []string{"absolute", "relative"},
+}
+
 // 全局参数规范
 var GlobalParameters = map[string]ParameterSpec{
     "BPM": {
@@ -48,6 +84,24 @@ var GlobalParameters = map[string]ParameterSpec{
         Required:     false,
         Description:  "默认音符时值",
     },
+    "key": {
+        Name:         "key",
+        Type:         ParamString,
+        DefaultValue: "", // C 大调
+        Required:     false,
+        Description:  "调号，如 Eb_major、fs_minor",
+        Validate:     validateKey,
+    },
+    "time": {
+        Name:         "time",
+        Type:         ParamString,
+        DefaultValue: "", // 4/4
+        Required:     false,
+        Description:  "拍号，如 3/4、6/8",
+        Validate:     validateTimeSignature,
+    },
+	"dynamics":    dynamicsParameter,
+	"octave_mode": octaveModeParameter,
 }
 
 // Track参数规范
@@ -73,6 +127,31 @@ var TrackParameters = map[string]ParameterSpec{
         Required:     false,
         Description:  "音量 (0-127)",
     },
+    "voicing": {
+        Name:         "voicing",
+        Type:         ParamString,
+        DefaultValue: "", // 未设置时沿用外层容器
+        Required:     false,
+        Description:  "和弦排列方式",
+        Values:       []string{"close", "open", "spread"},
+    },
+    "key": {
+        Name:         "key",
+        Type:         ParamString,
+        DefaultValue: "", // 未设置时沿用外层容器
+        Required:     false,
+        Description:  "调号，如 Eb_major、fs_minor",
+        Validate:     validateKey,
+    },
+    "BPM": {
+        Name:         "BPM",
+        Type:         ParamTempo,
+        DefaultValue: 0, // 未设置时沿用外层容器
+        Required:     false,
+        Description:  "每分钟节拍数或渐变 (如 120 -> 90 over 4)，只在该容器内生效",
+    },
+	"dynamics":    dynamicsParameter,
+	"octave_mode": octaveModeParameter,
 }
 
 // Section参数规范
@@ -98,6 +177,31 @@ var SectionParameters = map[string]ParameterSpec{
         Required:     false,
         Description:  "音量 (0-127)",
     },
+    "voicing": {
+        Name:         "voicing",
+        Type:         ParamString,
+        DefaultValue: "", // 未设置时沿用外层容器
+        Required:     false,
+        Description:  "和弦排列方式",
+        Values:       []string{"close", "open", "spread"},
+    },
+    "key": {
+        Name:         "key",
+        Type:         ParamString,
+        DefaultValue: "", // 未设置时沿用外层容器
+        Required:     false,
+        Description:  "调号，如 Eb_major、fs_minor",
+        Validate:     validateKey,
+    },
+    "BPM": {
+        Name:         "BPM",
+        Type:         ParamTempo,
+        DefaultValue: 0, // 未设置时沿用外层容器
+        Required:     false,
+        Description:  "每分钟节拍数或渐变 (如 120 -> 90 over 4)，只在该容器内生效",
+    },
+	"dynamics":    dynamicsParameter,
+	"octave_mode": octaveModeParameter,
 }
 
 // Set设置节点
@@ -176,6 +280,14 @@ func (s *SetNode) ResolveParameters() (map[string]interface{}, error) {
         if err != nil {
             return nil, fmt.Errorf("参数 %s 类型错误: %v", key, err)
         }
+        if len(spec.Values) > 0 && !containsString(spec.Values, converted) {
+            return nil, fmt.Errorf("参数 %s 的值 %v 无效 (可选: %s)", key, converted, strings.Join(spec.Values, "、"))
+        }
+        if spec.Validate != nil {
+            if err := spec.Validate(converted); err != nil {
+                return nil, fmt.Errorf("参数 %s 的值无效: %v", key, err)
+            }
+        }
 
         resolved[key] = converted
     }
@@ -202,6 +314,14 @@ func (s *SetNode) convertParameter(value interface{}, paramType ParameterType) (
             return v, nil
         }
         return nil, fmt.Errorf("期望浮点数类型")
+    case ParamTempo:
+        switch v := value.(type) {
+        case int:
+            return v, nil
+        case TempoRamp:
+            return v, nil
+        }
+        return nil, fmt.Errorf("期望整数或速度渐变 (如 120 -> 90 over 4)")
     case ParamString:
         if v, ok := value.(string); ok {
             return v, nil
@@ -214,4 +334,31 @@ func (s *SetNode) convertParameter(value interface{}, paramType ParameterType) (
         return nil, fmt.Errorf("期望布尔类型")
     }
     return nil, fmt.Errorf("未知参数类型")
-}
\ No newline at end of file
+}
+
+// 调号参数需能被解析
+func validateKey(value interface{}) error {
+    if name, ok := value.(string); ok && name != "" {
+        _, err := core.ParseKey(name)
+        return err
+    }
+    return nil
+}
+
+// 拍号参数需能被解析
+func validateTimeSignature(value interface{}) error {
+    if text, ok := value.(string); ok && text != "" {
+        _, err := core.ParseTimeSignature(text)
+        return err
+    }
+    return nil
+}
+
+func containsString(values []string, value interface{}) bool {
+    for _, v := range values {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
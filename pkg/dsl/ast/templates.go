@@ -0,0 +1,141 @@
+package ast
+
+import (
+	"catRock/pkg/dsl/mytype"
+	"catRock/pkg/score"
+	"fmt"
+	"strings"
+)
+
+// Template节点 - 顶层的模板定义，内容在 use 时按参数展开
+type TemplateNode struct {
+	Name     string
+	Params   []string
+	Source   string // 模板内容的书写形式，用于调试输出
+	Position mytype.Position
+}
+
+var _ ASTNode = (*TemplateNode)(nil)
+
+func (t *TemplateNode) String() string {
+	return fmt.Sprintf("Template{Name: %s, Params: %v}", t.Name, t.Params)
+}
+
+func (t *TemplateNode) DetailedString(indent string) string {
+	result := fmt.Sprintf("TemplateNode '%s' {\n", t.Name)
+	result += fmt.Sprintf("%s  位置: %s\n", indent, t.Position)
+	if len(t.Params) > 0 {
+		result += fmt.Sprintf("%s  参数: %s\n", indent, strings.Join(t.Params, ", "))
+	}
+	result += fmt.Sprintf("%s  内容: %s\n", indent, t.Source)
+	result += fmt.Sprintf("%s}\n", indent)
+	return result
+}
+
+// Import节点 - 导入另一个 .crock 文件中定义的模板、音轨和段落
+type ImportNode struct {
+	Path     string // 书写的路径
+	Alias    string // as 之后的别名，为空时直接使用原名
+	File     string // 相对于导入它的文件解析后的路径
+	Position mytype.Position
+}
+
+var _ ASTNode = (*ImportNode)(nil)
+
+func (i *ImportNode) String() string {
+	return fmt.Sprintf("Import{Path: %s, Alias: %s}", i.Path, i.Alias)
+}
+
+func (i *ImportNode) DetailedString(indent string) string {
+	result := fmt.Sprintf("ImportNode \"%s\" {\n", i.Path)
+	result += fmt.Sprintf("%s  位置: %s\n", indent, i.Position)
+	result += fmt.Sprintf("%s  文件: %s\n", indent, i.File)
+	if i.Alias != "" {
+		result += fmt.Sprintf("%s  别名: %s\n", indent, i.Alias)
+	}
+	result += fmt.Sprintf("%s}\n", indent)
+	return result
+}
+
+// Use节点 - 模板的一次使用，Body 为按实参展开后的内容
+type UseNode struct {
+	Name string
+	Args []string // 实参的书写形式
+
+	// 变换修饰
+	Transpose     int     // transpose(+12)
+	TimeScale     float64 // augment(2) / diminish(2)，1 为不变
+	VelocityScale float64 // velocity(0.8)，1 为不变
+
+	Body     *SectionNode
+	Element  PlayableNode // 引用导入的音轨或段落时为被引用的节点，此时 Body 为空
+	Position mytype.Position
+}
+
+var _ PlayableNode = (*UseNode)(nil)
+
+func (u *UseNode) String() string {
+	return fmt.Sprintf("Use{Name: %s, Args: %v}", u.Name, u.Args)
+}
+
+// 修饰的书写形式
+func (u *UseNode) modifiers() string {
+	modifiers := []string{}
+	if u.Transpose != 0 {
+		modifiers = append(modifiers, fmt.Sprintf("transpose(%+d)", u.Transpose))
+	}
+	if u.TimeScale != 1 {
+		modifiers = append(modifiers, fmt.Sprintf("时值x%g", u.TimeScale))
+	}
+	if u.VelocityScale != 1 {
+		modifiers = append(modifiers, fmt.Sprintf("velocity(%g)", u.VelocityScale))
+	}
+	return strings.Join(modifiers, " ")
+}
+
+func (u *UseNode) DetailedString(indent string) string {
+	result := fmt.Sprintf("UseNode '%s' {\n", u.Name)
+	result += fmt.Sprintf("%s  位置: %s\n", indent, u.Position)
+	if len(u.Args) > 0 {
+		result += fmt.Sprintf("%s  实参: %s\n", indent, strings.Join(u.Args, ", "))
+	}
+	if modifiers := u.modifiers(); modifiers != "" {
+		result += fmt.Sprintf("%s  修饰: %s\n", indent, modifiers)
+	}
+	if u.Body != nil {
+		result += fmt.Sprintf("%s  展开: %s", indent, u.Body.DetailedString(indent+"    "))
+	}
+	if u.Element != nil {
+		result += fmt.Sprintf("%s  引用: %s", indent, u.Element.DetailedString(indent+"    "))
+	}
+	result += fmt.Sprintf("%s}\n", indent)
+	return result
+}
+
+func (u *UseNode) ToPlayable() score.Playable {
+	var playable score.Playable
+	if u.Element != nil {
+		playable = u.Element.ToPlayable()
+	} else {
+		body := u.Body
+		if body == nil {
+			// 未展开 (模板缺失等错误已在解析阶段报告)
+			body = &SectionNode{Name: u.Name}
+		}
+
+		playable = body.ToPlayable()
+		if section, ok := playable.(*score.Section); ok {
+			section.ID = fmt.Sprintf("template_%s", u.Name)
+		}
+	}
+
+	if u.Transpose == 0 && u.TimeScale == 1 && u.VelocityScale == 1 {
+		return playable
+	}
+
+	transform := score.NewTransform(playable)
+	transform.Transpose = u.Transpose
+	transform.TimeScale = u.TimeScale
+	transform.VelocityScale = u.VelocityScale
+	return transform
+}
@@ -1,6 +1,7 @@
 package dsl
 
 import (
+	"catRock/pkg/core"
 	"catRock/pkg/dsl/ast"
 	"catRock/pkg/score"
 	"fmt"
@@ -77,6 +78,15 @@ func (g *Generator) GenerateScore(scoreNode *ast.ScoreNode) (*score.Score, error
     
     scoreObj.RootElement = rootElement
     
+    // 检查小节线之间的长度是否符合拍号
+    if barErrors := scoreObj.CheckBars(); len(barErrors) > 0 {
+        messages := make([]string, len(barErrors))
+        for i, barError := range barErrors {
+            messages[i] = barError.Error()
+        }
+        return nil, fmt.Errorf("小节长度与拍号 %s 不符:\n   %s", scoreObj.Time, strings.Join(messages, "\n   "))
+    }
+    
     return scoreObj, nil
 }
 
@@ -92,9 +102,11 @@ func (g *Generator) applyGlobalSettings(scoreObj *score.Score, globalSets []*ast
             return fmt.Errorf("解析全局参数失败: %v", err)
         }
         
-        // 后面的设置覆盖前面的
+        // 后面写出的设置覆盖前面的，未写的参数只补默认值
         for key, value := range resolved {
-            globalParams[key] = value
+            if _, written := setNode.Parameters[key]; written || globalParams[key] == nil {
+                globalParams[key] = value
+            }
         }
     }
     
@@ -113,6 +125,30 @@ func (g *Generator) applyGlobalSettings(scoreObj *score.Score, globalSets []*ast
         }
     }
     
+    if keyValue, ok := globalParams["key"].(string); ok && keyValue != "" {
+        key, err := core.ParseKey(keyValue)
+        if err != nil {
+            return fmt.Errorf("解析全局参数失败: %v", err)
+        }
+        scoreObj.SetKey(key)
+    }
+    
+    if timeValue, ok := globalParams["time"].(string); ok && timeValue != "" {
+        time, err := core.ParseTimeSignature(timeValue)
+        if err != nil {
+            return fmt.Errorf("解析全局参数失败: %v", err)
+        }
+        scoreObj.SetTime(time)
+    }
+    
+    if dynamicsValue, ok := globalParams["dynamics"].(string); ok && dynamicsValue != "" {
+        mode, err := core.ParseDynamicsMode(dynamicsValue)
+        if err != nil {
+            return fmt.Errorf("解析全局参数失败: %v", err)
+        }
+        scoreObj.SetDynamicsMode(mode)
+    }
+    
     // 可以添加更多全局设置的处理...
     
     return nil
@@ -0,0 +1,318 @@
+package dsl
+
+import (
+	"catRock/pkg/score"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var pitchNames = []string{"C", "Cs", "D", "Ds", "E", "F", "Fs", "G", "Gs", "A", "As", "B"}
+
+// 解析并生成乐谱，要求没有解析和生成错误
+func generate(t *testing.T, source string) *score.Score {
+	t.Helper()
+	scoreObj, err := NewGenerator().GenerateScore(mustParse(t, source))
+	if err != nil {
+		t.Fatalf("生成 %q 失败: %v", source, err)
+	}
+	return scoreObj
+}
+
+// 生成的错误，没有错误时返回空串
+func generateError(t *testing.T, source string) string {
+	t.Helper()
+	if _, err := NewGenerator().GenerateScore(mustParse(t, source)); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// 把 NOTE_ON 事件写成 "音名@拍/时长"，如 "C4@0/1"，同一时刻按音高排列；
+// withVelocity 为 true 时附加 "~力度"
+func describeEvents(t *testing.T, scoreObj *score.Score, withVelocity bool) string {
+	t.Helper()
+	events, err := score.NewPlayEngine(scoreObj).GenerateEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var notes []score.Event
+	for _, event := range events {
+		if event.Action == score.NOTE_ON {
+			notes = append(notes, event)
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Time != notes[j].Time {
+			return notes[i].Time < notes[j].Time
+		}
+		return notes[i].Data.(byte) < notes[j].Data.(byte)
+	})
+
+	parts := make([]string, len(notes))
+	for i, event := range notes {
+		midi := int(event.Data.(byte))
+		parts[i] = fmt.Sprintf("%s%d@%.4g/%.4g", pitchNames[midi%12], midi/12-5, event.Time, event.Duration)
+		if withVelocity {
+			parts[i] += fmt.Sprintf("~%d", event.Velocity)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// 生成 section s { body } 的音符事件
+func sectionEvents(t *testing.T, settings, body string) string {
+	t.Helper()
+	return describeEvents(t, generate(t, settings+"\ntrack t {\n section s {\n"+body+"\n }\n}\n"), false)
+}
+
+type syntaxTest struct {
+	name     string
+	settings string
+	body     string
+	want     string
+}
+
+func runSyntaxTests(t *testing.T, tests []syntaxTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sectionEvents(t, tt.settings, tt.body); got != tt.want {
+				t.Errorf("%s\n得到 %s\n期望 %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+// 期望解析出错且错误信息包含 want
+func expectParseError(t *testing.T, source string, want string) {
+	t.Helper()
+	errors := parseErrors(source)
+	for _, err := range errors {
+		if strings.Contains(err, want) {
+			return
+		}
+	}
+	t.Errorf("解析 %q 期望包含 %q 的错误，得到 %v", source, want, errors)
+}
+
+func inSection(body string) string {
+	return "track t {\n section s {\n" + body + "\n }\n}\n"
+}
+
+func TestDurations(t *testing.T) {
+	runSyntaxTests(t, []syntaxTest{
+		{"分数", "", "C4/4 D4/8 E4/2 F4/32", "C4@0/1 D4@1/0.5 E4@1.5/2 F4@3.5/0.125"},
+		{"附点", "", "C4/4. D4/8. E4/4..", "C4@0/1.5 D4@1.5/0.75 E4@2.25/1.75"},
+		{"任意比例", "", "C4/3:8 D4/5:16 E4/3:8.", "C4@0/1.5 D4@1.5/1.25 E4@2.75/2.25"},
+		{"时值名称", "", "C4 half. D4 quarter E4 eighth", "C4@0/3 D4@3/1 E4@4/0.5"},
+		{"默认时值", "", "C4 D4/8 rest E4", "C4@0/1 D4@1/0.5 E4@2.5/1"},
+		{"休止符", "", "rest/2 C4 rest/8. D4", "C4@2/1 D4@3.75/1"},
+	})
+
+	errors := []struct {
+		body string
+		want string
+	}{
+		{"C4/3", "时值 /3 不是2的幂"},
+		{"C4/0:8", "无效的时值分子: 0"},
+		{"C4/3:0", "无效的时值分母: 0"},
+		{"C4/0", "无效的时值: 0"},
+	}
+	for _, tt := range errors {
+		expectParseError(t, inSection(tt.body), tt.want)
+	}
+}
+
+func TestTies(t *testing.T) {
+	runSyntaxTests(t, []syntaxTest{
+		{"连接时值", "", "C4/2~/16 D4", "C4@0/2.25 D4@2.25/1"},
+		{"多个连接时值", "", "C4/4~/8~/16", "C4@0/1.75"},
+		{"延音线", "", "C4/4 _ C4/8 D4", "C4@0/1.5 D4@1.5/1"},
+		{"延音线连续相连", "", "C4/4 _ C4/4 _ C4/2", "C4@0/4"},
+		{"和弦中相同音高相连", "", "[C4 E4]/2 _ [C4 G4]/4", "C4@0/3 E4@0/2 G4@2/1"},
+		{"不同音高不相连", "", "C4/4 _ D4/4", "C4@0/1 D4@1/1"},
+	})
+
+	t.Run("跨段落", func(t *testing.T) {
+		source := inSection("section verse { C4/2 _ }\nsection chorus { C4/4 D4 }")
+		if got, want := describeEvents(t, generate(t, source), false), "C4@0/3 D4@3/1"; got != want {
+			t.Errorf("得到 %s，期望 %s", got, want)
+		}
+	})
+}
+
+func TestGroups(t *testing.T) {
+	runSyntaxTests(t, []syntaxTest{
+		{"普通分组", "", "(C4 D4/8) E4", "C4@0/1 D4@1/0.5 E4@1.5/1"},
+		{"三连音", "", "(C4/8 D4/8 E4/8)/4 F4", "C4@0/0.3333 D4@0.3333/0.3333 E4@0.6667/0.3333 F4@1/1"},
+		{"五连音", "", "(C4/16 D4/16 E4/16 F4/16 G4/16)/4",
+			"C4@0/0.2 D4@0.2/0.2 E4@0.4/0.2 F4@0.6/0.2 G4@0.8/0.2"},
+		{"嵌套分组比例相乘", "", "((C4/8 D4/8 E4/8)/4 F4/4)/4",
+			"C4@0/0.1667 D4@0.1667/0.1667 E4@0.3333/0.1667 F4@0.5/0.5"},
+		{"分组中的和弦", "", "([C4 E4]/8 D4/8 E4/8)/4", "C4@0/0.3333 E4@0/0.3333 D4@0.3333/0.3333 E4@0.6667/0.3333"},
+	})
+}
+
+func TestChordVoicing(t *testing.T) {
+	runSyntaxTests(t, []syntaxTest{
+		{"第一转位", "", "[C4 E4 G4] inv1", "E4@0/1 G4@0/1 C5@0/1"},
+		{"第二转位", "", "[C4 E4 G4]/2 inv2", "G4@0/2 C5@0/2 E5@0/2"},
+		{"drop2", "", "[C4 E4 G4 B4] drop2", "G3@0/1 C4@0/1 E4@0/1 B4@0/1"},
+		{"和弦符号", "", "[Cmaj7]", "C4@0/1 E4@0/1 G4@0/1 B4@0/1"},
+		{"和弦符号的八度", "", `["G7"]/2 oct3`, "G3@0/2 B3@0/2 D4@0/2 F4@0/2"},
+		{"和弦符号转位", "", "[Am] oct3 inv1", "C4@0/1 E4@0/1 A4@0/1"},
+		{"open 排列", "", "set { voicing: open }\n[C4 E4 G4]", "C4@0/1 G4@0/1 E5@0/1"},
+		{"先转位再排列", "", "set { voicing: open }\n[C4 E4 G4] inv1", "E4@0/1 C5@0/1 G5@0/1"},
+	})
+}
+
+func TestRepeats(t *testing.T) {
+	runSyntaxTests(t, []syntaxTest{
+		{"反复", "", "repeat 3 { C4 } D4", "C4@0/1 C4@1/1 C4@2/1 D4@3/1"},
+		{"跳房子结尾", "", "repeat 2 { C4 D4 } ending 1 { E4 } ending 2 { F4/2 } G4",
+			"C4@0/1 D4@1/1 E4@2/1 C4@3/1 D4@4/1 F4@5/2 G4@7/1"},
+		{"部分遍数没有结尾", "", "repeat 3 { C4 } ending 2 { D4 }", "C4@0/1 C4@1/1 D4@2/1 C4@3/1"},
+		{"嵌套反复", "", "repeat 2 { C4 repeat 2 { D4/8 } }",
+			"C4@0/1 D4@1/0.5 D4@1.5/0.5 C4@2/1 D4@3/0.5 D4@3.5/0.5"},
+	})
+
+	errors := []struct {
+		body string
+		want string
+	}{
+		{"repeat 2 { C4 } ending 3 { D4 }", "结尾编号 3 超出反复次数 1-2"},
+		{"repeat 2 { C4 } ending 0 { D4 }", "结尾编号 0 超出反复次数 1-2"},
+		{"repeat 2 { C4 } ending 1 { D4 } ending 1 { E4 }", "结尾 1 重复定义"},
+		{"repeat 2 { set { volume: 80 } C4 }", "repeat 内不能使用 set"},
+	}
+	for _, tt := range errors {
+		expectParseError(t, inSection(tt.body), tt.want)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"参数替换", "template arp(root, third) { root/8 third/8 }\n" + inSection("use arp(C4, E4) use arp([D4 F4], A4)"),
+			"C4@0/0.5 E4@0.5/0.5 D4@1/0.5 F4@1/0.5 A4@1.5/0.5"},
+		{"先使用后定义", inSection("use riff D4") + "template riff { C4/2 }", "C4@0/2 D4@2/1"},
+		{"移调", "template riff { C4 E4 }\n" + inSection("use riff transpose(+12) use riff transpose(-1)"),
+			"C5@0/1 E5@1/1 B3@2/1 Ds4@3/1"},
+		{"时值缩放", "template riff { C4 E4/2 }\n" + inSection("use riff augment(2) use riff diminish(2)"),
+			"C4@0/2 E4@2/4 C4@6/0.5 E4@6.5/1"},
+		{"模板嵌套", "template outer { C4 use inner }\ntemplate inner { D4 }\n" + inSection("use outer transpose(+2)"),
+			"D4@0/1 E4@1/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeEvents(t, generate(t, tt.source), false); got != tt.want {
+				t.Errorf("得到 %s\n期望 %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("力度缩放", func(t *testing.T) {
+		source := "template riff { C4~100 }\n" + inSection("use riff velocity(0.5)")
+		if got, want := describeEvents(t, generate(t, source), true), "C4@0/1~50"; got != want {
+			t.Errorf("得到 %s，期望 %s", got, want)
+		}
+	})
+
+	expectParseError(t, "template ping { use pong }\ntemplate pong { use ping }\n"+inSection("use ping"), "循环")
+	if len(parseErrors(inSection("use missing"))) == 0 {
+		t.Error("使用未定义的模板期望解析错误")
+	}
+}
+
+func TestVelocityAndArticulations(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"C4~120 D4", "C4@0/1~120 D4@1/1~100"},
+		{"C4/2~40", "C4@0/2~40"},
+		{"C4^ D4^^ E4-", "C4@0/1~125 D4@1/0.75~127 E4@2/1~110"},
+		{"C4* D4** E4", "C4@0/0.5~100 D4@1/0.25~100 E4@2/1~100"},
+		{"C4~80^*", "C4@0/0.5~100"},
+		{"[C4 E4]/2~90*", "C4@0/1~90 E4@0/1~90"},
+		{"C4/2~/8* _ C4/4", "C4@0/3.5~100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			if got := describeEvents(t, generate(t, inSection(tt.body)), true); got != tt.want {
+				t.Errorf("得到 %s，期望 %s", got, tt.want)
+			}
+		})
+	}
+
+	expectParseError(t, inSection("C4~120~/8"), "~ 连接的时值须写在力度和演奏法之前")
+}
+
+func TestKeySignatures(t *testing.T) {
+	runSyntaxTests(t, []syntaxTest{
+		{"降号调", "set { key: Eb_major }", "E4 A4 B4 C4", "Ds4@0/1 Gs4@1/1 As4@2/1 C4@3/1"},
+		{"显式变音记号不受调号影响", "set { key: Eb_major }", "En4 Fs4 Bb4 Cs4", "E4@0/1 Fs4@1/1 As4@2/1 Cs4@3/1"},
+		{"小调", "set { key: fs_minor }", "F4 C4 G4 A4", "Fs4@0/1 Cs4@1/1 Gs4@2/1 A4@3/1"},
+		{"手动构建的和弦按调号", "set { key: D_major }", "[D4 F4 A4]", "D4@0/1 Fs4@0/1 A4@0/1"},
+		{"和弦符号不受调号影响", "set { key: Eb_major }", "[Em]", "E4@0/1 G4@0/1 B4@0/1"},
+		{"段落覆盖调号", "set { key: Eb_major }", "set { key: C_major }\nE4", "E4@0/1"},
+	})
+}
+
+func TestBarlines(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		body     string
+		want     string // 生成错误中应包含的内容，为空时期望没有错误
+	}{
+		{"小节长度正确", "set { time: 3/4 }", "C4 D4 E4 | F4/2 G4 |", ""},
+		{"最后一小节可以不完整", "", "C4/1 | D4", ""},
+		{"少了", "set { time: 3/4 }", "C4 D4 E4 | F4/2 G4 | A4/2 |", "第3小节少了 1/4"},
+		{"多了", "", "C4/1 | C4/1 C4/8 |", "第2小节多了 1/8"},
+		{"6/8 拍", "set { time: 6/8 }", "C4/4. D4/4. | E4/2 |", "第2小节少了 1/4"},
+		{"反复按展开后计数", "", "repeat 2 { C4/1 | } D4/2 |", "第3小节少了 1/2"},
+		{"连音", "set { time: 2/4 }", "(C4/8 D4/8 E4/8)/4 F4 |", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := generateError(t, tt.settings+"\n"+inSection(tt.body))
+			if tt.want == "" && got != "" {
+				t.Errorf("期望没有错误，得到 %s", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("期望包含 %q 的错误，得到 %q", tt.want, got)
+			}
+		})
+	}
+
+	expectParseError(t, "track t {\n C4/4 |\n}\n", "轨道内不能直接使用小节线")
+	expectParseError(t, inSection("(C4 | D4)"), "3:5 - 期望可播放元素，得到 |")
+}
+
+func TestRelativeOctaves(t *testing.T) {
+	relative := "set { octave_mode: relative }"
+	runSyntaxTests(t, []syntaxTest{
+		{"就近的八度", relative, "C4 D E F G A B C", "C4@0/1 D4@1/1 E4@2/1 F4@3/1 G4@4/1 A4@5/1 B4@6/1 C5@7/1"},
+		{"向下就近", relative, "C4 B A G", "C4@0/1 B3@1/1 A3@2/1 G3@3/1"},
+		{"不看升降号", relative, "C4 Fs Gb", "C4@0/1 Fs4@1/1 Fs4@2/1"},
+		{"八度记号", relative, "C4 C' B, A G/2", "C4@0/1 C5@1/1 B3@2/1 A3@3/1 G3@4/2"},
+		{"连写八度记号", relative, "C4 G'' Bb,,/8", "C4@0/1 G5@1/1 As3@2/0.5"},
+		{"和弦以第一个音为参考", relative, "[C5 E G] A B", "C5@0/1 E5@0/1 G5@0/1 A4@1/1 B4@2/1"},
+		{"写出八度数字的音符为绝对音高", relative, "E G2 A", "E4@0/1 G2@1/1 A2@2/1"},
+		{"可切回绝对八度", relative, "D set { octave_mode: absolute } E5", "D4@0/1 E5@1/1"},
+	})
+
+	t.Run("段落结束后恢复参考音", func(t *testing.T) {
+		source := relative + "\n" + inSection("section verse { G5 }\nsection chorus { A }")
+		if got, want := describeEvents(t, generate(t, source), false), "G5@0/1 A3@1/1"; got != want {
+			t.Errorf("得到 %s，期望 %s", got, want)
+		}
+	})
+}
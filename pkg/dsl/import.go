@@ -0,0 +1,198 @@
+package dsl
+
+import (
+	"catRock/pkg/dsl/ast"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 导入缓存：同一次解析中每个文件只解析一次，并记录正在解析的文件以检测循环导入
+type importer struct {
+	files map[string]*importedFile // 按绝对路径缓存
+	stack []*importedFile          // 正在解析的文件，从根文件开始
+}
+
+type importedFile struct {
+	path  string // 书写形式的路径 (相对于根文件所在的工作目录)
+	score *ast.ScoreNode
+	scope *scope
+	done  bool
+}
+
+func newImporter() *importer {
+	return &importer{files: map[string]*importedFile{}}
+}
+
+func absolutePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// 开始解析文件：登记到缓存并压入解析栈
+func (im *importer) begin(path string) *importedFile {
+	file := &importedFile{path: path}
+	im.files[absolutePath(path)] = file
+	im.stack = append(im.stack, file)
+	return file
+}
+
+// 文件解析完成
+func (im *importer) end(file *importedFile, score *ast.ScoreNode, scope *scope) {
+	file.score = score
+	file.scope = scope
+	file.done = true
+	if len(im.stack) > 0 && im.stack[len(im.stack)-1] == file {
+		im.stack = im.stack[:len(im.stack)-1]
+	}
+}
+
+// 从 file 开始到栈顶再回到 file 的导入链，如 a.crock → b.crock → a.crock
+func (im *importer) cycle(file *importedFile) string {
+	chain := []string{}
+	for i, loading := range im.stack {
+		if loading == file {
+			for _, f := range im.stack[i:] {
+				chain = append(chain, f.path)
+			}
+			break
+		}
+	}
+	return strings.Join(append(chain, file.path), " → ")
+}
+
+// 解析导入语句：import "drums/basic.crock" as drums
+func (p *Parser) parseImport() *ast.ImportNode {
+	position := p.currentToken.Position
+
+	if !p.expectToken(IMPORT) {
+		return nil
+	}
+
+	if p.currentToken.Type != STRING {
+		p.addError(fmt.Sprintf("期望带引号的文件路径，得到 %s", p.currentToken.Literal))
+		return nil
+	}
+
+	node := &ast.ImportNode{
+		Path:     p.currentToken.Literal,
+		Position: position,
+	}
+	p.nextToken()
+
+	// "as" 会被识别为音符 A#，按字面判断
+	if p.currentToken.Literal == "as" {
+		p.nextToken()
+		if p.currentToken.Type != IDENTIFIER {
+			p.addError(fmt.Sprintf("期望导入别名，得到 %s (别名不能是音符名或关键字)", p.currentToken.Literal))
+			p.nextToken() // 跳过无效的别名
+			return nil
+		}
+		node.Alias = p.currentToken.Literal
+		p.nextToken()
+	}
+
+	// 路径相对于导入它的文件
+	node.File = node.Path
+	if !filepath.IsAbs(node.Path) && position.File != "" {
+		node.File = filepath.Join(filepath.Dir(position.File), node.Path)
+	}
+
+	file := p.loadImport(node)
+	if file == nil {
+		return node
+	}
+	p.registerImport(node, file)
+
+	return node
+}
+
+// 读取并解析导入的文件，已解析过的直接使用缓存
+func (p *Parser) loadImport(node *ast.ImportNode) *importedFile {
+	fail := func(msg string) {
+		p.errors = append(p.errors, fmt.Sprintf("解析错误 %s - %s", node.Position, msg))
+	}
+
+	if file, ok := p.importer.files[absolutePath(node.File)]; ok {
+		if !file.done {
+			fail(fmt.Sprintf("循环导入: %s", p.importer.cycle(file)))
+			return nil
+		}
+		return file
+	}
+
+	content, err := os.ReadFile(node.File)
+	if err != nil {
+		fail(fmt.Sprintf("无法读取导入文件 %s: %v", node.File, err))
+		return nil
+	}
+
+	file := p.importer.begin(node.File)
+	sub := NewParser(NewFileLexer(string(content), node.File))
+	sub.importer = p.importer
+	score := sub.ParseScore()
+	p.importer.end(file, score, sub.scope)
+
+	// 导入文件中的错误已带有文件名
+	p.errors = append(p.errors, sub.errors...)
+
+	return file
+}
+
+// 把导入文件中定义的模板和顶层的具名音轨、段落加入当前作用域，有别名时以 "别名." 为前缀
+func (p *Parser) registerImport(node *ast.ImportNode, file *importedFile) {
+	prefix := ""
+	if node.Alias != "" {
+		prefix = node.Alias + "."
+	}
+
+	define := func(name string) bool {
+		_, isTemplate := p.scope.templates[name]
+		_, isElement := p.scope.elements[name]
+		if isTemplate || isElement {
+			p.errors = append(p.errors, fmt.Sprintf("解析错误 %s - 导入的 %s 与已有的定义重名 (可用 as 指定别名)",
+				node.Position, name))
+			return false
+		}
+		return true
+	}
+
+	// 只导出文件自身定义的模板，不包括它再导入的
+	names := []string{}
+	for name, def := range file.scope.templates {
+		if def.scope == file.scope {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if define(prefix + name) {
+			p.scope.templates[prefix+name] = file.scope.templates[name]
+		}
+	}
+
+	for _, element := range file.score.Elements {
+		var name string
+		switch elem := element.(type) {
+		case *ast.TrackNode:
+			if elem == nil {
+				continue
+			}
+			name = elem.Name
+		case *ast.SectionNode:
+			if elem == nil {
+				continue
+			}
+			name = elem.Name
+		default:
+			continue
+		}
+		if define(prefix + name) {
+			p.scope.elements[prefix+name] = element
+		}
+	}
+}
@@ -4,11 +4,16 @@ import "catRock/pkg/dsl/mytype"
 
 type Lexer struct {
 	input        string
-	position     int  // 当前字符位置
-	readPosition int  // 下一个字符位置
-	ch           byte // 当前字符
-	line         int  // 当前行号
-	column       int  // 当前列号
+	position     int    // 当前字符位置
+	readPosition int    // 下一个字符位置
+	ch           byte   // 当前字符
+	line         int    // 当前行号
+	column       int    // 当前列号
+	file         string // 源文件路径，记录在token位置中
+
+	// 回放模式：依次返回预先给定的token (用于展开模板)
+	tokens     []Token
+	tokenIndex int
 }
 
 func NewLexer(input string) *Lexer {
@@ -23,6 +28,18 @@ func NewLexer(input string) *Lexer {
 	return l
 }
 
+// 创建带文件名的词法分析器，token位置中会记录文件路径
+func NewFileLexer(input string, file string) *Lexer {
+	l := NewLexer(input)
+	l.file = file
+	return l
+}
+
+// 创建回放给定token序列的词法分析器，序列结束后返回EOF
+func NewTokenLexer(tokens []Token) *Lexer {
+	return &Lexer{tokens: tokens}
+}
+
 func (l *Lexer) readChar() {
 	if l.readPosition >= len(l.input) {
 		l.ch = 0 // EOF
@@ -48,12 +65,16 @@ func (l *Lexer) peekChar() byte {
 }
 
 func (l *Lexer) NextToken() Token {
+	if l.tokens != nil {
+		return l.nextReplayToken()
+	}
+
 	var tok Token
 
 	l.skipWhitespace()
 
 	// 记录当前位置
-	pos := mytype.Position{Line: l.line, Column: l.column}
+	pos := mytype.Position{File: l.file, Line: l.line, Column: l.column}
 
 	switch l.ch {
 	case ':':
@@ -83,6 +104,43 @@ func (l *Lexer) NextToken() Token {
 		tok = Token{Type: LPAREN, Literal: string(l.ch), Position: pos}
 	case ')': // 新增 - 右圆括号
 		tok = Token{Type: RPAREN, Literal: string(l.ch), Position: pos}
+	case '~': // 延音：连接后续时值
+		tok = Token{Type: TILDE, Literal: string(l.ch), Position: pos}
+	case '^': // 重音
+		tok = Token{Type: CARET, Literal: string(l.ch), Position: pos}
+	case '-': // 保持音，-> 为速度渐变
+		if l.peekChar() == '>' {
+			l.readChar()
+			tok = Token{Type: ARROW, Literal: "->", Position: pos}
+		} else {
+			tok = Token{Type: MINUS, Literal: string(l.ch), Position: pos}
+		}
+	case '!': // 延长记号
+		tok = Token{Type: BANG, Literal: string(l.ch), Position: pos}
+	case '<': // 渐强
+		tok = Token{Type: LANGLE, Literal: string(l.ch), Position: pos}
+	case '>': // 渐弱
+		tok = Token{Type: RANGLE, Literal: string(l.ch), Position: pos}
+	case '*': // 断音
+		tok = Token{Type: STAR, Literal: string(l.ch), Position: pos}
+	case '|': // 小节线
+		tok = Token{Type: BARLINE, Literal: string(l.ch), Position: pos}
+	case '\'': // 升高八度
+		tok = Token{Type: APOSTROPHE, Literal: string(l.ch), Position: pos}
+	case ',': // 模板参数分隔符，紧跟音符时降低八度
+		tok = Token{Type: COMMA, Literal: string(l.ch), Position: pos}
+	case '\\': // 力度记号，如 \f，相对八度模式下与音符 f 区分
+		if isLetter(l.peekChar()) {
+			l.readChar()
+			return Token{Type: DYNAMIC, Literal: l.readIdentifier(), Position: pos}
+		}
+		tok = Token{Type: ILLEGAL, Literal: string(l.ch), Position: pos}
+	case '"': // 字符串，用于导入路径
+		literal, ok := l.readString()
+		if !ok {
+			return Token{Type: ILLEGAL, Literal: "\"" + literal, Position: pos}
+		}
+		return Token{Type: STRING, Literal: literal, Position: pos}
 	case '\r':
 		if l.peekChar() == '\n' {
 			l.readChar() // 跳过\r
@@ -109,6 +167,20 @@ func (l *Lexer) NextToken() Token {
 	return tok
 }
 
+func (l *Lexer) nextReplayToken() Token {
+	if l.tokenIndex < len(l.tokens) {
+		tok := l.tokens[l.tokenIndex]
+		l.tokenIndex++
+		return tok
+	}
+
+	var pos mytype.Position
+	if len(l.tokens) > 0 {
+		pos = l.tokens[len(l.tokens)-1].Position
+	}
+	return Token{Type: EOF, Literal: "", Position: pos}
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' {
 		l.readChar()
@@ -140,6 +212,21 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
+// 读取双引号之间的内容，不支持转义；行尾前未闭合时返回 false
+func (l *Lexer) readString() (string, bool) {
+	l.readChar() // 跳过开头的引号
+	position := l.position
+	for l.ch != '"' {
+		if l.ch == '\n' || l.ch == '\r' || l.ch == 0 {
+			return l.input[position:l.position], false
+		}
+		l.readChar()
+	}
+	literal := l.input[position:l.position]
+	l.readChar() // 跳过结尾的引号
+	return literal, true
+}
+
 func (l *Lexer) readNumber() string {
 	position := l.position
 	for isDigit(l.ch) {
@@ -158,32 +245,47 @@ func isDigit(ch byte) bool {
 
 // 关键字和标识符映射
 var keywords = map[string]TokenType{
-	"set":     SET,
-	"track":   TRACK,
-	"section": SECTION,
-
-    // 基本音符（大小写都支持）
-    "C": NOTE_C, "c": NOTE_C,
-    "D": NOTE_D, "d": NOTE_D,
-    "E": NOTE_E, "e": NOTE_E,
-    "F": NOTE_F, "f": NOTE_F,
-    "G": NOTE_G, "g": NOTE_G,
-    "A": NOTE_A, "a": NOTE_A,
-    "B": NOTE_B, "b": NOTE_B,
-    
-    // 升号音符 (s后缀，键盘友好)
-    "Cs": NOTE_CS, "cs": NOTE_CS,
-    "Ds": NOTE_DS, "ds": NOTE_DS,
-    "Fs": NOTE_FS, "fs": NOTE_FS,
-    "Gs": NOTE_GS, "gs": NOTE_GS,
-    "As": NOTE_AS, "as": NOTE_AS,
-    
-    // 降号音符 (b后缀)
-    "Db": NOTE_DB, "db": NOTE_DB,
-    "Eb": NOTE_EB, "eb": NOTE_EB,
-    "Gb": NOTE_GB, "gb": NOTE_GB,
-    "Ab": NOTE_AB, "ab": NOTE_AB,
-    "Bb": NOTE_BB, "bb": NOTE_BB,
+	"set":      SET,
+	"track":    TRACK,
+	"section":  SECTION,
+	"repeat":   REPEAT,
+	"ending":   ENDING,
+	"template": TEMPLATE,
+	"use":      USE,
+	"import":   IMPORT,
+	"_":        TIE,
+
+	// 基本音符（大小写都支持）
+	"C": NOTE_C, "c": NOTE_C,
+	"D": NOTE_D, "d": NOTE_D,
+	"E": NOTE_E, "e": NOTE_E,
+	"F": NOTE_F, "f": NOTE_F,
+	"G": NOTE_G, "g": NOTE_G,
+	"A": NOTE_A, "a": NOTE_A,
+	"B": NOTE_B, "b": NOTE_B,
+
+	// 升号音符 (s后缀，键盘友好)
+	"Cs": NOTE_CS, "cs": NOTE_CS,
+	"Ds": NOTE_DS, "ds": NOTE_DS,
+	"Fs": NOTE_FS, "fs": NOTE_FS,
+	"Gs": NOTE_GS, "gs": NOTE_GS,
+	"As": NOTE_AS, "as": NOTE_AS,
+
+	// 降号音符 (b后缀)
+	"Db": NOTE_DB, "db": NOTE_DB,
+	"Eb": NOTE_EB, "eb": NOTE_EB,
+	"Gb": NOTE_GB, "gb": NOTE_GB,
+	"Ab": NOTE_AB, "ab": NOTE_AB,
+	"Bb": NOTE_BB, "bb": NOTE_BB,
+
+	// 还原号 (n后缀)，不受调号影响
+	"Cn": NOTE_C, "cn": NOTE_C,
+	"Dn": NOTE_D, "dn": NOTE_D,
+	"En": NOTE_E, "en": NOTE_E,
+	"Fn": NOTE_F, "fn": NOTE_F,
+	"Gn": NOTE_G, "gn": NOTE_G,
+	"An": NOTE_A, "an": NOTE_A,
+	"Bn": NOTE_B, "bn": NOTE_B,
 	"quarter": IDENTIFIER,
 	"half":    IDENTIFIER,
 	"whole":   IDENTIFIER,
@@ -1,6 +1,17 @@
 package mytype
 
+import "fmt"
+
 type Position struct {
-	Line   int // 行号，从1开始
-	Column int // 列号，从0开始
-}
\ No newline at end of file
+	File   string // 文件路径，直接解析源码时为空
+	Line   int    // 行号，从1开始
+	Column int    // 列号，从0开始
+}
+
+// 书写形式 "行:列"，有文件时为 "文件:行:列"
+func (p Position) String() string {
+	if p.File != "" {
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
@@ -1,6 +1,7 @@
 package dsl
 
 import (
+	"catRock/pkg/core"
 	"catRock/pkg/dsl/ast"
 	"fmt"
 	"strconv"
@@ -12,12 +13,24 @@ type Parser struct {
 	currentToken Token
 	peekToken    Token
 	errors       []string
+
+	scope    *scope        // 本文件可通过 use 引用的名称，展开时与子解析器共享
+	importer *importer     // 导入缓存，同一次解析中的所有文件共享
+	uses     []*ast.UseNode // 待展开的模板使用
+	useArgs  map[*ast.UseNode][][]Token
+
+	relative  bool          // 当前容器使用相对八度 (octave_mode: relative)
+	reference relativePitch // 相对八度的参考音，即上一个音符
 }
 
 func NewParser(lexer *Lexer) *Parser {
 	p := &Parser{
-		lexer:  lexer,
-		errors: []string{},
+		lexer:     lexer,
+		errors:    []string{},
+		scope:   newScope(),
+		useArgs: map[*ast.UseNode][][]Token{},
+
+		reference: defaultRelativePitch,
 	}
 
 	// 读取两个token，初始化currentToken和peekToken
@@ -37,10 +50,7 @@ func (p *Parser) Errors() []string {
 }
 
 func (p *Parser) addError(msg string) {
-	errorMsg := fmt.Sprintf("解析错误 %d:%d - %s",
-		p.currentToken.Position.Line,
-		p.currentToken.Position.Column,
-		msg)
+	errorMsg := fmt.Sprintf("解析错误 %s - %s", p.currentToken.Position, msg)
 	p.errors = append(p.errors, errorMsg)
 }
 
@@ -52,6 +62,15 @@ func (p *Parser) ParseScore() *ast.ScoreNode {
 		Position:   p.currentToken.Position,
 	}
 
+	// 根文件：创建导入缓存并登记自身，以便检测导回根文件的循环
+	if p.importer == nil {
+		p.importer = newImporter()
+		if p.lexer.file != "" {
+			root := p.importer.begin(p.lexer.file)
+			defer p.importer.end(root, score, p.scope)
+		}
+	}
+
 	// 解析顶层元素
 	for p.currentToken.Type != EOF {
 		element := p.parseTopLevelElement()
@@ -59,12 +78,23 @@ func (p *Parser) ParseScore() *ast.ScoreNode {
 			switch elem := element.(type) {
 			case *ast.SetNode:
 				score.GlobalSets = append(score.GlobalSets, elem)
+			case *ast.TemplateNode:
+				if elem != nil {
+					score.Templates = append(score.Templates, elem)
+				}
+			case *ast.ImportNode:
+				if elem != nil {
+					score.Imports = append(score.Imports, elem)
+				}
 			case ast.PlayableNode:
 				score.Elements = append(score.Elements, elem)
 			}
 		}
 	}
 
+	// 模板可以定义在使用之后，全部解析完再展开
+	p.expandUses(nil)
+
 	return score
 }
 
@@ -77,6 +107,14 @@ func (p *Parser) parseTopLevelElement() ast.ASTNode {
 		return p.parseTrack()
 	case SECTION:
 		return p.parseSection()
+	case REPEAT:
+		return p.parseRepeat()
+	case TEMPLATE:
+		return p.parseTemplate()
+	case IMPORT:
+		return p.parseImport()
+	case USE:
+		return p.parseUse()
 	case NOTE_C, NOTE_D, NOTE_E, NOTE_F, NOTE_G, NOTE_A, NOTE_B,
 		NOTE_CS, NOTE_DS, NOTE_FS, NOTE_GS, NOTE_AS,
 		NOTE_DB, NOTE_EB, NOTE_GB, NOTE_AB, NOTE_BB:
@@ -147,6 +185,8 @@ func (p *Parser) parseSetBlock(context ast.ParameterContext) *ast.SetNode {
 		return nil
 	}
 
+	p.applyOctaveMode(parameters)
+
 	return &ast.SetNode{
 		Parameters: parameters,
 		Context:    context,
@@ -159,8 +199,12 @@ func (p *Parser) parseParameterValue() interface{} {
 	switch p.currentToken.Type {
 	case NUMBER:
 		if p.peekToken.Type == SLASH {
+			// 保留分数的写法，如拍号 6/8 不能约分为 3/4
 			return p.parseFraction()
 		}
+		if p.peekToken.Type == ARROW {
+			return p.parseTempoRamp()
+		}
 		value, err := strconv.Atoi(p.currentToken.Literal)
 		if err != nil {
 			p.addError(fmt.Sprintf("无效的数字: %s", p.currentToken.Literal))
@@ -181,38 +225,78 @@ func (p *Parser) parseParameterValue() interface{} {
 	}
 }
 
-func (p *Parser) parseFraction() float64 {
+// 解析参数中的分数，返回 "分子/分母" 字符串
+func (p *Parser) parseFraction() interface{} {
 	numerator := p.currentToken.Literal
 	p.nextToken()
 	p.nextToken() // 跳过 SLASH
 
 	if p.currentToken.Type != NUMBER {
 		p.addError(fmt.Sprintf("期望分母数字，得到 %s", p.currentToken.Literal))
-		return 0.0
+		return nil
 	}
 
 	denominator := p.currentToken.Literal
 	p.nextToken()
 
-	// 转换为浮点数
-	num, err := strconv.ParseFloat(numerator, 64)
-	if err != nil {
+	if _, err := strconv.Atoi(numerator); err != nil {
 		p.addError(fmt.Sprintf("无效的分子: %s", numerator))
-		return 0.0
+		return nil
 	}
 
-	den, err := strconv.ParseFloat(denominator, 64)
+	den, err := strconv.Atoi(denominator)
 	if err != nil {
 		p.addError(fmt.Sprintf("无效的分母: %s", denominator))
-		return 0.0
+		return nil
 	}
 
 	if den == 0 {
 		p.addError("分母不能为零")
-		return 0.0
+		return nil
 	}
 
-	return num / den
+	return numerator + "/" + denominator
+}
+
+// 解析速度渐变，如 120 -> 90 over 4
+func (p *Parser) parseTempoRamp() interface{} {
+	number := func(name string) (int, bool) {
+		if p.currentToken.Type != NUMBER {
+			p.addError(fmt.Sprintf("期望%s，得到 %s", name, p.currentToken.Literal))
+			return 0, false
+		}
+		value, err := strconv.Atoi(p.currentToken.Literal)
+		if err != nil || value <= 0 {
+			p.addError(fmt.Sprintf("无效的%s: %s", name, p.currentToken.Literal))
+			return 0, false
+		}
+		p.nextToken()
+		return value, true
+	}
+
+	from, ok := number("起始速度")
+	if !ok {
+		return nil
+	}
+	p.nextToken() // 跳过 ->
+
+	to, ok := number("目标速度")
+	if !ok {
+		return nil
+	}
+
+	if p.currentToken.Type != IDENTIFIER || p.currentToken.Literal != "over" {
+		p.addError(fmt.Sprintf("期望 over 及渐变的拍数，得到 %s", p.currentToken.Literal))
+		return nil
+	}
+	p.nextToken()
+
+	beats, ok := number("渐变拍数")
+	if !ok {
+		return nil
+	}
+
+	return ast.TempoRamp{From: from, To: to, Beats: beats}
 }
 
 // 解析Track
@@ -235,6 +319,8 @@ func (p *Parser) parseTrack() *ast.TrackNode {
 		return nil
 	}
 
+	defer p.enterOctaveScope()()
+
 	track := &ast.TrackNode{
 		Name:     name,
 		Sets:     []*ast.SetNode{},
@@ -244,6 +330,18 @@ func (p *Parser) parseTrack() *ast.TrackNode {
 
 	// 解析Track内容
 	for p.currentToken.Type != RBRACE && p.currentToken.Type != EOF {
+		if p.currentToken.Type == BARLINE {
+			// 轨道的元素并行播放，小节线没有意义
+			p.addError("轨道内不能直接使用小节线 |，请写在 section 中")
+			p.nextToken()
+			continue
+		}
+		if p.isDynamicToken() {
+			// 力度记号只影响同一顺序容器中之后的元素
+			p.addError(fmt.Sprintf("轨道内不能直接使用力度记号 %s，请写在 section 中", p.currentToken.Literal))
+			p.nextToken()
+			continue
+		}
 		element := p.parseContainerElement()
 		if element != nil {
 			switch elem := element.(type) {
@@ -283,6 +381,8 @@ func (p *Parser) parseSection() *ast.SectionNode {
 		return nil
 	}
 
+	defer p.enterOctaveScope()()
+
 	section := &ast.SectionNode{
 		Name:     name,
 		Sets:     []*ast.SetNode{},
@@ -320,51 +420,204 @@ func (p *Parser) parseNote() *ast.NoteNode {
 		return nil
 	}
 
-	noteName := p.currentToken.Literal
+	nameToken := p.currentToken
+	noteName := nameToken.Literal
 	p.nextToken()
 
-	if p.currentToken.Type != NUMBER {
-		p.addError(fmt.Sprintf("期望八度数字，得到 %s", p.currentToken.Literal))
+	octave, ok := p.parseOctave(nameToken)
+	if !ok {
 		return nil
 	}
 
-	octave, err := strconv.Atoi(p.currentToken.Literal)
-	if err != nil || octave < 0 || octave > 9 {
-		p.addError(fmt.Sprintf("无效的八度值: %s", p.currentToken.Literal))
-		return nil
+	// 解析时值 - 支持 /分数表示法
+	duration := p.parseNoteDuration()
+
+	note := &ast.NoteNode{
+		Name:          noteName,
+		Octave:        octave,
+		Duration:      duration,
+		TiedDurations: p.parseTiedDurations(),
+		Position:      position,
 	}
+	note.Velocity, note.Articulations = p.parseArticulations()
+	note.Tie = p.parseTieMark()
+	return note
+}
 
-	p.nextToken()
+// 解析 ~ 连接的后续时值，如 C4/2~/16；~ 后是数字时为力度，留给 parseArticulations
+func (p *Parser) parseTiedDurations() []string {
+	durations := []string{}
+	for p.currentToken.Type == TILDE && p.peekToken.Type != NUMBER {
+		p.nextToken() // 跳过 '~'
 
-	// 解析时值 - 支持 /分数表示法
-	duration := p.parseNoteDuration()
+		if p.currentToken.Type != SLASH && durationWords[p.currentToken.Literal] == 0 {
+			p.addError(fmt.Sprintf("期望 ~ 后的时值，得到 %s", p.currentToken.Literal))
+			break
+		}
+		durations = append(durations, p.parseNoteDuration())
+	}
+	return durations
+}
 
-	return &ast.NoteNode{
-		Name:     noteName,
-		Octave:   octave,
-		Duration: duration,
-		Position: position,
+// 解析时值之后的力度和演奏法：~120 (力度)、^ (重音)、^^ (强重音)、- (保持音)、* (断音)、** (短断音)、! (延长记号)
+func (p *Parser) parseArticulations() (int, []core.Articulation) {
+	velocity := 0
+	var articulations []core.Articulation
+
+	add := func(articulation core.Articulation) {
+		for _, existing := range articulations {
+			if existing == articulation {
+				return
+			}
+		}
+		articulations = append(articulations, articulation)
+	}
+
+	// 紧跟着的同一符号表示加强，如 ^^、**
+	doubled := func(single core.Articulation, double core.Articulation) {
+		first := p.currentToken
+		p.nextToken()
+		if p.currentToken.Type == first.Type && adjacent(first, p.currentToken) {
+			p.nextToken()
+			add(double)
+			return
+		}
+		add(single)
 	}
+
+	for {
+		switch p.currentToken.Type {
+		case TILDE:
+			p.nextToken()
+			if p.currentToken.Type != NUMBER {
+				p.addError(fmt.Sprintf("~ 连接的时值须写在力度和演奏法之前，得到 %s", p.currentToken.Literal))
+				return velocity, articulations
+			}
+			value, err := strconv.Atoi(p.currentToken.Literal)
+			if err != nil || value < 1 || value > 127 {
+				p.addError(fmt.Sprintf("力度应为 1-127，得到 %s", p.currentToken.Literal))
+			} else {
+				velocity = value
+			}
+			p.nextToken()
+		case CARET:
+			doubled(core.Accent, core.Marcato)
+		case STAR:
+			doubled(core.Staccato, core.Staccatissimo)
+		case MINUS:
+			p.nextToken()
+			add(core.Tenuto)
+		case BANG:
+			p.nextToken()
+			add(core.Fermata)
+		default:
+			return velocity, articulations
+		}
+	}
+}
+
+// 解析元素后的延音线 _，表示与下一个同音高的音符相连
+func (p *Parser) parseTieMark() bool {
+	if p.currentToken.Type != TIE {
+		return false
+	}
+	p.nextToken()
+	return true
+}
+
+// 时值名称对应的分母（以全音符为1）
+var durationWords = map[string]int{
+	"whole":     1,
+	"half":      2,
+	"quarter":   4,
+	"eighth":    8,
+	"sixteenth": 16,
 }
 
-// 检查这个方法的实现
+// 时值有误时使用的默认值
+const defaultDuration = "1/4"
+
+// 解析时值，返回以全音符为单位的最简分数，如 "1/4"、"3/8"；未写时值时返回空串。
+// 支持 /N (N为2的幂)、/分子:分母、时值名称 (quarter 等)，以及其后任意个附点
 func (p *Parser) parseNoteDuration() string {
-    if p.currentToken.Type != SLASH {
-        // 如果没有时值，可能返回了默认值？
-        return "1/4"  // ← 这里可能有问题
-    }
-    
-    p.nextToken() // 跳过 '/'
-    
-    if p.currentToken.Type != NUMBER {
-        p.addError("期望时值数字")
-        return "1/4"  // ← 默认值
-    }
-    
-    duration := "1/" + p.currentToken.Literal
-    p.nextToken()
-    
-    return duration
+	num, den := 1, 4
+
+	switch {
+	case p.currentToken.Type == SLASH:
+		p.nextToken() // 跳过 '/'
+
+		if p.currentToken.Type != NUMBER {
+			p.addError(fmt.Sprintf("期望时值数字，得到 %s", p.currentToken.Literal))
+			return defaultDuration
+		}
+		// 先读完整个 /分子:分母 再校验，错误报在各自的数字上，也避免剩下的 :分母 引起连锁错误
+		number := p.currentToken
+		p.nextToken()
+
+		if p.currentToken.Type == COLON {
+			// 任意比例 /分子:分母
+			p.nextToken()
+			if p.currentToken.Type != NUMBER {
+				p.addError(fmt.Sprintf("期望时值分母，得到 %s", p.currentToken.Literal))
+				return defaultDuration
+			}
+			denominatorToken := p.currentToken
+			p.nextToken()
+
+			value, ok := p.parseDurationPart(number, "时值分子")
+			denominator, denominatorOK := p.parseDurationPart(denominatorToken, "时值分母")
+			if !ok || !denominatorOK {
+				return defaultDuration
+			}
+			num, den = value, denominator
+		} else {
+			value, ok := p.parseDurationPart(number, "时值")
+			if !ok {
+				return defaultDuration
+			}
+			if value&(value-1) != 0 {
+				p.errors = append(p.errors, fmt.Sprintf("解析错误 %s - 时值 /%d 不是2的幂，其他比例请写成 /分子:分母 (如 /3:8)", number.Position, value))
+				return defaultDuration
+			}
+			num, den = 1, value
+		}
+
+	case p.currentToken.Type == IDENTIFIER && durationWords[p.currentToken.Literal] > 0:
+		den = durationWords[p.currentToken.Literal]
+		p.nextToken()
+
+	default:
+		return ""
+	}
+
+	// 附点：每个附点再增加上一部分的一半，n个附点为原时值的 (2^(n+1)-1)/2^n
+	dots := 0
+	for p.currentToken.Type == DOT {
+		dots++
+		p.nextToken()
+	}
+	num *= 1<<(dots+1) - 1
+	den *= 1 << dots
+
+	divisor := gcd(num, den)
+	return fmt.Sprintf("%d/%d", num/divisor, den/divisor)
+}
+
+// 时值中的数字必须为正整数，0 或溢出时在该数字的位置报错
+func (p *Parser) parseDurationPart(token Token, what string) (int, bool) {
+	value, err := strconv.Atoi(token.Literal)
+	if err != nil || value <= 0 {
+		p.errors = append(p.errors, fmt.Sprintf("解析错误 %s - 无效的%s: %s (必须为正整数)", token.Position, what, token.Literal))
+		return 0, false
+	}
+	return value, true
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
 }
 
 // 完全重写parseChord方法
@@ -377,18 +630,27 @@ func (p *Parser) parseChord() *ast.ChordNode {
 
 	var content interface{}
 
-	// 检查是和弦名还是音符列表
-	if p.currentToken.Type == IDENTIFIER {
-		// 和弦名 如 Am, Cmaj
-		chordName := p.currentToken.Literal
-		content = chordName
-		p.nextToken()
+	// 检查是和弦符号还是音符列表
+	if symbol, ok := p.tryChordSymbol(); ok {
+		// 和弦符号 如 Am, Cmaj7, G7/B
+		content = symbol
 	} else {
-		// 音符列表 如 [C4 E4 G4]
+		// 音符列表 如 [C4 E4 G4]；相对八度模式下和弦之后的音符相对于和弦的第一个音
 		notes := []*ast.NoteNode{}
+		defer func() {
+			if len(notes) > 0 {
+				p.reference = relativePitch{step: noteStep(notes[0].Name), octave: notes[0].Octave}
+			}
+		}()
 		for p.currentToken.Type != RBRACKET && p.currentToken.Type != EOF {
 			if p.isNoteToken(p.currentToken.Type) {
 				note := p.parseNote()
+				if note != nil && note.Tie {
+					p.addError("和弦内的音符不能单独使用延音线 _，请写在和弦之后")
+				}
+				if note != nil && (note.Velocity > 0 || len(note.Articulations) > 0) {
+					p.addError("和弦内的音符不能单独设置力度或演奏法，请写在和弦之后")
+				}
 				if note != nil {
 					notes = append(notes, note)
 				}
@@ -404,13 +666,119 @@ func (p *Parser) parseChord() *ast.ChordNode {
 		return nil
 	}
 
-	// 解析和弦时值
-	duration := p.parseNoteDuration()
+	chord := &ast.ChordNode{
+		Content:       content,
+		Duration:      p.parseNoteDuration(),
+		TiedDurations: p.parseTiedDurations(),
+		Octave:        defaultChordOctave,
+		Position:      position,
+	}
+	chord.Velocity, chord.Articulations = p.parseArticulations()
+	p.parseChordModifiers(chord)
+	chord.Tie = p.parseTieMark()
 
-	return &ast.ChordNode{
-		Content:  content,
-		Duration: duration,
-		Position: position,
+	return chord
+}
+
+// 和弦符号默认的根音八度
+const defaultChordOctave = 4
+
+// 尝试把方括号内紧挨着的token拼成和弦符号，如 [F#m7b5]、[G7/B]。
+// 内容含空格、无法识别或是单个带八度的音符 (如 [C5]) 时恢复状态，按音符列表解析；
+// 与音符写法相同的和弦符号用引号写出，如 ["C5"]、["G7"]
+func (p *Parser) tryChordSymbol() (string, bool) {
+	if p.currentToken.Type == STRING {
+		symbol, position := p.currentToken.Literal, p.currentToken.Position
+		p.nextToken()
+		if _, err := core.ParseChordSymbol(symbol); err != nil {
+			p.errors = append(p.errors, fmt.Sprintf("解析错误 %s - %v", position, err))
+		}
+		return symbol, true
+	}
+
+	lexer := *p.lexer
+	current, peek := p.currentToken, p.peekToken
+
+	restore := func() {
+		*p.lexer = lexer
+		p.currentToken, p.peekToken = current, peek
+	}
+
+	// 音符名紧跟一位八度数字，如 [C5]、[G7]，是单音和弦
+	if p.isNoteToken(current.Type) && peek.Type == NUMBER && len(peek.Literal) == 1 && adjacent(current, peek) {
+		p.nextToken()
+		p.nextToken()
+		single := p.currentToken.Type == RBRACKET
+		restore()
+		if single {
+			return "", false
+		}
+	}
+
+	symbol := ""
+	previous := p.currentToken
+	for p.currentToken.Type != RBRACKET {
+		if p.currentToken.Type == EOF || p.currentToken.Type == NEWLINE {
+			restore()
+			return "", false
+		}
+		// token 之间有空格，说明是音符列表
+		if symbol != "" && !adjacent(previous, p.currentToken) {
+			restore()
+			return "", false
+		}
+		symbol += p.currentToken.Literal
+		previous = p.currentToken
+		p.nextToken()
+	}
+
+	if symbol == "" {
+		restore()
+		return "", false
+	}
+
+	if _, err := core.ParseChordSymbol(symbol); err != nil {
+		// 单个带八度或八度记号的音符，如 [C4]、[C']
+		if p.isNoteToken(current.Type) && (peek.Type == NUMBER || peek.Type == APOSTROPHE || peek.Type == COMMA) {
+			restore()
+			return "", false
+		}
+		p.errors = append(p.errors, fmt.Sprintf("解析错误 %s - %v", current.Position, err))
+	}
+
+	return symbol, true
+}
+
+// 解析和弦时值之后的修饰：oct3 (和弦符号的根音八度)、inv1 (转位)、drop2 (drop排列)
+func (p *Parser) parseChordModifiers(chord *ast.ChordNode) {
+	for p.currentToken.Type == IDENTIFIER {
+		modifier := p.currentToken.Literal
+
+		var target *int
+		var limit int
+		switch modifier {
+		case "oct":
+			target, limit = &chord.Octave, 9
+		case "inv":
+			target, limit = &chord.Inversion, 6
+		case "drop":
+			target, limit = &chord.Drop, 6
+		default:
+			return
+		}
+		p.nextToken()
+
+		if p.currentToken.Type != NUMBER {
+			p.addError(fmt.Sprintf("期望 %s 后的数字，得到 %s", modifier, p.currentToken.Literal))
+			return
+		}
+		value, err := strconv.Atoi(p.currentToken.Literal)
+		if err != nil || value > limit || (modifier == "drop" && value < 2) {
+			p.addError(fmt.Sprintf("无效的 %s 值: %s", modifier, p.currentToken.Literal))
+		} else {
+			*target = value
+		}
+		p.nextToken()
 	}
 }
 
@@ -434,6 +802,112 @@ func (p *Parser) parseRest() *ast.RestNode {
 	}
 }
 
+// 解析小节线 |
+func (p *Parser) parseBarline() *ast.BarlineNode {
+	position := p.currentToken.Position
+
+	if !p.expectToken(BARLINE) {
+		return nil
+	}
+
+	return &ast.BarlineNode{Position: position}
+}
+
+// 解析力度记号，如 mf
+func (p *Parser) parseDynamic() *ast.DynamicNode {
+	position := p.currentToken.Position
+
+	dynamic, err := core.ParseDynamic(p.currentToken.Literal)
+	if err != nil {
+		p.addError(err.Error())
+		p.nextToken()
+		return nil
+	}
+	p.nextToken()
+
+	return &ast.DynamicNode{Dynamic: dynamic, Position: position}
+}
+
+// 当前token是否为力度记号；\ 开头的总是力度记号。
+// 绝对八度模式下 f 后面没有八度数字时也是力度记号，相对八度模式下单独的 f 是音符 F，强请写成 \f
+func (p *Parser) isDynamicToken() bool {
+	switch p.currentToken.Type {
+	case DYNAMIC:
+		return true
+	case IDENTIFIER:
+		_, err := core.ParseDynamic(p.currentToken.Literal)
+		return err == nil
+	case NOTE_F:
+		return !p.relative && p.currentToken.Literal == "f" && p.peekToken.Type != NUMBER
+	}
+	return false
+}
+
+// 解析渐强/渐弱：cresc(n) { ... } 或 dim(n) { ... }，省略 (n) 时为 1 级
+func (p *Parser) parseHairpin() *ast.HairpinNode {
+	position := p.currentToken.Position
+	keyword := p.currentToken.Literal
+	p.nextToken()
+
+	steps := 1
+	if p.currentToken.Type == LPAREN {
+		p.nextToken()
+		if p.currentToken.Type != NUMBER {
+			p.addError(fmt.Sprintf("期望 %s 的级数，得到 %s", keyword, p.currentToken.Literal))
+			return nil
+		}
+		n, err := strconv.Atoi(p.currentToken.Literal)
+		if err != nil || n < 1 {
+			p.addError(fmt.Sprintf("无效的 %s 级数: %s", keyword, p.currentToken.Literal))
+			n = 1 // 继续解析主体，避免连锁错误
+		}
+		steps = n
+		p.nextToken()
+		if !p.expectToken(RPAREN) {
+			return nil
+		}
+	}
+	if keyword == "dim" {
+		steps = -steps
+	}
+
+	elements, ok := p.parseRepeatBody(keyword)
+	if !ok {
+		return nil
+	}
+
+	return &ast.HairpinNode{Steps: steps, Elements: elements, Position: position}
+}
+
+// 解析记号形式的渐强/渐弱：< ... > 渐强一级，> ... < 渐弱一级
+func (p *Parser) parseAngleHairpin() *ast.HairpinNode {
+	position := p.currentToken.Position
+	opening := p.currentToken
+	closing, steps := RANGLE, 1
+	if opening.Type == RANGLE {
+		closing, steps = LANGLE, -1
+	}
+	p.nextToken()
+
+	elements := []ast.PlayableNode{}
+	for p.currentToken.Type != closing && p.currentToken.Type != EOF {
+		if p.currentToken.Type == SET || p.currentToken.Type == RBRACE {
+			p.addError(fmt.Sprintf("%s 没有闭合，期望 %s", opening.Literal, tokenTypeToString(closing)))
+			return nil
+		}
+		element := p.parseContainerElement()
+		if playable, ok := element.(ast.PlayableNode); ok {
+			elements = append(elements, playable)
+		}
+	}
+
+	if !p.expectToken(closing) {
+		return nil
+	}
+
+	return &ast.HairpinNode{Steps: steps, Elements: elements, Position: position}
+}
+
 // 新增：解析分组音符（处理括号）
 func (p *Parser) parseGroup() *ast.GroupNode {
 	position := p.currentToken.Position
@@ -465,8 +939,102 @@ func (p *Parser) parseGroup() *ast.GroupNode {
 	return group
 }
 
+// 解析反复：repeat N { ... }，其后可跟 ending N { ... } 跳房子结尾
+func (p *Parser) parseRepeat() *ast.RepeatNode {
+	position := p.currentToken.Position
+
+	if !p.expectToken(REPEAT) {
+		return nil
+	}
+
+	if p.currentToken.Type != NUMBER {
+		p.addError(fmt.Sprintf("期望反复次数，得到 %s", p.currentToken.Literal))
+		return nil
+	}
+	times, err := strconv.Atoi(p.currentToken.Literal)
+	if err != nil || times < 1 {
+		p.addError(fmt.Sprintf("无效的反复次数: %s", p.currentToken.Literal))
+		times = 1 // 继续解析主体，避免连锁错误
+	}
+	p.nextToken()
+
+	repeat := &ast.RepeatNode{
+		Times:    times,
+		Endings:  []*ast.EndingNode{},
+		Position: position,
+	}
+
+	elements, ok := p.parseRepeatBody("repeat")
+	if !ok {
+		return nil
+	}
+	repeat.Elements = elements
+
+	// 结尾可以写在后续行
+	for {
+		for p.currentToken.Type == NEWLINE {
+			p.nextToken()
+		}
+		if p.currentToken.Type != ENDING {
+			break
+		}
+
+		ending := &ast.EndingNode{Position: p.currentToken.Position}
+		p.nextToken()
+
+		if p.currentToken.Type != NUMBER {
+			p.addError(fmt.Sprintf("期望结尾编号，得到 %s", p.currentToken.Literal))
+			return nil
+		}
+		number, err := strconv.Atoi(p.currentToken.Literal)
+		switch {
+		case err != nil || number < 1 || number > times:
+			p.addError(fmt.Sprintf("结尾编号 %s 超出反复次数 1-%d", p.currentToken.Literal, times))
+		case repeat.Ending(number) != nil:
+			p.addError(fmt.Sprintf("结尾 %d 重复定义", number))
+		}
+		ending.Number = number
+		p.nextToken()
+
+		elements, ok := p.parseRepeatBody("ending")
+		if !ok {
+			return nil
+		}
+		ending.Elements = elements
+		repeat.Endings = append(repeat.Endings, ending)
+	}
+
+	return repeat
+}
+
+// 解析反复或结尾的 { ... } 内容
+func (p *Parser) parseRepeatBody(keyword string) ([]ast.PlayableNode, bool) {
+	if !p.expectToken(LBRACE) {
+		return nil, false
+	}
+
+	elements := []ast.PlayableNode{}
+	for p.currentToken.Type != RBRACE && p.currentToken.Type != EOF {
+		if p.currentToken.Type == SET {
+			p.addError(fmt.Sprintf("%s 内不能使用 set，请放在外层的 section 或 track 中", keyword))
+		}
+		element := p.parseContainerElement()
+		if playable, ok := element.(ast.PlayableNode); ok {
+			elements = append(elements, playable)
+		}
+	}
+
+	if !p.expectToken(RBRACE) {
+		return nil, false
+	}
+	return elements, true
+}
+
 // 新增：解析可播放元素的通用方法
 func (p *Parser) parsePlayableElement() ast.PlayableNode {
+    if (p.currentToken.Type == NOTE_F || p.currentToken.Type == DYNAMIC) && p.isDynamicToken() {
+        return p.parseDynamic()
+    }
     switch p.currentToken.Type {
     // 扩展音符支持 - 添加半音音符
     case NOTE_C, NOTE_D, NOTE_E, NOTE_F, NOTE_G, NOTE_A, NOTE_B,
@@ -477,10 +1045,17 @@ func (p *Parser) parsePlayableElement() ast.PlayableNode {
         return p.parseChord()
     case LPAREN:
         return p.parseGroup()
+    case REPEAT:
+        return p.parseRepeat()
+    case USE:
+        return p.parseUse()
     case IDENTIFIER:
         if p.currentToken.Literal == "rest" {
             return p.parseRest()
         }
+        if p.isDynamicToken() {
+            return p.parseDynamic()
+        }
         p.addError(fmt.Sprintf("未知标识符: %s", p.currentToken.Literal))
         p.nextToken()
         return nil
@@ -494,6 +1069,9 @@ func (p *Parser) parsePlayableElement() ast.PlayableNode {
     }
 }
 func (p *Parser) parseContainerElement() ast.ASTNode {
+    if (p.currentToken.Type == NOTE_F || p.currentToken.Type == DYNAMIC) && p.isDynamicToken() {
+        return p.parseDynamic()
+    }
     switch p.currentToken.Type {
     case SET:
         return p.parseSetBlock(ast.SectionContext)
@@ -510,10 +1088,24 @@ func (p *Parser) parseContainerElement() ast.ASTNode {
         return p.parseChord()
     case LPAREN: // 新增：支持分组
         return p.parseGroup()
+    case REPEAT:
+        return p.parseRepeat()
+    case USE:
+        return p.parseUse()
+    case BARLINE:
+        return p.parseBarline()
+    case LANGLE, RANGLE:
+        return p.parseAngleHairpin()
     case IDENTIFIER:
         if p.currentToken.Literal == "rest" {
             return p.parseRest()
         }
+        if p.currentToken.Literal == "cresc" || p.currentToken.Literal == "dim" {
+            return p.parseHairpin()
+        }
+        if p.isDynamicToken() {
+            return p.parseDynamic()
+        }
         p.addError(fmt.Sprintf("未知标识符: %s", p.currentToken.Literal))
         p.nextToken()
         return nil
@@ -580,6 +1172,42 @@ func tokenTypeToString(tokenType TokenType) string {
 		return "TRACK"
 	case SECTION:
 		return "SECTION"
+	case REPEAT:
+		return "REPEAT"
+	case ENDING:
+		return "ENDING"
+	case TEMPLATE:
+		return "TEMPLATE"
+	case USE:
+		return "USE"
+	case IMPORT:
+		return "IMPORT"
+	case STRING:
+		return "STRING"
+	case LPAREN:
+		return "("
+	case RPAREN:
+		return ")"
+	case COMMA:
+		return ","
+	case CARET:
+		return "^"
+	case MINUS:
+		return "-"
+	case STAR:
+		return "*"
+	case BARLINE:
+		return "|"
+	case BANG:
+		return "!"
+	case ARROW:
+		return "->"
+	case LANGLE:
+		return "<"
+	case RANGLE:
+		return ">"
+	case APOSTROPHE:
+		return "'"
 	case LBRACE:
 		return "{"
 	case RBRACE:
@@ -594,6 +1222,10 @@ func tokenTypeToString(tokenType TokenType) string {
 		return "/"
 	case DOT:
 		return "."
+	case TILDE:
+		return "~"
+	case TIE:
+		return "_"
 	case NUMBER:
 		return "NUMBER"
 	case IDENTIFIER:
@@ -0,0 +1,125 @@
+package dsl
+
+import (
+	"catRock/pkg/dsl/ast"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// 解析源码，要求没有解析错误
+func mustParse(t *testing.T, source string) *ast.ScoreNode {
+	t.Helper()
+	parser := NewParser(NewLexer(source))
+	score := parser.ParseScore()
+	if errors := parser.Errors(); len(errors) > 0 {
+		t.Fatalf("解析 %q 失败:\n%s", source, strings.Join(errors, "\n"))
+	}
+	return score
+}
+
+// 解析源码，返回解析错误
+func parseErrors(source string) []string {
+	parser := NewParser(NewLexer(source))
+	parser.ParseScore()
+	return parser.Errors()
+}
+
+// 把 section 中的音符和力度记号写成简短的文字，便于比较，如 "F4 \f C5"
+func describeElements(elements []ast.PlayableNode) string {
+	var parts []string
+	for _, element := range elements {
+		switch e := element.(type) {
+		case *ast.NoteNode:
+			parts = append(parts, fmt.Sprintf("%s%d", strings.ToUpper(e.Name[:1])+e.Name[1:], e.Octave))
+		case *ast.ChordNode:
+			switch content := e.Content.(type) {
+			case string:
+				parts = append(parts, fmt.Sprintf("%q", content))
+			case []*ast.NoteNode:
+				var notes []ast.PlayableNode
+				for _, note := range content {
+					notes = append(notes, note)
+				}
+				parts = append(parts, "["+describeElements(notes)+"]")
+			}
+		case *ast.DynamicNode:
+			parts = append(parts, `\`+e.Dynamic.String())
+		default:
+			parts = append(parts, fmt.Sprintf("%T", element))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// 解析 section s { body } 并返回其中的元素
+func sectionElements(t *testing.T, settings, body string) string {
+	t.Helper()
+	score := mustParse(t, settings+"\ntrack t {\n section s {\n"+body+"\n }\n}\n")
+	track := score.Elements[0].(*ast.TrackNode)
+	return describeElements(track.Elements[0].(*ast.SectionNode).Elements)
+}
+
+func TestDynamicOrNoteF(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		body     string
+		want     string
+	}{
+		{"绝对八度中单独的 f 是力度记号", "", "f C4 F4", `\f C4 F4`},
+		{"绝对八度中 f 后跟八度数字是音符", "", "f4 C4", "F4 C4"},
+		{"相对八度中单独的 f 是音符", "set { octave_mode: relative }", "c d e f g a b c", "C4 D4 E4 F4 G4 A4 B4 C5"},
+		{"相对八度中用反斜杠写力度记号", "set { octave_mode: relative }", `\f c f \mf d`, `\f C4 F4 \mf D4`},
+		{"绝对八度中也可以写反斜杠", "", `\ff C4`, `\ff C4`},
+		{"相对八度中其他力度记号不受影响", "set { octave_mode: relative }", "p c mf d", `\p C4 \mf D4`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sectionElements(t, tt.settings, tt.body); got != tt.want {
+				t.Errorf("得到 %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownBackslashDynamic(t *testing.T) {
+	errors := parseErrors("track t {\n section s {\n \\xyz C4\n }\n}\n")
+	if len(errors) == 0 || !strings.Contains(errors[0], "未知的力度记号") {
+		t.Errorf("期望未知力度记号的错误，得到 %v", errors)
+	}
+}
+
+func TestChordSymbolOrSingleNote(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"[C5]/1", "[C5]"},
+		{"[G7]", "[G7]"},
+		{"[Bb6]", "[Bb6]"},
+		{"[C4 E4 G4]", "[C4 E4 G4]"},
+		{`["C5"]`, `"C5"`},
+		{`["G7"]/4 oct3`, `"G7"`},
+		{"[Cmaj7]", `"Cmaj7"`},
+		{"[G7/B]", `"G7/B"`},
+		{"[C13]", `"C13"`},
+		{"[C69]", `"C69"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			if got := sectionElements(t, "", tt.body); got != tt.want {
+				t.Errorf("得到 %s，期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidQuotedChordSymbol(t *testing.T) {
+	errors := parseErrors("track t {\n section s {\n [\"X9\"]\n }\n}\n")
+	if len(errors) == 0 || !strings.Contains(errors[0], "3:3") {
+		t.Errorf("期望在引号处报错，得到 %v", errors)
+	}
+}
@@ -0,0 +1,126 @@
+package dsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 八度模式：set { octave_mode: relative } 之后，音符可以省略八度数字，
+// 落在离上一个音符最近的八度，用 ' 和 , 升高或降低一个八度
+const (
+	absoluteOctaveMode = "absolute"
+	relativeOctaveMode = "relative"
+)
+
+// 相对八度的参考音：上一个音符的音级 (C=0 ... B=6) 和八度
+type relativePitch struct {
+	step   int
+	octave int
+}
+
+// 每个容器开始时的参考音 C4
+var defaultRelativePitch = relativePitch{step: 0, octave: 4}
+
+// 音符名 (如 Cs、eb、fn) 的音级，升降号不影响
+func noteStep(name string) int {
+	return strings.Index("CDEFGAB", strings.ToUpper(name[:1]))
+}
+
+// 离参考音最近的八度：音级相差不超过四度，增四度向上
+func (r relativePitch) nearest(step int) int {
+	octave := r.octave
+	switch diff := step - r.step; {
+	case diff > 3:
+		octave--
+	case diff < -3:
+		octave++
+	}
+	return octave
+}
+
+// set 块中的 octave_mode 从此处开始作用于当前容器
+func (p *Parser) applyOctaveMode(parameters map[string]interface{}) {
+	switch parameters["octave_mode"] {
+	case relativeOctaveMode:
+		p.relative = true
+	case absoluteOctaveMode:
+		p.relative = false
+	}
+}
+
+// 进入音轨或段落：容器内的八度模式和参考音不影响外层，返回恢复外层的函数
+func (p *Parser) enterOctaveScope() func() {
+	relative, reference := p.relative, p.reference
+	return func() { p.relative, p.reference = relative, reference }
+}
+
+// 解析音符名之后的八度：八度数字，或相对模式下的 ' , 记号 (可以没有)
+func (p *Parser) parseOctave(name Token) (int, bool) {
+	step := noteStep(name.Literal)
+
+	if p.currentToken.Type == NUMBER {
+		octave, err := strconv.Atoi(p.currentToken.Literal)
+		if err != nil || octave < 0 || octave > 9 {
+			p.addError(fmt.Sprintf("无效的八度值: %s", p.currentToken.Literal))
+			return 0, false
+		}
+		digit := p.currentToken
+		p.nextToken()
+		if p.currentToken.Type == APOSTROPHE {
+			p.addError("八度数字之后不能再写八度记号 '")
+			p.skipOctaveMarks(digit)
+			return 0, false
+		}
+		p.reference = relativePitch{step: step, octave: octave}
+		return octave, true
+	}
+
+	if !p.relative {
+		if p.isOctaveMark(name) {
+			p.addError(fmt.Sprintf("八度记号 %s 只能在 octave_mode: relative 中使用", p.currentToken.Literal))
+			p.skipOctaveMarks(name)
+		} else {
+			p.addError(fmt.Sprintf("期望八度数字，得到 %s", p.currentToken.Literal))
+		}
+		return 0, false
+	}
+
+	octave := p.reference.nearest(step)
+	previous := name
+	for p.isOctaveMark(previous) {
+		if p.currentToken.Type == APOSTROPHE {
+			octave++
+		} else {
+			octave--
+		}
+		previous = p.currentToken
+		p.nextToken()
+	}
+	if octave < 0 || octave > 9 {
+		p.addError(fmt.Sprintf("音符 %s 的八度超出范围 0-9: %d", name.Literal, octave))
+		return 0, false
+	}
+
+	p.reference = relativePitch{step: step, octave: octave}
+	return octave, true
+}
+
+// 当前token是否为紧跟在 previous 之后的八度记号；与前面隔开的逗号是分隔符
+func (p *Parser) isOctaveMark(previous Token) bool {
+	switch p.currentToken.Type {
+	case APOSTROPHE:
+		return true
+	case COMMA:
+		return adjacent(previous, p.currentToken)
+	}
+	return false
+}
+
+// 出错时跳过紧跟的八度记号，避免连锁错误
+func (p *Parser) skipOctaveMarks(previous Token) {
+	for p.isOctaveMark(previous) {
+		previous = p.currentToken
+		p.nextToken()
+	}
+}
@@ -0,0 +1,395 @@
+package dsl
+
+import (
+	"catRock/pkg/dsl/ast"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 模板定义：内容以token形式保存，use 时替换参数后再解析
+type templateDef struct {
+	node  *ast.TemplateNode
+	body  []Token
+	scope *scope // 定义模板的文件的作用域，展开时按它查找名称
+
+	relative bool // 定义处使用相对八度，模板内容从参考音 C4 开始
+}
+
+// 名称作用域：一个文件中可通过 use 引用的模板，以及导入的音轨和段落
+type scope struct {
+	templates map[string]*templateDef
+	elements  map[string]ast.PlayableNode
+}
+
+func newScope() *scope {
+	return &scope{
+		templates: map[string]*templateDef{},
+		elements:  map[string]ast.PlayableNode{},
+	}
+}
+
+// 解析模板定义：template name(a, b) { ... }
+func (p *Parser) parseTemplate() *ast.TemplateNode {
+	position := p.currentToken.Position
+
+	if !p.expectToken(TEMPLATE) {
+		return nil
+	}
+
+	if p.currentToken.Type != IDENTIFIER {
+		p.addError(fmt.Sprintf("期望模板名称，得到 %s", p.currentToken.Literal))
+		return nil
+	}
+	name := p.currentToken.Literal
+	p.nextToken()
+
+	params := []string{}
+	if p.currentToken.Type == LPAREN {
+		p.nextToken()
+		for p.currentToken.Type != RPAREN && p.currentToken.Type != EOF {
+			if p.currentToken.Type != IDENTIFIER {
+				p.addError(fmt.Sprintf("模板 %s 的参数名无效: %s (参数名不能是音符名或关键字)", name, p.currentToken.Literal))
+				return nil
+			}
+			for _, param := range params {
+				if param == p.currentToken.Literal {
+					p.addError(fmt.Sprintf("模板 %s 的参数 %s 重复", name, param))
+				}
+			}
+			params = append(params, p.currentToken.Literal)
+			p.nextToken()
+
+			if p.currentToken.Type == COMMA {
+				p.nextToken()
+			}
+		}
+		if !p.expectToken(RPAREN) {
+			return nil
+		}
+	}
+
+	if !p.expectToken(LBRACE) {
+		return nil
+	}
+
+	// 收集到匹配的 } 为止的token
+	body := []Token{}
+	depth := 0
+	for depth > 0 || p.currentToken.Type != RBRACE {
+		switch p.currentToken.Type {
+		case EOF:
+			p.addError(fmt.Sprintf("模板 %s 缺少 }", name))
+			return nil
+		case LBRACE:
+			depth++
+		case RBRACE:
+			depth--
+		}
+		body = append(body, p.currentToken)
+		p.nextToken()
+	}
+	p.nextToken() // 跳过 '}'
+
+	node := &ast.TemplateNode{
+		Name:     name,
+		Params:   params,
+		Source:   tokensToSource(body),
+		Position: position,
+	}
+
+	if existing, ok := p.scope.templates[name]; ok {
+		p.errors = append(p.errors, fmt.Sprintf("解析错误 %s - 模板 %s 重复定义 (首次定义于 %s)",
+			position, name, existing.node.Position))
+		return nil
+	}
+	if _, ok := p.scope.elements[name]; ok {
+		p.errors = append(p.errors, fmt.Sprintf("解析错误 %s - 模板 %s 与导入的音轨或段落重名", position, name))
+		return nil
+	}
+	p.scope.templates[name] = &templateDef{node: node, body: body, scope: p.scope, relative: p.relative}
+
+	return node
+}
+
+// 解析模板使用：use name(args) transpose(+12) augment(2) velocity(0.8)
+func (p *Parser) parseUse() *ast.UseNode {
+	position := p.currentToken.Position
+
+	if !p.expectToken(USE) {
+		return nil
+	}
+
+	if p.currentToken.Type != IDENTIFIER {
+		p.addError(fmt.Sprintf("期望模板名称，得到 %s", p.currentToken.Literal))
+		return nil
+	}
+
+	use := &ast.UseNode{
+		Name:          p.currentToken.Literal,
+		Args:          []string{},
+		TimeScale:     1,
+		VelocityScale: 1,
+		Position:      position,
+	}
+	nameToken := p.currentToken
+	p.nextToken()
+
+	// 导入别名下的名称：drums.groove
+	for p.currentToken.Type == DOT && adjacent(nameToken, p.currentToken) &&
+		p.peekToken.Type == IDENTIFIER && adjacent(p.currentToken, p.peekToken) {
+		p.nextToken()
+		use.Name += "." + p.currentToken.Literal
+		nameToken = p.currentToken
+		p.nextToken()
+	}
+
+	// 实参：紧跟模板名的括号内以逗号分隔的token序列 (有空格时视为其后的分组)
+	args := [][]Token{}
+	if p.currentToken.Type == LPAREN && adjacent(nameToken, p.currentToken) {
+		p.nextToken()
+		current := []Token{}
+		depth := 0
+		for depth > 0 || p.currentToken.Type != RPAREN {
+			switch p.currentToken.Type {
+			case EOF:
+				p.addError(fmt.Sprintf("use %s 的参数缺少 )", use.Name))
+				return nil
+			case LPAREN:
+				depth++
+			case RPAREN:
+				depth--
+			}
+			if depth == 0 && p.currentToken.Type == COMMA {
+				args = append(args, current)
+				current = []Token{}
+			} else {
+				current = append(current, p.currentToken)
+			}
+			p.nextToken()
+		}
+		p.nextToken() // 跳过 ')'
+		if len(current) > 0 || len(args) > 0 {
+			args = append(args, current)
+		}
+	}
+	for _, arg := range args {
+		use.Args = append(use.Args, tokensToSource(arg))
+	}
+
+	p.parseUseModifiers(use)
+
+	p.uses = append(p.uses, use)
+	p.useArgs[use] = args
+	return use
+}
+
+// 解析 use 之后的变换修饰
+func (p *Parser) parseUseModifiers(use *ast.UseNode) {
+	for p.currentToken.Type == IDENTIFIER && p.peekToken.Type == LPAREN {
+		modifier := p.currentToken.Literal
+		switch modifier {
+		case "transpose", "augment", "diminish", "velocity":
+		default:
+			return
+		}
+		p.nextToken()
+		p.nextToken() // 跳过 '('
+
+		switch modifier {
+		case "transpose":
+			sign := 1
+			if p.currentToken.Literal == "+" || p.currentToken.Literal == "-" {
+				if p.currentToken.Literal == "-" {
+					sign = -1
+				}
+				p.nextToken()
+			}
+			semitones, err := strconv.Atoi(p.currentToken.Literal)
+			if p.currentToken.Type != NUMBER || err != nil {
+				p.addError(fmt.Sprintf("transpose 期望半音数，得到 %s", p.currentToken.Literal))
+				p.skipModifier()
+				continue
+			}
+			p.nextToken()
+			use.Transpose += sign * semitones
+
+		case "augment", "diminish", "velocity":
+			factor, ok := p.parseFactor()
+			if !ok {
+				p.addError(fmt.Sprintf("%s 期望正数比例 (如 2、1.5、3/2)，得到 %s", modifier, p.currentToken.Literal))
+				p.skipModifier()
+				continue
+			}
+			switch modifier {
+			case "augment":
+				use.TimeScale *= factor
+			case "diminish":
+				use.TimeScale /= factor
+			case "velocity":
+				use.VelocityScale *= factor
+			}
+		}
+
+		if !p.expectToken(RPAREN) {
+			return
+		}
+	}
+}
+
+// 出错时跳过修饰剩余的部分
+func (p *Parser) skipModifier() {
+	for p.currentToken.Type != RPAREN && p.currentToken.Type != NEWLINE && p.currentToken.Type != EOF {
+		p.nextToken()
+	}
+	if p.currentToken.Type == RPAREN {
+		p.nextToken()
+	}
+}
+
+// 解析正数比例：整数、小数 (1.5) 或分数 (3/2)
+func (p *Parser) parseFactor() (float64, bool) {
+	if p.currentToken.Type != NUMBER {
+		return 0, false
+	}
+	text := p.currentToken.Literal
+	p.nextToken()
+
+	if p.currentToken.Type == DOT && p.peekToken.Type == NUMBER {
+		p.nextToken()
+		text += "." + p.currentToken.Literal
+		p.nextToken()
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if p.currentToken.Type == SLASH && p.peekToken.Type == NUMBER {
+		p.nextToken()
+		denominator, err := strconv.ParseFloat(p.currentToken.Literal, 64)
+		p.nextToken()
+		if err != nil || denominator == 0 {
+			return 0, false
+		}
+		value /= denominator
+	}
+
+	return value, value > 0
+}
+
+// 展开所有待展开的模板使用，stack 为正在展开的模板名 (用于检测循环引用)
+func (p *Parser) expandUses(stack []string) {
+	uses := p.uses
+	p.uses = nil
+
+	for _, use := range uses {
+		p.expandUse(use, stack)
+	}
+}
+
+func (p *Parser) expandUse(use *ast.UseNode, stack []string) {
+	usedAt := fmt.Sprintf("使用于 %s", use.Position)
+	fail := func(msg string) {
+		p.errors = append(p.errors, fmt.Sprintf("解析错误 %s - %s (%s)", use.Position, msg, usedAt))
+	}
+
+	// 导入的音轨或段落：直接引用，不接受参数
+	if element, ok := p.scope.elements[use.Name]; ok {
+		if len(p.useArgs[use]) > 0 {
+			fail(fmt.Sprintf("%s 不是模板，不能传入参数", use.Name))
+			return
+		}
+		use.Element = element
+		return
+	}
+
+	def, ok := p.scope.templates[use.Name]
+	if !ok {
+		fail(fmt.Sprintf("未定义的模板 %s", use.Name))
+		return
+	}
+
+	for _, name := range stack {
+		if name == use.Name {
+			fail(fmt.Sprintf("模板 %s 循环引用: %s", use.Name, strings.Join(append(stack, use.Name), " → ")))
+			return
+		}
+	}
+
+	args := p.useArgs[use]
+	if len(args) != len(def.node.Params) {
+		fail(fmt.Sprintf("模板 %s 需要 %d 个参数，得到 %d 个", use.Name, len(def.node.Params), len(args)))
+		return
+	}
+
+	// 替换参数
+	values := map[string][]Token{}
+	for i, param := range def.node.Params {
+		values[param] = args[i]
+	}
+	tokens := []Token{}
+	for _, tok := range def.body {
+		if value, ok := values[tok.Literal]; ok && tok.Type == IDENTIFIER {
+			tokens = append(tokens, value...)
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+
+	// 用子解析器按段落内容解析
+	sub := NewParser(NewTokenLexer(tokens))
+	sub.scope = def.scope
+	sub.relative = def.relative
+
+	body := &ast.SectionNode{
+		Name:     use.Name,
+		Sets:     []*ast.SetNode{},
+		Elements: []ast.PlayableNode{},
+		Position: def.node.Position,
+	}
+	for sub.currentToken.Type != EOF {
+		element := sub.parseContainerElement()
+		switch elem := element.(type) {
+		case *ast.SetNode:
+			elem.Context = ast.SectionContext
+			body.Sets = append(body.Sets, elem)
+		case ast.PlayableNode:
+			body.Elements = append(body.Elements, elem)
+		}
+	}
+	sub.expandUses(append(append([]string{}, stack...), use.Name))
+
+	// 模板内的错误附上模板名和使用位置
+	for _, err := range sub.errors {
+		p.errors = append(p.errors, fmt.Sprintf("%s (模板 %s，%s)", err, use.Name, usedAt))
+	}
+
+	use.Body = body
+}
+
+// 两个token之间没有空白
+func adjacent(previous Token, next Token) bool {
+	return next.Position.Line == previous.Position.Line &&
+		next.Position.Column == previous.Position.Column+len(previous.Literal)
+}
+
+// 把token序列还原为书写形式，相邻token之间按原位置决定是否加空格
+func tokensToSource(tokens []Token) string {
+	var builder strings.Builder
+	for i, tok := range tokens {
+		if tok.Type == NEWLINE {
+			builder.WriteString(" ")
+			continue
+		}
+		if i > 0 && tokens[i-1].Type != NEWLINE && !adjacent(tokens[i-1], tok) {
+			builder.WriteString(" ")
+		}
+		if tok.Type == STRING {
+			builder.WriteString(`"` + tok.Literal + `"`)
+			continue
+		}
+		builder.WriteString(tok.Literal)
+	}
+	return strings.TrimSpace(builder.String())
+}
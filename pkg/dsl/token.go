@@ -23,11 +23,17 @@ const (
 	// 字面量
 	NUMBER
 	IDENTIFIER // 字符串
+	STRING     // "..." 带引号的字符串
 
 	// 关键字
 	SET     //set
 	TRACK   //track
 	SECTION //section
+	REPEAT  //repeat
+	ENDING  //ending
+	TEMPLATE //template
+	USE      //use
+	IMPORT   //import
 
 	// 音符名称
 	NOTE_C
@@ -62,6 +68,19 @@ const (
 	RBRACKET // ]
 	LPAREN   // (
 	RPAREN   // )
+	TILDE    // ~
+	TIE      // _ 延音线
+	COMMA    // ,
+	CARET    // ^ 重音
+	MINUS    // - 保持音
+	STAR     // * 断音
+	BARLINE  // | 小节线
+	BANG     // ! 延长记号
+	ARROW    // -> 速度渐变
+	LANGLE   // < 渐强
+	RANGLE   // > 渐弱
+	APOSTROPHE // ' 升高八度 (相对八度)
+	DYNAMIC    // \f \mf 等力度记号
 )
 
 type Token struct {
@@ -78,9 +97,15 @@ var tokenNames = map[TokenType]string{
     NEWLINE:    "NEWLINE",
     NUMBER:     "NUMBER",
     IDENTIFIER: "IDENTIFIER",
+    STRING:     "STRING",
     SET:        "SET",
     TRACK:      "TRACK",
     SECTION:    "SECTION",
+    REPEAT:     "REPEAT",
+    ENDING:     "ENDING",
+    TEMPLATE:   "TEMPLATE",
+    USE:        "USE",
+    IMPORT:     "IMPORT",
     NOTE_C:     "NOTE_C",
     NOTE_D:     "NOTE_D",
     NOTE_E:     "NOTE_E",
@@ -114,6 +139,19 @@ var tokenNames = map[TokenType]string{
     RBRACKET:   "RBRACKET",
     LPAREN:     "LPAREN",
     RPAREN:     "RPAREN",
+    TILDE:      "TILDE",
+    TIE:        "TIE",
+    COMMA:      "COMMA",
+    CARET:      "CARET",
+    MINUS:      "MINUS",
+    STAR:       "STAR",
+    BARLINE:    "BARLINE",
+    BANG:       "BANG",
+    ARROW:      "ARROW",
+    LANGLE:     "LANGLE",
+    RANGLE:     "RANGLE",
+    APOSTROPHE: "APOSTROPHE",
+    DYNAMIC:    "DYNAMIC",
 }
 
 func (t TokenType) String() string {
@@ -2,6 +2,7 @@ package io
 
 import (
 	"catRock/pkg/core"
+	"sort"
 )
 
 type ConnectStatus int // 连接状态码
@@ -65,4 +66,83 @@ const (
     NOTE_OFF_EVENT
     PROGRAM_CHANGE_EVENT
     CONTROL_CHANGE_EVENT
-)
\ No newline at end of file
+)
+
+// 通过低级接口发送一个事件
+func SendEvent(sender MIDIEventSender, event Event) error {
+    switch event.Type {
+    case NOTE_ON_EVENT:
+        return sender.SendNoteOn(event.Channel, event.Data1, event.Data2)
+    case NOTE_OFF_EVENT:
+        return sender.SendNoteOff(event.Channel, event.Data1, event.Data2)
+    case PROGRAM_CHANGE_EVENT:
+        return sender.SendProgramChange(event.Channel, event.Data1)
+    case CONTROL_CHANGE_EVENT:
+        return sender.SendControlChange(event.Channel, event.Data1, event.Data2)
+    default:
+        return nil
+    }
+}
+
+// 按时间排序；同一时刻依次为控制/音色、NOTE_OFF、NOTE_ON，
+// 避免重复音高被刚发送的NOTE_OFF截断
+func SortEvents(events []Event) {
+    sort.SliceStable(events, func(i, j int) bool {
+        if events[i].Time != events[j].Time {
+            return events[i].Time < events[j].Time
+        }
+        return eventOrder(events[i].Type) < eventOrder(events[j].Type)
+    })
+}
+
+func eventOrder(eventType EventType) int {
+    switch eventType {
+    case PROGRAM_CHANGE_EVENT, CONTROL_CHANGE_EVENT:
+        return 0
+    case NOTE_OFF_EVENT:
+        return 1
+    default:
+        return 2
+    }
+}
+
+// MIDI通道模式控制器
+const (
+    CC_ALL_SOUND_OFF         uint8 = 120
+    CC_RESET_ALL_CONTROLLERS uint8 = 121
+    CC_ALL_NOTES_OFF         uint8 = 123
+)
+
+// 对指定通道发送 All Notes Off (CC123) 和 Reset All Controllers (CC121)
+func ResetChannels(sender MIDIEventSender, channels []uint8) error {
+    var firstErr error
+    for _, channel := range channels {
+        if err := sender.SendControlChange(channel, CC_ALL_NOTES_OFF, 0); err != nil && firstErr == nil {
+            firstErr = err
+        }
+        if err := sender.SendControlChange(channel, CC_RESET_ALL_CONTROLLERS, 0); err != nil && firstErr == nil {
+            firstErr = err
+        }
+    }
+    return firstErr
+}
+
+// 紧急静音：对全部16个通道的所有音高发送NOTE_OFF，
+// 再发送 All Sound Off、All Notes Off 和 Reset All Controllers
+func Panic(sender MIDIEventSender) error {
+    var firstErr error
+    for channel := uint8(0); channel < 16; channel++ {
+        for note := 0; note < 128; note++ {
+            if err := sender.SendNoteOff(channel, uint8(note), 0); err != nil && firstErr == nil {
+                firstErr = err
+            }
+        }
+        if err := sender.SendControlChange(channel, CC_ALL_SOUND_OFF, 0); err != nil && firstErr == nil {
+            firstErr = err
+        }
+        if err := ResetChannels(sender, []uint8{channel}); err != nil && firstErr == nil {
+            firstErr = err
+        }
+    }
+    return firstErr
+}
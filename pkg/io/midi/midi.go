@@ -19,6 +19,7 @@ type MIDIPlayer struct {
 	volume    int
 	Channel   uint8             // MIDI 通道
 	Program   core.InstrumentID // MIDI 程序号
+	Port      string            // 输出端口：序号、完整名称或名称片段，为空时使用第一个端口
 }
 
 var _ io.IO = (*MIDIPlayer)(nil) // 确保 MIDIPlayer 实现了 io.IO 接口
@@ -39,13 +40,12 @@ func (p *MIDIPlayer) Connect() (io.ConnectStatus, error) {
 	// 获取可用的 MIDI 输出端口
 	outports := midi.GetOutPorts()
 
-	// 检查是否有可用端口
-	if len(outports) == 0 {
-		return io.Disconnected, fmt.Errorf("no MIDI output ports available")
+	// 按 Port 选择端口
+	index, err := SelectPort(portInfos(outports), p.Port)
+	if err != nil {
+		return io.Disconnected, err
 	}
-
-	// 选择第一个可用端口
-	p.driverOut = outports[0]
+	p.driverOut = outports[index]
 
 	// 创建发送器
 	sender, err := midi.SendTo(p.driverOut)
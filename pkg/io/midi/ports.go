@@ -0,0 +1,95 @@
+package midi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gitlab.com/gomidi/midi/v2"
+)
+
+// 指定输出端口的环境变量，取值与 --port 相同
+const PortEnv = "CATROCK_MIDI_PORT"
+
+// MIDI输出端口
+type PortInfo struct {
+	Index int
+	Name  string
+}
+
+// 列出可用的MIDI输出端口
+func ListOutPorts() []PortInfo {
+	return portInfos(midi.GetOutPorts())
+}
+
+func portInfos(outports midi.OutPorts) []PortInfo {
+	ports := make([]PortInfo, len(outports))
+	for i, port := range outports {
+		ports[i] = PortInfo{Index: i, Name: port.String()}
+	}
+	return ports
+}
+
+// 按序号、完整名称或名称片段（不区分大小写）选择端口，返回端口序号。
+// spec 为空时选择第一个端口
+func SelectPort(ports []PortInfo, spec string) (int, error) {
+	if len(ports) == 0 {
+		return -1, fmt.Errorf("no MIDI output ports available")
+	}
+
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0, nil
+	}
+
+	// 序号
+	if index, err := strconv.Atoi(spec); err == nil {
+		if index < 0 || index >= len(ports) {
+			return -1, fmt.Errorf("MIDI output port index %d out of range (0-%d)", index, len(ports)-1)
+		}
+		return index, nil
+	}
+
+	// 完整名称
+	for i, port := range ports {
+		if port.Name == spec {
+			return i, nil
+		}
+	}
+	for i, port := range ports {
+		if strings.EqualFold(port.Name, spec) {
+			return i, nil
+		}
+	}
+
+	// 名称片段，必须唯一
+	matches := []int{}
+	for i, port := range ports {
+		if strings.Contains(strings.ToLower(port.Name), strings.ToLower(spec)) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("no MIDI output port matches %q, available: %s", spec, portNames(ports, nil))
+	case 1:
+		return matches[0], nil
+	default:
+		return -1, fmt.Errorf("%q matches several MIDI output ports: %s", spec, portNames(ports, matches))
+	}
+}
+
+func portNames(ports []PortInfo, indexes []int) string {
+	if indexes == nil {
+		for i := range ports {
+			indexes = append(indexes, i)
+		}
+	}
+
+	names := []string{}
+	for _, i := range indexes {
+		names = append(names, fmt.Sprintf("[%d] %s", ports[i].Index, ports[i].Name))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,540 @@
+package midifile
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// 默认量化网格：十六分音符
+const DefaultGrid = 16
+
+// 支持的量化网格（1/N 全音符），与DSL可表示的时值一致
+var validGrids = []int{1, 2, 4, 8, 16}
+
+// core.NewNote 以MIDI 60作为C0
+const midiOctaveOffset = 5
+
+// 音符未写力度时的默认值
+const defaultVelocity = 100
+
+// 鼓组通道（0起始）及对应的CatRock乐器ID
+const (
+	drumChannel    = 9
+	drumInstrument = 128
+)
+
+type ImportOptions struct {
+	Grid   int    // 量化网格：1/Grid 全音符
+	Name   string // 外层轨道名
+	Source string // 来源文件名，写入生成文件的注释
+}
+
+type ImportResult struct {
+	Source   string   // 生成的DSL源码
+	Warnings []string // 导入过程中的有损转换提示
+}
+
+// 导入的音符（时间单位：拍）
+type importedNote struct {
+	start    float64
+	end      float64
+	key      uint8
+	velocity uint8
+}
+
+// 同时开始、同时结束的一组音符，输出为单音或和弦
+type noteGroup struct {
+	start      float64
+	end        float64
+	keys       []uint8
+	velocities []uint8
+}
+
+type channelData struct {
+	channel    uint8
+	program    int
+	hasProgram bool
+	notes      []importedNote
+}
+
+// 从文件导入
+func ImportFile(filename string, options ImportOptions) (*ImportResult, error) {
+	file, err := smf.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取MIDI文件失败: %v", err)
+	}
+
+	if options.Source == "" {
+		options.Source = filepath.Base(filename)
+	}
+	if options.Name == "" {
+		options.Name = identifierFromFilename(filename)
+	}
+
+	return Import(file, options)
+}
+
+// 将SMF转换为CatRock DSL源码
+func Import(file *smf.SMF, options ImportOptions) (*ImportResult, error) {
+	if options.Grid == 0 {
+		options.Grid = DefaultGrid
+	}
+	if !isValidGrid(options.Grid) {
+		return nil, fmt.Errorf("无效的量化网格: %d (可选: 1, 2, 4, 8, 16)", options.Grid)
+	}
+	if options.Name == "" {
+		options.Name = "imported"
+	}
+
+	ticks, ok := file.TimeFormat.(smf.MetricTicks)
+	if !ok {
+		return nil, fmt.Errorf("不支持的时间格式: %v (仅支持 metric ticks)", file.TimeFormat)
+	}
+	resolution := float64(ticks.Resolution())
+	gridBeats := 4.0 / float64(options.Grid)
+
+	result := &ImportResult{}
+	bpm := 0.0
+	channels := map[uint8]*channelData{}
+
+	getChannel := func(channel uint8) *channelData {
+		if data, ok := channels[channel]; ok {
+			return data
+		}
+		data := &channelData{channel: channel}
+		channels[channel] = data
+		return data
+	}
+
+	for _, track := range file.Tracks {
+		var absTicks int64
+		active := map[[2]uint8][]importedNote{} // (通道, 音高) → 未结束的音符
+
+		for _, event := range track {
+			absTicks += int64(event.Delta)
+			beat := float64(absTicks) / resolution
+
+			var channel, key, velocity, program uint8
+			var tempo float64
+
+			switch {
+			case event.Message.GetMetaTempo(&tempo):
+				if bpm == 0 {
+					bpm = tempo
+					if beat > 0 {
+						result.addWarning("第%.2f拍的速度 (%.1f BPM) 用作全曲速度，之前按该速度播放", beat, tempo)
+					}
+				} else if tempo != bpm {
+					result.addWarning("忽略第%.2f拍的速度变化 (%.1f BPM)", beat, tempo)
+				}
+
+			case event.Message.GetProgramChange(&channel, &program):
+				data := getChannel(channel)
+				if !data.hasProgram {
+					data.program = int(program)
+					data.hasProgram = true
+				}
+
+			case event.Message.GetNoteStart(&channel, &key, &velocity):
+				id := [2]uint8{channel, key}
+				active[id] = append(active[id], importedNote{start: beat, key: key, velocity: velocity})
+
+			case event.Message.GetNoteEnd(&channel, &key):
+				id := [2]uint8{channel, key}
+				if pending := active[id]; len(pending) > 0 {
+					note := pending[0]
+					note.end = beat
+					active[id] = pending[1:]
+					data := getChannel(channel)
+					data.notes = append(data.notes, note)
+				}
+			}
+		}
+
+		// 轨道结束时仍未关闭的音符
+		for id, pending := range active {
+			for _, note := range pending {
+				note.end = float64(absTicks) / resolution
+				data := getChannel(id[0])
+				data.notes = append(data.notes, note)
+			}
+		}
+	}
+
+	if bpm == 0 {
+		bpm = 120
+	}
+
+	ordered := []*channelData{}
+	for _, data := range channels {
+		if len(data.notes) > 0 {
+			ordered = append(ordered, data)
+		}
+	}
+	if len(ordered) == 0 {
+		return nil, fmt.Errorf("MIDI文件中没有音符")
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].channel < ordered[j].channel
+	})
+
+	var out strings.Builder
+	if options.Source != "" {
+		fmt.Fprintf(&out, "// 由 catrock import 从 %s 导入\n", options.Source)
+	}
+	fmt.Fprintf(&out, "set {\n    BPM: %d\n}\n\n", int(math.Round(bpm)))
+
+	// 顶层多个元素会顺序播放，所有通道放在同一个外层轨道中并行
+	fmt.Fprintf(&out, "track %s {\n", options.Name)
+	for i, data := range ordered {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		writeChannel(&out, data, gridBeats, result)
+	}
+	out.WriteString("}\n")
+
+	result.Source = out.String()
+	return result, nil
+}
+
+// 输出一个通道：一个轨道，每个复音声部一个段落
+func writeChannel(out *strings.Builder, data *channelData, gridBeats float64, result *ImportResult) {
+	instrument := data.program
+	if data.channel == drumChannel {
+		instrument = drumInstrument
+	}
+
+	fmt.Fprintf(out, "    track channel_%s {\n", numberName(int(data.channel)))
+	fmt.Fprintf(out, "        set {\n            channel: %d\n            instrument: %d\n        }\n", data.channel, instrument)
+
+	groups, moved := quantizeNotes(data.notes, gridBeats)
+	if moved > 0 {
+		result.addWarning("通道%d 有%d个音符的起止时间不在 1/%d 网格上 (如三连音)，已量化", data.channel, moved, int(math.Round(4/gridBeats)))
+	}
+
+	shifted, mixed := 0, 0
+	for _, group := range groups {
+		for _, key := range group.keys {
+			if _, ok := pitchName(key); !ok {
+				shifted++
+			}
+		}
+		if _, same := groupVelocity(group.velocities); !same {
+			mixed++
+		}
+	}
+	if shifted > 0 {
+		result.addWarning("通道%d 有%d个音符低于C0 (MIDI 60)，已按八度上移", data.channel, shifted)
+	}
+	if mixed > 0 {
+		result.addWarning("通道%d 有%d个和弦的音符力度不同，已取平均力度", data.channel, mixed)
+	}
+
+	for i, voice := range splitVoices(groups) {
+		fmt.Fprintf(out, "\n        section voice_%s {\n", numberName(i+1))
+		writeVoice(out, voice)
+		out.WriteString("        }\n")
+	}
+
+	out.WriteString("    }\n")
+}
+
+// 量化并合并同时开始、同时结束的音符，返回起止时间被移动的音符数
+func quantizeNotes(notes []importedNote, gridBeats float64) ([]noteGroup, int) {
+	byTime := map[[2]float64]*noteGroup{}
+	groups := []*noteGroup{}
+	moved := 0
+
+	for _, note := range notes {
+		start := math.Round(note.start/gridBeats) * gridBeats
+		end := math.Round(note.end/gridBeats) * gridBeats
+		if end <= start {
+			end = start + gridBeats
+		}
+		if math.Abs(start-note.start) > 1e-6 || math.Abs(end-note.end) > 1e-6 {
+			moved++
+		}
+
+		id := [2]float64{start, end}
+		group, ok := byTime[id]
+		if !ok {
+			group = &noteGroup{start: start, end: end}
+			byTime[id] = group
+			groups = append(groups, group)
+		}
+
+		duplicate := false
+		for _, key := range group.keys {
+			if key == note.key {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			group.keys = append(group.keys, note.key)
+			group.velocities = append(group.velocities, note.velocity)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].start != groups[j].start {
+			return groups[i].start < groups[j].start
+		}
+		return groups[i].end > groups[j].end
+	})
+
+	result := make([]noteGroup, len(groups))
+	for i, group := range groups {
+		sort.Sort(byKey{group})
+		result[i] = *group
+	}
+	return result, moved
+}
+
+// 按音高排序，力度随音高一起移动
+type byKey struct{ *noteGroup }
+
+func (g byKey) Len() int           { return len(g.keys) }
+func (g byKey) Less(i, j int) bool { return g.keys[i] < g.keys[j] }
+func (g byKey) Swap(i, j int) {
+	g.keys[i], g.keys[j] = g.keys[j], g.keys[i]
+	g.velocities[i], g.velocities[j] = g.velocities[j], g.velocities[i]
+}
+
+// 把重叠的音符组分配到互不重叠的声部
+func splitVoices(groups []noteGroup) [][]noteGroup {
+	voices := [][]noteGroup{}
+	ends := []float64{}
+
+	for _, group := range groups {
+		placed := false
+		for i := range voices {
+			if ends[i] <= group.start+1e-9 {
+				voices[i] = append(voices[i], group)
+				ends[i] = group.end
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			voices = append(voices, []noteGroup{group})
+			ends = append(ends, group.end)
+		}
+	}
+
+	return voices
+}
+
+// 输出一个声部，每小节（4拍）换行
+func writeVoice(out *strings.Builder, voice []noteGroup) {
+	const indent = "            "
+	const barLength = 4.0
+
+	line := []string{}
+	position := 0.0
+	nextBar := barLength
+
+	emit := func(token string, beats float64) {
+		line = append(line, token)
+		position += beats
+		if position >= nextBar-1e-9 {
+			out.WriteString(indent + strings.Join(line, " ") + "\n")
+			line = line[:0]
+			for nextBar <= position+1e-9 {
+				nextBar += barLength
+			}
+		}
+	}
+
+	for _, group := range voice {
+		if group.start > position+1e-9 {
+			for _, den := range durationDenominators(group.start - position) {
+				emit(fmt.Sprintf("rest/%d", den), 4/float64(den))
+			}
+		}
+
+		emit(groupToken(group), group.end-group.start)
+	}
+
+	if len(line) > 0 {
+		out.WriteString(indent + strings.Join(line, " ") + "\n")
+	}
+}
+
+// 单音写作 C5/4.~90，和弦写作 [C5 E5 G5]/4.~90，力度为默认值时省略
+func groupToken(group noteGroup) string {
+	suffix := durationToken(group.end - group.start)
+	if velocity, _ := groupVelocity(group.velocities); velocity != defaultVelocity {
+		suffix += fmt.Sprintf("~%d", velocity)
+	}
+
+	if len(group.keys) == 1 {
+		name, _ := pitchName(group.keys[0])
+		return name + suffix
+	}
+
+	notes := make([]string, len(group.keys))
+	for i, key := range group.keys {
+		notes[i], _ = pitchName(key)
+	}
+	return "[" + strings.Join(notes, " ") + "]" + suffix
+}
+
+// 一组音符的力度：取平均值，力度为0时按1处理；第二个返回值表示各音符力度是否相同
+func groupVelocity(velocities []uint8) (int, bool) {
+	if len(velocities) == 0 {
+		return defaultVelocity, true
+	}
+	sum, same := 0, true
+	for _, velocity := range velocities {
+		sum += int(velocity)
+		if velocity != velocities[0] {
+			same = false
+		}
+	}
+	velocity := int(math.Round(float64(sum) / float64(len(velocities))))
+	if velocity < 1 {
+		velocity = 1
+	}
+	return velocity, same
+}
+
+var pitchNames = []string{"C", "Cs", "D", "Ds", "E", "F", "Fs", "G", "Gs", "A", "As", "B"}
+
+// MIDI音高转DSL音符名，超出可表示范围的按八度移入，此时第二个返回值为 false
+func pitchName(key uint8) (string, bool) {
+	octave := int(key)/12 - midiOctaveOffset
+	ok := octave >= 0 && octave <= 9
+	for octave < 0 {
+		octave++
+	}
+	for octave > 9 {
+		octave--
+	}
+	return fmt.Sprintf("%s%d", pitchNames[key%12], octave), ok
+}
+
+// 拍数转DSL时值：/4、附点 /4.、/4..，其他长度写作 /分子:分母，如 /5:16
+func durationToken(beats float64) string {
+	// 量化后的时值是 1/64 全音符的整数倍
+	const unit = 64
+	num := int(math.Round(beats / 4 * unit))
+	if num <= 0 {
+		num = 1
+	}
+	den := unit
+	divisor := gcd(num, den)
+	num, den = num/divisor, den/divisor
+
+	switch {
+	case num == 1:
+		return fmt.Sprintf("/%d", den)
+	case num == 3 && den >= 2:
+		return fmt.Sprintf("/%d.", den/2)
+	case num == 7 && den >= 4:
+		return fmt.Sprintf("/%d..", den/4)
+	}
+	return fmt.Sprintf("/%d:%d", num, den)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// 把拍数拆分为 /1 /2 /4 /8 /16 时值之和
+func durationDenominators(beats float64) []int {
+	result := []int{}
+	remaining := beats
+
+	for remaining > 1e-9 {
+		found := false
+		for _, den := range validGrids {
+			value := 4 / float64(den)
+			if value <= remaining+1e-9 {
+				result = append(result, den)
+				remaining -= value
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+
+	if len(result) == 0 {
+		result = append(result, validGrids[len(validGrids)-1])
+	}
+	return result
+}
+
+func isValidGrid(grid int) bool {
+	for _, valid := range validGrids {
+		if grid == valid {
+			return true
+		}
+	}
+	return false
+}
+
+var numberWords = []string{
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+	"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen",
+	"seventeen", "eighteen", "nineteen",
+}
+
+var tensWords = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+
+// 数字转英文单词，DSL标识符不能包含数字
+func numberName(n int) string {
+	if n < len(numberWords) {
+		return numberWords[n]
+	}
+	if n < 100 {
+		if n%10 == 0 {
+			return tensWords[n/10]
+		}
+		return tensWords[n/10] + "_" + numberWords[n%10]
+	}
+	return numberName(n/100) + "_hundred_" + numberName(n%100)
+}
+
+// 由文件名生成合法的DSL标识符（仅字母和下划线）
+func identifierFromFilename(filename string) string {
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+
+	var builder strings.Builder
+	for _, ch := range base {
+		switch {
+		case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z':
+			builder.WriteRune(ch)
+		case '0' <= ch && ch <= '9':
+			if builder.Len() > 0 && !strings.HasSuffix(builder.String(), "_") {
+				builder.WriteString("_")
+			}
+			builder.WriteString(numberName(int(ch - '0')))
+		default:
+			builder.WriteString("_")
+		}
+	}
+
+	name := strings.Trim(builder.String(), "_")
+	if name == "" {
+		return "imported"
+	}
+	// 避免与音符名等关键字冲突
+	return "song_" + name
+}
+
+func (r *ImportResult) addWarning(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,258 @@
+package midifile
+
+import (
+	"catRock/pkg/dsl"
+	"strings"
+	"testing"
+
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+const testPPQ = 480
+
+// 时间单位为拍的 SMF 消息
+type timedMessage struct {
+	beat    float64
+	message smf.Message
+}
+
+// 由各轨道的消息构建 SMF，消息按给出的顺序写入
+func buildFile(t *testing.T, tracks ...[]timedMessage) *smf.SMF {
+	t.Helper()
+	file := smf.NewSMF1()
+	file.TimeFormat = smf.MetricTicks(testPPQ)
+
+	for _, messages := range tracks {
+		var track smf.Track
+		var lastTick uint32
+		for _, timed := range messages {
+			tick := uint32(timed.beat * testPPQ)
+			track.Add(tick-lastTick, timed.message)
+			lastTick = tick
+		}
+		track.Close(0)
+		if err := file.Add(track); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return file
+}
+
+// channel 上从 start 拍开始、持续 beats 拍的音符
+func note(channel, key, velocity uint8, start, beats float64) []timedMessage {
+	return []timedMessage{
+		{start, smf.Message(midi.NoteOn(channel, key, velocity))},
+		{start + beats, smf.Message(midi.NoteOff(channel, key))},
+	}
+}
+
+// 合并多组消息，按时间排序，同一时刻 NOTE_OFF 在前
+func merge(groups ...[]timedMessage) []timedMessage {
+	var result []timedMessage
+	for _, group := range groups {
+		result = append(result, group...)
+	}
+	for i := 1; i < len(result); i++ {
+		for j := i; j > 0 && less(result[j], result[j-1]); j-- {
+			result[j], result[j-1] = result[j-1], result[j]
+		}
+	}
+	return result
+}
+
+func less(a, b timedMessage) bool {
+	if a.beat != b.beat {
+		return a.beat < b.beat
+	}
+	var channel, key uint8
+	return a.message.GetNoteEnd(&channel, &key) && !b.message.GetNoteEnd(&channel, &key)
+}
+
+func importFile(t *testing.T, file *smf.SMF, options ImportOptions) *ImportResult {
+	t.Helper()
+	result, err := Import(file, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestImport(t *testing.T) {
+	conductor := []timedMessage{{0, smf.MetaTempo(90)}}
+	piano := merge(
+		[]timedMessage{{0, smf.Message(midi.ProgramChange(0, 5))}},
+		note(0, 108, 100, 0, 1),
+		note(0, 112, 80, 1, 2), note(0, 115, 80, 1, 2),
+		note(0, 110, 100, 4, 1.5),
+	)
+	drums := merge(note(9, 96, 100, 0.5, 0.5))
+
+	result := importFile(t, buildFile(t, conductor, piano, drums), ImportOptions{Name: "song", Source: "song.mid"})
+
+	want := `// 由 catrock import 从 song.mid 导入
+set {
+    BPM: 90
+}
+
+track song {
+    track channel_zero {
+        set {
+            channel: 0
+            instrument: 5
+        }
+
+        section voice_one {
+            C4/4 [E4 G4]/2~80 rest/4
+            D4/4.
+        }
+    }
+
+    track channel_nine {
+        set {
+            channel: 9
+            instrument: 128
+        }
+
+        section voice_one {
+            rest/8 C3/8
+        }
+    }
+}
+`
+	if result.Source != want {
+		t.Errorf("得到:\n%s\n期望:\n%s", result.Source, want)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("不应有警告: %v", result.Warnings)
+	}
+
+	// 生成的源码能被解析并生成乐谱
+	parser := dsl.NewParser(dsl.NewLexer(result.Source))
+	scoreNode := parser.ParseScore()
+	if errors := parser.Errors(); len(errors) > 0 {
+		t.Fatalf("解析失败: %v", errors)
+	}
+	if _, err := dsl.NewGenerator().GenerateScore(scoreNode); err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+}
+
+func TestImportVoicesAndWarnings(t *testing.T) {
+	conductor := []timedMessage{{0, smf.MetaTempo(120)}, {4, smf.MetaTempo(60)}}
+	// 长音与两个短音重叠，拆成两个声部；三连音不在网格上
+	track := merge(
+		note(0, 108, 100, 0, 2),
+		note(0, 112, 100, 0, 1), note(0, 113, 100, 1, 1),
+		note(0, 115, 100, 2, 1.0/3), note(0, 117, 100, 2+1.0/3, 1.0/3),
+	)
+
+	result := importFile(t, buildFile(t, conductor, track), ImportOptions{Grid: 8})
+
+	for _, want := range []string{
+		"section voice_one {\n            C4/2 G4/8 A4/8\n",
+		"section voice_two {\n            E4/4 F4/4\n",
+	} {
+		if !strings.Contains(result.Source, want) {
+			t.Errorf("源码中缺少 %q:\n%s", want, result.Source)
+		}
+	}
+
+	wantWarnings := []string{
+		"忽略第4.00拍的速度变化 (60.0 BPM)",
+		"通道0 有2个音符的起止时间不在 1/8 网格上 (如三连音)，已量化",
+	}
+	if strings.Join(result.Warnings, "\n") != strings.Join(wantWarnings, "\n") {
+		t.Errorf("警告:\n%s\n期望:\n%s", strings.Join(result.Warnings, "\n"), strings.Join(wantWarnings, "\n"))
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	empty := buildFile(t, []timedMessage{{0, smf.MetaTempo(120)}})
+	if _, err := Import(empty, ImportOptions{}); err == nil || !strings.Contains(err.Error(), "没有音符") {
+		t.Errorf("没有音符时的错误为 %v", err)
+	}
+
+	notes := buildFile(t, note(0, 108, 100, 0, 1))
+	if _, err := Import(notes, ImportOptions{Grid: 3}); err == nil || !strings.Contains(err.Error(), "无效的量化网格") {
+		t.Errorf("无效网格时的错误为 %v", err)
+	}
+}
+
+func TestDurationToken(t *testing.T) {
+	tests := []struct {
+		beats float64
+		want  string
+	}{
+		{4, "/1"},
+		{1, "/4"},
+		{0.25, "/16"},
+		{1.5, "/4."},
+		{3.5, "/2.."},
+		{1.25, "/5:16"},
+		{0, "/64"},
+	}
+
+	for _, tt := range tests {
+		if got := durationToken(tt.beats); got != tt.want {
+			t.Errorf("durationToken(%v) = %s，期望 %s", tt.beats, got, tt.want)
+		}
+	}
+}
+
+func TestPitchName(t *testing.T) {
+	tests := []struct {
+		key  uint8
+		want string
+		ok   bool
+	}{
+		{60, "C0", true},
+		{108, "C4", true},
+		{109, "Cs4", true},
+		{59, "B0", false},
+		{0, "C0", false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := pitchName(tt.key); got != tt.want || ok != tt.ok {
+			t.Errorf("pitchName(%d) = %s, %v，期望 %s, %v", tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIdentifierFromFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"song.mid", "song_song"},
+		{"/tmp/My Song 2.mid", "song_My_Song_two"},
+		{"42.mid", "song_four_two"},
+		{"---.mid", "imported"},
+	}
+
+	for _, tt := range tests {
+		if got := identifierFromFilename(tt.filename); got != tt.want {
+			t.Errorf("identifierFromFilename(%q) = %s，期望 %s", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestNumberName(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "zero"},
+		{15, "fifteen"},
+		{40, "forty"},
+		{42, "forty_two"},
+		{105, "one_hundred_five"},
+	}
+
+	for _, tt := range tests {
+		if got := numberName(tt.n); got != tt.want {
+			t.Errorf("numberName(%d) = %s，期望 %s", tt.n, got, tt.want)
+		}
+	}
+}
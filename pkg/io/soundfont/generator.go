@@ -0,0 +1,179 @@
+package soundfont
+
+import "math"
+
+// SF2 生成器编号（只列出渲染器用到的部分）
+type GeneratorType uint16
+
+const (
+	StartAddrsOffset           GeneratorType = 0
+	EndAddrsOffset             GeneratorType = 1
+	StartloopAddrsOffset       GeneratorType = 2
+	EndloopAddrsOffset         GeneratorType = 3
+	StartAddrsCoarseOffset     GeneratorType = 4
+	EndAddrsCoarseOffset       GeneratorType = 12
+	Pan                        GeneratorType = 17
+	DelayVolEnv                GeneratorType = 33
+	AttackVolEnv               GeneratorType = 34
+	HoldVolEnv                 GeneratorType = 35
+	DecayVolEnv                GeneratorType = 36
+	SustainVolEnv              GeneratorType = 37
+	ReleaseVolEnv              GeneratorType = 38
+	InstrumentID               GeneratorType = 41
+	KeyRange                   GeneratorType = 43
+	VelRange                   GeneratorType = 44
+	StartloopAddrsCoarseOffset GeneratorType = 45
+	InitialAttenuation         GeneratorType = 48
+	EndloopAddrsCoarseOffset   GeneratorType = 50
+	CoarseTune                 GeneratorType = 51
+	FineTune                   GeneratorType = 52
+	SampleID                   GeneratorType = 53
+	SampleModes                GeneratorType = 54
+	ScaleTuning                GeneratorType = 56
+	ExclusiveClass             GeneratorType = 57
+	OverridingRootKey          GeneratorType = 58
+)
+
+// 未设置时的默认值（SF2 2.04 第8.1.3节）
+var generatorDefaults = map[GeneratorType]int{
+	DelayVolEnv:       -12000,
+	AttackVolEnv:      -12000,
+	HoldVolEnv:        -12000,
+	DecayVolEnv:       -12000,
+	ReleaseVolEnv:     -12000,
+	ScaleTuning:       100,
+	OverridingRootKey: -1,
+}
+
+// 采样循环模式
+const (
+	loopNone         = 0
+	loopContinuous   = 1
+	loopUntilRelease = 3
+)
+
+// 一个音符实际使用的采样及合并后的生成器
+type Region struct {
+	Sample     *Sample
+	generators map[GeneratorType]int
+}
+
+// 查找按下某个键时应播放的所有区域
+func (p *Preset) Regions(key uint8, velocity uint8) []Region {
+	regions := []Region{}
+
+	for _, presetZone := range p.Zones {
+		if !presetZone.matches(key, velocity) || presetZone.Instrument == nil {
+			continue
+		}
+
+		instrument := presetZone.Instrument
+		for _, instrumentZone := range instrument.Zones {
+			if !instrumentZone.matches(key, velocity) || instrumentZone.Sample == nil {
+				continue
+			}
+			if instrumentZone.Sample.Type&romSampleFlag != 0 {
+				continue
+			}
+
+			// 乐器层：局部区域覆盖全局区域，未设置时取默认值
+			generators := map[GeneratorType]int{}
+			for gen, value := range generatorDefaults {
+				generators[gen] = value
+			}
+			if instrument.Global != nil {
+				for gen, value := range instrument.Global.Generators {
+					generators[gen] = int(value)
+				}
+			}
+			for gen, value := range instrumentZone.Generators {
+				generators[gen] = int(value)
+			}
+
+			// 预设层：相对值，叠加到乐器层
+			offsets := map[GeneratorType]int{}
+			if p.Global != nil {
+				for gen, value := range p.Global.Generators {
+					offsets[gen] = int(value)
+				}
+			}
+			for gen, value := range presetZone.Generators {
+				offsets[gen] = int(value)
+			}
+			for gen, value := range offsets {
+				if isPresetAdditive(gen) {
+					generators[gen] += value
+				}
+			}
+
+			regions = append(regions, Region{Sample: instrumentZone.Sample, generators: generators})
+		}
+	}
+
+	return regions
+}
+
+// 采样地址、索引类生成器不允许出现在预设层
+func isPresetAdditive(gen GeneratorType) bool {
+	switch gen {
+	case StartAddrsOffset, EndAddrsOffset, StartloopAddrsOffset, EndloopAddrsOffset,
+		StartAddrsCoarseOffset, EndAddrsCoarseOffset, StartloopAddrsCoarseOffset, EndloopAddrsCoarseOffset,
+		InstrumentID, SampleID, SampleModes, ExclusiveClass, OverridingRootKey:
+		return false
+	default:
+		return true
+	}
+}
+
+func (r Region) get(gen GeneratorType) int {
+	return r.generators[gen]
+}
+
+// 采样播放范围（在采样数据中的绝对位置）
+func (r Region) addresses() (start, end, loopStart, loopEnd int) {
+	start = int(r.Sample.Start) + r.get(StartAddrsOffset) + 32768*r.get(StartAddrsCoarseOffset)
+	end = int(r.Sample.End) + r.get(EndAddrsOffset) + 32768*r.get(EndAddrsCoarseOffset)
+	loopStart = int(r.Sample.LoopStart) + r.get(StartloopAddrsOffset) + 32768*r.get(StartloopAddrsCoarseOffset)
+	loopEnd = int(r.Sample.LoopEnd) + r.get(EndloopAddrsOffset) + 32768*r.get(EndloopAddrsCoarseOffset)
+	return
+}
+
+func (r Region) loopMode() int {
+	return r.get(SampleModes) & 3
+}
+
+// 相对采样原始音高的音分偏移
+func (r Region) pitchCents(key uint8) float64 {
+	root := r.get(OverridingRootKey)
+	if root < 0 {
+		root = int(r.Sample.OriginalPitch)
+		if root > 127 {
+			root = 60
+		}
+	}
+
+	return float64(int(key)-root)*float64(r.get(ScaleTuning)) +
+		float64(r.get(CoarseTune))*100 +
+		float64(r.get(FineTune)) +
+		float64(r.Sample.PitchCorrection)
+}
+
+// 初始衰减（厘贝）转换为线性增益
+func (r Region) attenuationGain() float64 {
+	return centibelsToGain(float64(r.get(InitialAttenuation)))
+}
+
+// 时间音分转秒
+func timecentsToSeconds(timecents int) float64 {
+	if timecents <= -12000 {
+		return 0
+	}
+	return math.Pow(2, float64(timecents)/1200)
+}
+
+func centibelsToGain(centibels float64) float64 {
+	if centibels <= 0 {
+		return 1
+	}
+	return math.Pow(10, -centibels/200)
+}
@@ -0,0 +1,289 @@
+package soundfont
+
+import (
+	"math"
+)
+
+const (
+	DefaultMaxVoices = 128
+
+	drumChannel = 9
+
+	// 衰减超过 96dB 视为静音
+	silenceCentibels = 960
+)
+
+type voice struct {
+	channel   uint8
+	note      uint8
+	exclusive int
+
+	// 采样播放
+	position   float64
+	step       float64
+	start, end int
+	loopStart  int
+	loopEnd    int
+	loopMode   int
+	amplitude  float64
+
+	// 音量包络（秒 / 厘贝）
+	delay, attack, hold, decay, release float64
+	sustainCentibels                    float64
+
+	age              int
+	released         bool
+	releaseAge       int
+	releaseCentibels float64
+	finished         bool
+}
+
+type channelState struct {
+	program    uint8
+	volume     uint8
+	expression uint8
+}
+
+// 基于SoundFont采样的离线渲染器，实现 io.MIDIEventSender
+type Renderer struct {
+	font       *SoundFont
+	sampleRate int
+	maxVoices  int
+	gain       float64
+	voices     []*voice
+	channels   map[uint8]*channelState
+}
+
+func NewRenderer(font *SoundFont, sampleRate int) *Renderer {
+	return &Renderer{
+		font:       font,
+		sampleRate: sampleRate,
+		maxVoices:  DefaultMaxVoices,
+		gain:       0.5,
+		voices:     []*voice{},
+		channels:   make(map[uint8]*channelState),
+	}
+}
+
+func (r *Renderer) channel(channel uint8) *channelState {
+	state, ok := r.channels[channel]
+	if !ok {
+		state = &channelState{volume: 100, expression: 127}
+		r.channels[channel] = state
+	}
+	return state
+}
+
+func (r *Renderer) SendNoteOn(channel uint8, note uint8, velocity uint8) error {
+	if velocity == 0 {
+		return r.SendNoteOff(channel, note, 0)
+	}
+
+	state := r.channel(channel)
+	bank := 0
+	if channel == drumChannel {
+		bank = PercussionBank
+	}
+
+	preset := r.font.FindPreset(bank, int(state.program))
+	if preset == nil {
+		return nil
+	}
+
+	channelGain := math.Pow(float64(state.volume)/127, 2) * math.Pow(float64(state.expression)/127, 2)
+	velocityGain := math.Pow(float64(velocity)/127, 2)
+
+	for _, region := range preset.Regions(note, velocity) {
+		v := r.newVoice(region, channel, note)
+		v.amplitude = region.attenuationGain() * channelGain * velocityGain
+
+		// 同一互斥组的声部（如开/闭镲）立即停止
+		if v.exclusive != 0 {
+			for _, other := range r.voices {
+				if other.channel == channel && other.exclusive == v.exclusive {
+					other.finished = true
+				}
+			}
+		}
+
+		if len(r.voices) >= r.maxVoices {
+			r.voices = r.voices[1:]
+		}
+		r.voices = append(r.voices, v)
+	}
+
+	return nil
+}
+
+func (r *Renderer) newVoice(region Region, channel uint8, note uint8) *voice {
+	start, end, loopStart, loopEnd := region.addresses()
+
+	cents := region.pitchCents(note)
+	step := math.Pow(2, cents/1200) * float64(region.Sample.SampleRate) / float64(r.sampleRate)
+
+	return &voice{
+		channel:   channel,
+		note:      note,
+		exclusive: region.get(ExclusiveClass),
+
+		position:  float64(start),
+		step:      step,
+		start:     start,
+		end:       end,
+		loopStart: loopStart,
+		loopEnd:   loopEnd,
+		loopMode:  region.loopMode(),
+
+		delay:            timecentsToSeconds(region.get(DelayVolEnv)),
+		attack:           timecentsToSeconds(region.get(AttackVolEnv)),
+		hold:             timecentsToSeconds(region.get(HoldVolEnv)),
+		decay:            timecentsToSeconds(region.get(DecayVolEnv)),
+		release:          timecentsToSeconds(region.get(ReleaseVolEnv)),
+		sustainCentibels: math.Max(0, float64(region.get(SustainVolEnv))),
+	}
+}
+
+func (r *Renderer) SendNoteOff(channel uint8, note uint8, velocity uint8) error {
+	for _, v := range r.voices {
+		if v.channel == channel && v.note == note && !v.released {
+			r.release(v)
+		}
+	}
+	return nil
+}
+
+func (r *Renderer) SendProgramChange(channel uint8, program uint8) error {
+	r.channel(channel).program = program
+	return nil
+}
+
+func (r *Renderer) SendControlChange(channel uint8, controller uint8, value uint8) error {
+	state := r.channel(channel)
+
+	switch controller {
+	case 7: // 主音量
+		state.volume = value
+	case 11: // 表情
+		state.expression = value
+	case 120: // All Sound Off
+		for _, v := range r.voices {
+			if v.channel == channel {
+				v.finished = true
+			}
+		}
+	case 121: // Reset All Controllers
+		state.volume = 100
+		state.expression = 127
+	case 123: // All Notes Off
+		for _, v := range r.voices {
+			if v.channel == channel && !v.released {
+				r.release(v)
+			}
+		}
+	}
+	return nil
+}
+
+// 当前发声的声部数
+func (r *Renderer) ActiveVoices() int {
+	return len(r.voices)
+}
+
+// 将接下来的采样混入 buffer
+func (r *Renderer) Render(buffer []float64) {
+	for _, v := range r.voices {
+		for i := range buffer {
+			if v.finished {
+				break
+			}
+
+			level := r.envelopeGain(v)
+			if v.finished {
+				break
+			}
+
+			index := int(v.position)
+			frac := v.position - float64(index)
+			sample := r.font.sampleAt(index)*(1-frac) + r.font.sampleAt(index+1)*frac
+			buffer[i] += sample * level * v.amplitude * r.gain
+
+			v.position += v.step
+			looping := v.loopMode == loopContinuous || (v.loopMode == loopUntilRelease && !v.released)
+			if looping && v.loopEnd > v.loopStart && v.position >= float64(v.loopEnd) {
+				v.position -= float64(v.loopEnd - v.loopStart)
+			} else if v.position >= float64(v.end) {
+				v.finished = true
+			}
+		}
+	}
+
+	kept := r.voices[:0]
+	for _, v := range r.voices {
+		if !v.finished {
+			kept = append(kept, v)
+		}
+	}
+	r.voices = kept
+}
+
+func (r *Renderer) release(v *voice) {
+	v.releaseCentibels = v.attenuationAt(float64(v.age) / float64(r.sampleRate))
+	v.released = true
+	v.releaseAge = 0
+}
+
+// 计算当前包络增益并推进时间
+func (r *Renderer) envelopeGain(v *voice) float64 {
+	sampleRate := float64(r.sampleRate)
+
+	var centibels float64
+	if v.released {
+		if v.release <= 0 {
+			v.finished = true
+			return 0
+		}
+		// 释音阶段按分贝线性衰减，release 为衰减100dB所需时间
+		centibels = v.releaseCentibels + 1000*float64(v.releaseAge)/sampleRate/v.release
+		v.releaseAge++
+	} else {
+		centibels = v.attenuationAt(float64(v.age) / sampleRate)
+	}
+	v.age++
+
+	if centibels >= silenceCentibels {
+		if v.released || (v.sustainCentibels >= silenceCentibels && float64(v.age)/sampleRate > v.delay+v.attack+v.hold) {
+			v.finished = true
+		}
+		return 0
+	}
+	return centibelsToGain(centibels)
+}
+
+// 按下后经过 t 秒的衰减（厘贝）
+func (v *voice) attenuationAt(t float64) float64 {
+	if t < v.delay {
+		return silenceCentibels
+	}
+	t -= v.delay
+
+	if t < v.attack {
+		// 起音阶段振幅线性上升
+		gain := t / v.attack
+		if gain <= 0 {
+			return silenceCentibels
+		}
+		return math.Min(silenceCentibels, -200*math.Log10(gain))
+	}
+	t -= v.attack
+
+	if t < v.hold {
+		return 0
+	}
+	t -= v.hold
+
+	// 衰减阶段按分贝线性下降到延音电平，decay 为下降100dB所需时间
+	if v.decay <= 0 {
+		return v.sustainCentibels
+	}
+	return math.Min(v.sustainCentibels, 1000*t/v.decay)
+}
@@ -0,0 +1,351 @@
+package soundfont
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 打击乐预设所在的音色库编号
+const PercussionBank = 128
+
+type SoundFont struct {
+	Name        string
+	Presets     []*Preset
+	Instruments []*Instrument
+	Samples     []*Sample
+
+	data    []int16 // smpl块中的16位采样
+	presets map[int]*Preset
+}
+
+type Preset struct {
+	Name    string
+	Program int
+	Bank    int
+	Global  *Zone // 全局区域（可能为空）
+	Zones   []*Zone
+}
+
+type Instrument struct {
+	Name   string
+	Global *Zone
+	Zones  []*Zone
+}
+
+// 预设或乐器中的一个区域
+type Zone struct {
+	KeyLow, KeyHigh uint8
+	VelLow, VelHigh uint8
+	Generators      map[GeneratorType]int16
+
+	Instrument *Instrument // 预设区域指向的乐器
+	Sample     *Sample     // 乐器区域指向的采样
+}
+
+type Sample struct {
+	Name            string
+	Start, End      uint32 // 在采样数据中的位置
+	LoopStart       uint32
+	LoopEnd         uint32
+	SampleRate      uint32
+	OriginalPitch   uint8
+	PitchCorrection int8
+	Link            uint16
+	Type            uint16
+}
+
+// 采样类型中的ROM标志，ROM采样不在文件中
+const romSampleFlag = 0x8000
+
+// 读取SF2文件
+func Load(filename string) (*SoundFont, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取SoundFont失败: %v", err)
+	}
+
+	sf, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析SoundFont失败: %v", err)
+	}
+	return sf, nil
+}
+
+// 解析SF2数据
+func Parse(data []byte) (*SoundFont, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "sfbk" {
+		return nil, fmt.Errorf("不是有效的SF2文件")
+	}
+
+	sf := &SoundFont{presets: make(map[int]*Preset)}
+	hydra := map[string][]byte{}
+
+	err := walkChunks(data[12:], func(id string, body []byte) error {
+		if id != "LIST" || len(body) < 4 {
+			return nil
+		}
+
+		listType := string(body[0:4])
+		return walkChunks(body[4:], func(id string, chunk []byte) error {
+			switch {
+			case listType == "INFO" && id == "INAM":
+				sf.Name = cString(chunk)
+			case listType == "sdta" && id == "smpl":
+				sf.data = make([]int16, len(chunk)/2)
+				binary.Read(bytes.NewReader(chunk[:len(sf.data)*2]), binary.LittleEndian, sf.data)
+			case listType == "pdta":
+				hydra[id] = chunk
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range []string{"phdr", "pbag", "pgen", "inst", "ibag", "igen", "shdr"} {
+		if _, ok := hydra[id]; !ok {
+			return nil, fmt.Errorf("缺少 %s 块", id)
+		}
+	}
+
+	if err := sf.parseSamples(hydra["shdr"]); err != nil {
+		return nil, err
+	}
+	if err := sf.parseInstruments(hydra["inst"], hydra["ibag"], hydra["igen"]); err != nil {
+		return nil, err
+	}
+	if err := sf.parsePresets(hydra["phdr"], hydra["pbag"], hydra["pgen"]); err != nil {
+		return nil, err
+	}
+
+	return sf, nil
+}
+
+// 查找预设，找不到时依次回退到同音色库的0号预设和0号库
+func (sf *SoundFont) FindPreset(bank int, program int) *Preset {
+	if preset, ok := sf.presets[presetKey(bank, program)]; ok {
+		return preset
+	}
+	if preset, ok := sf.presets[presetKey(bank, 0)]; ok && bank == PercussionBank {
+		return preset
+	}
+	if preset, ok := sf.presets[presetKey(0, program)]; ok && bank != PercussionBank {
+		return preset
+	}
+	if bank != PercussionBank && len(sf.Presets) > 0 {
+		return sf.Presets[0]
+	}
+	return nil
+}
+
+// 读取采样（-1~1），越界时返回0
+func (sf *SoundFont) sampleAt(index int) float64 {
+	if index < 0 || index >= len(sf.data) {
+		return 0
+	}
+	return float64(sf.data[index]) / 32768
+}
+
+func (sf *SoundFont) parseSamples(shdr []byte) error {
+	const size = 46
+	if len(shdr)%size != 0 || len(shdr) < size {
+		return fmt.Errorf("shdr 块大小错误")
+	}
+
+	// 最后一条为结束标记 EOS
+	count := len(shdr)/size - 1
+	for i := 0; i < count; i++ {
+		record := shdr[i*size : (i+1)*size]
+		sample := &Sample{
+			Name:            cString(record[0:20]),
+			Start:           binary.LittleEndian.Uint32(record[20:]),
+			End:             binary.LittleEndian.Uint32(record[24:]),
+			LoopStart:       binary.LittleEndian.Uint32(record[28:]),
+			LoopEnd:         binary.LittleEndian.Uint32(record[32:]),
+			SampleRate:      binary.LittleEndian.Uint32(record[36:]),
+			OriginalPitch:   record[40],
+			PitchCorrection: int8(record[41]),
+			Link:            binary.LittleEndian.Uint16(record[42:]),
+			Type:            binary.LittleEndian.Uint16(record[44:]),
+		}
+		sf.Samples = append(sf.Samples, sample)
+	}
+	return nil
+}
+
+func (sf *SoundFont) parseInstruments(inst, ibag, igen []byte) error {
+	const size = 22
+	if len(inst)%size != 0 || len(inst) < 2*size {
+		return fmt.Errorf("inst 块大小错误")
+	}
+
+	bags, err := parseBags(ibag, igen)
+	if err != nil {
+		return fmt.Errorf("乐器区域: %v", err)
+	}
+
+	count := len(inst)/size - 1
+	for i := 0; i < count; i++ {
+		record := inst[i*size : (i+1)*size]
+		next := inst[(i+1)*size : (i+2)*size]
+		first := int(binary.LittleEndian.Uint16(record[20:]))
+		last := int(binary.LittleEndian.Uint16(next[20:]))
+
+		instrument := &Instrument{Name: cString(record[0:20])}
+		for b := first; b < last && b < len(bags); b++ {
+			zone := newZone(bags[b])
+			if id, ok := zone.Generators[SampleID]; ok {
+				if int(uint16(id)) >= len(sf.Samples) {
+					return fmt.Errorf("乐器 %s 引用了不存在的采样 %d", instrument.Name, uint16(id))
+				}
+				zone.Sample = sf.Samples[uint16(id)]
+				instrument.Zones = append(instrument.Zones, zone)
+			} else if b == first {
+				instrument.Global = zone
+			}
+		}
+		sf.Instruments = append(sf.Instruments, instrument)
+	}
+	return nil
+}
+
+func (sf *SoundFont) parsePresets(phdr, pbag, pgen []byte) error {
+	const size = 38
+	if len(phdr)%size != 0 || len(phdr) < 2*size {
+		return fmt.Errorf("phdr 块大小错误")
+	}
+
+	bags, err := parseBags(pbag, pgen)
+	if err != nil {
+		return fmt.Errorf("预设区域: %v", err)
+	}
+
+	count := len(phdr)/size - 1
+	for i := 0; i < count; i++ {
+		record := phdr[i*size : (i+1)*size]
+		next := phdr[(i+1)*size : (i+2)*size]
+		first := int(binary.LittleEndian.Uint16(record[24:]))
+		last := int(binary.LittleEndian.Uint16(next[24:]))
+
+		preset := &Preset{
+			Name:    cString(record[0:20]),
+			Program: int(binary.LittleEndian.Uint16(record[20:])),
+			Bank:    int(binary.LittleEndian.Uint16(record[22:])),
+		}
+		for b := first; b < last && b < len(bags); b++ {
+			zone := newZone(bags[b])
+			if id, ok := zone.Generators[InstrumentID]; ok {
+				if int(uint16(id)) >= len(sf.Instruments) {
+					return fmt.Errorf("预设 %s 引用了不存在的乐器 %d", preset.Name, uint16(id))
+				}
+				zone.Instrument = sf.Instruments[uint16(id)]
+				preset.Zones = append(preset.Zones, zone)
+			} else if b == first {
+				preset.Global = zone
+			}
+		}
+
+		sf.Presets = append(sf.Presets, preset)
+		key := presetKey(preset.Bank, preset.Program)
+		if _, exists := sf.presets[key]; !exists {
+			sf.presets[key] = preset
+		}
+	}
+	return nil
+}
+
+// 一个区域的原始生成器列表
+type generatorEntry struct {
+	oper   GeneratorType
+	amount [2]byte
+}
+
+// 按bag索引拆分生成器
+func parseBags(bag, gen []byte) ([][]generatorEntry, error) {
+	if len(bag)%4 != 0 || len(bag) < 4 || len(gen)%4 != 0 {
+		return nil, fmt.Errorf("块大小错误")
+	}
+
+	entries := make([]generatorEntry, len(gen)/4)
+	for i := range entries {
+		entries[i] = generatorEntry{
+			oper:   GeneratorType(binary.LittleEndian.Uint16(gen[i*4:])),
+			amount: [2]byte{gen[i*4+2], gen[i*4+3]},
+		}
+	}
+
+	count := len(bag)/4 - 1
+	bags := make([][]generatorEntry, count)
+	for i := 0; i < count; i++ {
+		first := int(binary.LittleEndian.Uint16(bag[i*4:]))
+		last := int(binary.LittleEndian.Uint16(bag[(i+1)*4:]))
+		if first > last || last > len(entries) {
+			return nil, fmt.Errorf("生成器索引越界")
+		}
+		bags[i] = entries[first:last]
+	}
+	return bags, nil
+}
+
+func newZone(entries []generatorEntry) *Zone {
+	zone := &Zone{
+		KeyLow: 0, KeyHigh: 127,
+		VelLow: 0, VelHigh: 127,
+		Generators: make(map[GeneratorType]int16),
+	}
+
+	for _, entry := range entries {
+		switch entry.oper {
+		case KeyRange:
+			zone.KeyLow, zone.KeyHigh = entry.amount[0], entry.amount[1]
+		case VelRange:
+			zone.VelLow, zone.VelHigh = entry.amount[0], entry.amount[1]
+		default:
+			zone.Generators[entry.oper] = int16(binary.LittleEndian.Uint16(entry.amount[:]))
+		}
+	}
+	return zone
+}
+
+func (z *Zone) matches(key uint8, velocity uint8) bool {
+	return key >= z.KeyLow && key <= z.KeyHigh && velocity >= z.VelLow && velocity <= z.VelHigh
+}
+
+func presetKey(bank int, program int) int {
+	return bank<<8 | program
+}
+
+// 读取以NUL结尾的定长字符串
+func cString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// RIFF子块遍历
+func walkChunks(data []byte, visit func(id string, body []byte) error) error {
+	for len(data) >= 8 {
+		id := string(data[0:4])
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		if size > len(data)-8 {
+			return fmt.Errorf("%s 块超出文件范围", id)
+		}
+
+		if err := visit(id, data[8:8+size]); err != nil {
+			return err
+		}
+
+		// 块按偶数字节对齐
+		next := 8 + size + size%2
+		if next > len(data) {
+			break
+		}
+		data = data[next:]
+	}
+	return nil
+}
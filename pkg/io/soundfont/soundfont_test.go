@@ -0,0 +1,356 @@
+package soundfont
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// 测试用的生成器
+type testGen struct {
+	oper   GeneratorType
+	amount uint16
+}
+
+// 范围类生成器的取值：低字节为下限，高字节为上限
+func rangeAmount(low, high uint8) uint16 {
+	return uint16(low) | uint16(high)<<8
+}
+
+type testSample struct {
+	name               string
+	start, end         uint32
+	loopStart, loopEnd uint32
+	sampleRate         uint32
+	originalPitch      uint8
+	pitchCorrection    int8
+	sampleType         uint16
+}
+
+type testPreset struct {
+	name          string
+	program, bank uint16
+	zones         [][]testGen
+}
+
+// 按 SF2 结构拼出一个最小的 SoundFont 文件
+type fontBuilder struct {
+	name        string
+	data        []int16
+	samples     []testSample
+	instruments []testPreset // 只使用 name 和 zones
+	presets     []testPreset
+}
+
+func chunk(id string, body []byte) []byte {
+	result := append([]byte(id), make([]byte, 4)...)
+	binary.LittleEndian.PutUint32(result[4:], uint32(len(body)))
+	result = append(result, body...)
+	if len(body)%2 == 1 {
+		result = append(result, 0)
+	}
+	return result
+}
+
+func list(listType string, chunks ...[]byte) []byte {
+	body := []byte(listType)
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	return chunk("LIST", body)
+}
+
+func name20(name string) []byte {
+	field := make([]byte, 20)
+	copy(field, name)
+	return field
+}
+
+func u16(value uint16) []byte {
+	return binary.LittleEndian.AppendUint16(nil, value)
+}
+
+func u32(value uint32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, value)
+}
+
+// 返回 hdr、bag、gen 三个块的内容；header 写出一条头记录，bag 为其第一个区域的索引
+func headers(entries []testPreset, header func(entry testPreset, bag uint16) []byte, terminal []byte) ([]byte, []byte, []byte) {
+	var hdr, bag, gen []byte
+	var bagCount, genCount uint16
+	for _, entry := range entries {
+		hdr = append(hdr, header(entry, bagCount)...)
+		for _, zone := range entry.zones {
+			bag = append(bag, append(u16(genCount), u16(0)...)...)
+			bagCount++
+			for _, g := range zone {
+				gen = append(gen, append(u16(uint16(g.oper)), u16(g.amount)...)...)
+				genCount++
+			}
+		}
+	}
+	hdr = append(hdr, header(testPreset{name: string(terminal)}, bagCount)...)
+	bag = append(bag, append(u16(genCount), u16(0)...)...)
+	gen = append(gen, make([]byte, 4)...)
+	return hdr, bag, gen
+}
+
+func (b fontBuilder) build() []byte {
+	var smpl []byte
+	for _, value := range b.data {
+		smpl = binary.LittleEndian.AppendUint16(smpl, uint16(value))
+	}
+
+	var shdr []byte
+	for _, s := range append(b.samples, testSample{name: "EOS"}) {
+		shdr = append(shdr, name20(s.name)...)
+		for _, value := range []uint32{s.start, s.end, s.loopStart, s.loopEnd, s.sampleRate} {
+			shdr = append(shdr, u32(value)...)
+		}
+		shdr = append(shdr, s.originalPitch, byte(s.pitchCorrection))
+		shdr = append(shdr, u16(0)...)
+		shdr = append(shdr, u16(s.sampleType)...)
+	}
+
+	inst, ibag, igen := headers(b.instruments, func(entry testPreset, bag uint16) []byte {
+		return append(name20(entry.name), u16(bag)...)
+	}, []byte("EOI"))
+	phdr, pbag, pgen := headers(b.presets, func(entry testPreset, bag uint16) []byte {
+		record := append(name20(entry.name), u16(entry.program)...)
+		record = append(record, u16(entry.bank)...)
+		record = append(record, u16(bag)...)
+		return append(record, make([]byte, 12)...)
+	}, []byte("EOP"))
+
+	body := []byte("sfbk")
+	body = append(body, list("INFO", chunk("INAM", append([]byte(b.name), 0)))...)
+	body = append(body, list("sdta", chunk("smpl", smpl))...)
+	body = append(body, list("pdta",
+		chunk("phdr", phdr), chunk("pbag", pbag), chunk("pmod", nil), chunk("pgen", pgen),
+		chunk("inst", inst), chunk("ibag", ibag), chunk("imod", nil), chunk("igen", igen),
+		chunk("shdr", shdr))...)
+	return chunk("RIFF", body)
+}
+
+// 一个乐器分成高低两个键区，高音区按力度再分两层
+func testFont() fontBuilder {
+	data := make([]int16, 200)
+	for i := range data {
+		data[i] = int16(10000 * math.Sin(float64(i)/4))
+	}
+
+	return fontBuilder{
+		name: "Test Font",
+		data: data,
+		samples: []testSample{
+			{name: "low", start: 0, end: 100, loopStart: 10, loopEnd: 90, sampleRate: 22050, originalPitch: 60},
+			{name: "high", start: 100, end: 200, loopStart: 110, loopEnd: 190, sampleRate: 44100, originalPitch: 72, pitchCorrection: -5},
+		},
+		instruments: []testPreset{
+			{name: "piano", zones: [][]testGen{
+				{{InitialAttenuation, 100}},
+				{{KeyRange, rangeAmount(0, 63)}, {SampleModes, loopContinuous}, {SampleID, 0}},
+				{{KeyRange, rangeAmount(64, 127)}, {VelRange, rangeAmount(0, 100)}, {SampleID, 1}},
+				{{KeyRange, rangeAmount(64, 127)}, {VelRange, rangeAmount(101, 127)}, {OverridingRootKey, 70}, {SampleID, 1}},
+			}},
+		},
+		presets: []testPreset{
+			{name: "Piano", program: 0, bank: 0, zones: [][]testGen{
+				{{CoarseTune, 1}},
+				{{InstrumentID, 0}},
+			}},
+			{name: "Kit", program: 0, bank: PercussionBank, zones: [][]testGen{
+				{{KeyRange, rangeAmount(35, 81)}, {InstrumentID, 0}},
+			}},
+		},
+	}
+}
+
+func parseTestFont(t *testing.T, builder fontBuilder) *SoundFont {
+	t.Helper()
+	font, err := Parse(builder.build())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+func TestParse(t *testing.T) {
+	font := parseTestFont(t, testFont())
+
+	if font.Name != "Test Font" {
+		t.Errorf("名称为 %q", font.Name)
+	}
+	if len(font.Samples) != 2 || len(font.Instruments) != 1 || len(font.Presets) != 2 {
+		t.Fatalf("得到 %d 个采样、%d 个乐器、%d 个预设", len(font.Samples), len(font.Instruments), len(font.Presets))
+	}
+
+	high := font.Samples[1]
+	if high.Name != "high" || high.Start != 100 || high.End != 200 || high.LoopStart != 110 || high.LoopEnd != 190 ||
+		high.SampleRate != 44100 || high.OriginalPitch != 72 || high.PitchCorrection != -5 {
+		t.Errorf("采样解析错误: %+v", *high)
+	}
+
+	piano := font.Instruments[0]
+	if piano.Global == nil || piano.Global.Generators[InitialAttenuation] != 100 {
+		t.Errorf("乐器全局区域解析错误: %+v", piano.Global)
+	}
+	if len(piano.Zones) != 3 {
+		t.Fatalf("乐器有 %d 个区域，期望 3 个", len(piano.Zones))
+	}
+	if zone := piano.Zones[2]; zone.KeyLow != 64 || zone.KeyHigh != 127 || zone.VelLow != 101 || zone.VelHigh != 127 || zone.Sample != high {
+		t.Errorf("乐器区域解析错误: %+v", *zone)
+	}
+
+	kit := font.Presets[1]
+	if kit.Name != "Kit" || kit.Bank != PercussionBank || kit.Global != nil || len(kit.Zones) != 1 || kit.Zones[0].Instrument != piano {
+		t.Errorf("预设解析错误: %+v", *kit)
+	}
+	if font.sampleAt(-1) != 0 || font.sampleAt(200) != 0 {
+		t.Error("越界采样应为 0")
+	}
+}
+
+func TestFindPreset(t *testing.T) {
+	font := parseTestFont(t, testFont())
+
+	tests := []struct {
+		name    string
+		bank    int
+		program int
+		want    string
+	}{
+		{"精确匹配", 0, 0, "Piano"},
+		{"打击乐", PercussionBank, 0, "Kit"},
+		{"打击乐回退到0号预设", PercussionBank, 25, "Kit"},
+		{"其他音色库回退到0号库", 3, 0, "Piano"},
+		{"找不到时使用第一个预设", 0, 40, "Piano"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preset := font.FindPreset(tt.bank, tt.program)
+			if preset == nil || preset.Name != tt.want {
+				t.Errorf("得到 %v，期望 %s", preset, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPresetWithoutPercussion(t *testing.T) {
+	builder := testFont()
+	builder.presets = builder.presets[:1]
+	if preset := parseTestFont(t, builder).FindPreset(PercussionBank, 0); preset != nil {
+		t.Errorf("没有打击乐预设时得到 %s", preset.Name)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	font := parseTestFont(t, testFont())
+	piano, kit := font.Presets[0], font.Presets[1]
+
+	tests := []struct {
+		name     string
+		preset   *Preset
+		key      uint8
+		velocity uint8
+		sample   string
+		cents    float64 // 相对采样原始音高的音分
+		loopMode int
+	}{
+		// 预设全局区域的 coarseTune 叠加到乐器层
+		{"低音区", piano, 62, 100, "low", 200 + 100, loopContinuous},
+		{"高音区弱奏", piano, 72, 100, "high", 0 + 100 - 5, loopNone},
+		{"高音区强奏改用 overridingRootKey", piano, 72, 127, "high", 200 + 100 - 5, loopNone},
+		{"打击乐只有预设层", kit, 40, 100, "low", -2000, loopContinuous},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regions := tt.preset.Regions(tt.key, tt.velocity)
+			if len(regions) != 1 {
+				t.Fatalf("得到 %d 个区域，期望 1 个", len(regions))
+			}
+			region := regions[0]
+			if region.Sample.Name != tt.sample {
+				t.Errorf("采样为 %s，期望 %s", region.Sample.Name, tt.sample)
+			}
+			if got := region.pitchCents(tt.key); got != tt.cents {
+				t.Errorf("音分偏移为 %v，期望 %v", got, tt.cents)
+			}
+			if got := region.loopMode(); got != tt.loopMode {
+				t.Errorf("循环模式为 %d，期望 %d", got, tt.loopMode)
+			}
+			// 乐器全局区域的 100 厘贝衰减
+			if got, want := region.attenuationGain(), math.Pow(10, -0.5); math.Abs(got-want) > 1e-12 {
+				t.Errorf("增益为 %v，期望 %v", got, want)
+			}
+		})
+	}
+
+	if regions := kit.Regions(90, 100); len(regions) != 0 {
+		t.Errorf("键区以外得到 %d 个区域", len(regions))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	valid := testFont().build()
+
+	missingSampleRef := testFont()
+	missingSampleRef.instruments[0].zones[1][2].amount = 7
+
+	missingInstrumentRef := testFont()
+	missingInstrumentRef.presets[0].zones[1][0].amount = 3
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"不是RIFF", []byte("RIFX0000sfbk"), "不是有效的SF2文件"},
+		{"不是sfbk", append([]byte("RIFF0000WAVE"), valid[12:]...), "不是有效的SF2文件"},
+		{"块超出范围", append(valid[:12:12], chunk("LIST", make([]byte, 8))[:12]...), "LIST 块超出文件范围"},
+		{"缺少块", chunk("RIFF", append([]byte("sfbk"), list("INFO", chunk("INAM", []byte("x\x00")))...)), "缺少 phdr 块"},
+		{"采样不存在", missingSampleRef.build(), "乐器 piano 引用了不存在的采样 7"},
+		{"乐器不存在", missingInstrumentRef.build(), "预设 Piano 引用了不存在的乐器 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误为 %v，期望包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRendererLoopsUntilReleased(t *testing.T) {
+	renderer := NewRenderer(parseTestFont(t, testFont()), 22050)
+	renderer.SendNoteOn(0, 60, 100)
+	if got := renderer.ActiveVoices(); got != 1 {
+		t.Fatalf("按下后有 %d 个声部，期望 1 个", got)
+	}
+
+	// 循环采样在按住期间持续发声，超过采样长度也不停止
+	buffer := make([]float64, 1000)
+	renderer.Render(buffer)
+	silent := true
+	for _, sample := range buffer[900:] {
+		if sample != 0 {
+			silent = false
+			break
+		}
+	}
+	if silent || renderer.ActiveVoices() != 1 {
+		t.Fatalf("循环采样提前停止，声部数 %d", renderer.ActiveVoices())
+	}
+
+	// 默认释音时间为 0，松开后立即停止
+	renderer.SendNoteOff(0, 60, 0)
+	renderer.Render(make([]float64, 1))
+	if got := renderer.ActiveVoices(); got != 0 {
+		t.Errorf("松开后有 %d 个声部，期望 0 个", got)
+	}
+}
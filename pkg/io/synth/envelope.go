@@ -0,0 +1,51 @@
+package synth
+
+import "fmt"
+
+// ADSR包络（时间单位：秒，Sustain为0-1电平）
+type ADSR struct {
+	Attack  float64
+	Decay   float64
+	Sustain float64
+	Release float64
+}
+
+func DefaultADSR() ADSR {
+	return ADSR{
+		Attack:  0.01,
+		Decay:   0.15,
+		Sustain: 0.7,
+		Release: 0.2,
+	}
+}
+
+func (e ADSR) Validate() error {
+	if e.Attack < 0 || e.Decay < 0 || e.Release < 0 {
+		return fmt.Errorf("包络时间不能为负数")
+	}
+	if e.Sustain < 0 || e.Sustain > 1 {
+		return fmt.Errorf("延音电平必须在0-1之间: %.2f", e.Sustain)
+	}
+	return nil
+}
+
+// 按下后经过 t 秒的电平
+func (e ADSR) levelAt(t float64) float64 {
+	if t < e.Attack {
+		return t / e.Attack
+	}
+	t -= e.Attack
+
+	if t < e.Decay {
+		return 1 - (1-e.Sustain)*t/e.Decay
+	}
+	return e.Sustain
+}
+
+// 松开后经过 t 秒的电平，from 为松开时的电平
+func (e ADSR) releaseLevelAt(from float64, t float64) float64 {
+	if t >= e.Release {
+		return 0
+	}
+	return from * (1 - t/e.Release)
+}
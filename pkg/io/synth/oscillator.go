@@ -0,0 +1,95 @@
+package synth
+
+import (
+	"catRock/pkg/core"
+	"fmt"
+	"math"
+	"strings"
+)
+
+type Waveform int
+
+const (
+	Auto     Waveform = iota // 按乐器音色族选择
+	Sine                     // 正弦波
+	Saw                      // 锯齿波
+	Square                   // 方波
+	Triangle                 // 三角波
+	Noise                    // 噪声（鼓组）
+)
+
+func (w Waveform) String() string {
+	switch w {
+	case Auto:
+		return "auto"
+	case Sine:
+		return "sine"
+	case Saw:
+		return "saw"
+	case Square:
+		return "square"
+	case Triangle:
+		return "triangle"
+	case Noise:
+		return "noise"
+	default:
+		return fmt.Sprintf("waveform_%d", int(w))
+	}
+}
+
+// 解析波形名称
+func ParseWaveform(name string) (Waveform, error) {
+	switch strings.ToLower(name) {
+	case "", "auto":
+		return Auto, nil
+	case "sine", "sin":
+		return Sine, nil
+	case "saw", "sawtooth":
+		return Saw, nil
+	case "square", "pulse":
+		return Square, nil
+	case "triangle", "tri":
+		return Triangle, nil
+	default:
+		return Auto, fmt.Errorf("不支持的波形: %s (可选: auto, sine, saw, square, triangle)", name)
+	}
+}
+
+// 按General MIDI音色族选择最接近的基础波形
+func WaveformForProgram(program uint8) Waveform {
+	switch core.GetInstrumentFamily(core.InstrumentID(program)) {
+	case core.Organ, core.Reed, core.SynthLead:
+		return Square
+	case core.Strings, core.Ensemble, core.Brass, core.SynthPad:
+		return Saw
+	case core.Pipe, core.ChromaticPercussion:
+		return Sine
+	default:
+		return Triangle
+	}
+}
+
+// 计算单周期波形在相位 phase (0-1) 处的值
+func oscillate(waveform Waveform, phase float64) float64 {
+	switch waveform {
+	case Saw:
+		return 2*phase - 1
+	case Square:
+		if phase < 0.5 {
+			return 1
+		}
+		return -1
+	case Triangle:
+		if phase < 0.5 {
+			return 4*phase - 1
+		}
+		return 3 - 4*phase
+	default:
+		return math.Sin(2 * math.Pi * phase)
+	}
+}
+
+// MIDI音符号转频率（A4 = 69 = 440Hz）
+func noteFrequency(note uint8) float64 {
+	return 440 * math.Pow(2, (float64(note)-69)/12)
+}
@@ -0,0 +1,326 @@
+package synth
+
+import (
+	"catRock/pkg/io"
+	"fmt"
+	"math"
+)
+
+const (
+	DefaultSampleRate = 44100
+	DefaultBitDepth   = 16
+	DefaultMaxVoices  = 64
+
+	drumChannel = 9
+)
+
+// 鼓组使用的打击包络
+var drumEnvelope = ADSR{Attack: 0.001, Decay: 0.12, Sustain: 0, Release: 0.05}
+
+type Options struct {
+	SampleRate int      // 采样率
+	Waveform   Waveform // 振荡器波形，Auto 按乐器选择
+	Envelope   ADSR     // 音符包络
+	MaxVoices  int      // 最大复音数，超出时释放最早的声部
+	Gain       float64  // 混音增益
+}
+
+func DefaultOptions() Options {
+	return Options{
+		SampleRate: DefaultSampleRate,
+		Waveform:   Auto,
+		Envelope:   DefaultADSR(),
+		MaxVoices:  DefaultMaxVoices,
+		Gain:       0.25,
+	}
+}
+
+type voice struct {
+	channel  uint8
+	note     uint8
+	waveform Waveform
+	envelope ADSR
+
+	phase     float64 // 当前相位 (0-1)
+	step      float64 // 每个采样的相位增量
+	amplitude float64 // 力度与通道音量
+
+	age          int     // 按下后经过的采样数
+	released     bool    // 是否已松开
+	releaseAge   int     // 松开后经过的采样数
+	releaseLevel float64 // 松开时的包络电平
+}
+
+type channelState struct {
+	program    uint8
+	volume     uint8
+	expression uint8
+}
+
+// 软件合成器，实现 io.MIDIEventSender
+type Synth struct {
+	options  Options
+	voices   []*voice
+	channels map[uint8]*channelState
+	noise    uint32
+}
+
+func New(options Options) (*Synth, error) {
+	if options.SampleRate <= 0 {
+		return nil, fmt.Errorf("无效的采样率: %d", options.SampleRate)
+	}
+	if err := options.Envelope.Validate(); err != nil {
+		return nil, err
+	}
+	if options.MaxVoices <= 0 {
+		options.MaxVoices = DefaultMaxVoices
+	}
+	if options.Gain <= 0 {
+		options.Gain = DefaultOptions().Gain
+	}
+
+	return &Synth{
+		options:  options,
+		voices:   []*voice{},
+		channels: make(map[uint8]*channelState),
+		noise:    0x12345678,
+	}, nil
+}
+
+func (s *Synth) channel(channel uint8) *channelState {
+	state, ok := s.channels[channel]
+	if !ok {
+		state = &channelState{volume: 100, expression: 127}
+		s.channels[channel] = state
+	}
+	return state
+}
+
+func (s *Synth) SendNoteOn(channel uint8, note uint8, velocity uint8) error {
+	if velocity == 0 {
+		return s.SendNoteOff(channel, note, 0)
+	}
+
+	state := s.channel(channel)
+	v := &voice{
+		channel:  channel,
+		note:     note,
+		waveform: s.options.Waveform,
+		envelope: s.options.Envelope,
+		amplitude: float64(velocity) / 127 *
+			float64(state.volume) / 127 *
+			float64(state.expression) / 127,
+	}
+
+	if channel == drumChannel {
+		v.waveform = Noise
+		v.envelope = drumEnvelope
+	} else if v.waveform == Auto {
+		v.waveform = WaveformForProgram(state.program)
+	}
+
+	// 超出可表示范围的音高按八度下移，避免混叠
+	frequency := noteFrequency(note)
+	for frequency > float64(s.options.SampleRate)/4 {
+		frequency /= 2
+	}
+	v.step = frequency / float64(s.options.SampleRate)
+
+	if len(s.voices) >= s.options.MaxVoices {
+		s.voices = s.voices[1:]
+	}
+	s.voices = append(s.voices, v)
+	return nil
+}
+
+func (s *Synth) SendNoteOff(channel uint8, note uint8, velocity uint8) error {
+	for _, v := range s.voices {
+		if v.channel == channel && v.note == note && !v.released {
+			s.release(v)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *Synth) SendProgramChange(channel uint8, program uint8) error {
+	s.channel(channel).program = program
+	return nil
+}
+
+func (s *Synth) SendControlChange(channel uint8, controller uint8, value uint8) error {
+	state := s.channel(channel)
+
+	switch controller {
+	case 7: // 主音量
+		state.volume = value
+	case 11: // 表情
+		state.expression = value
+	case 120: // All Sound Off
+		s.removeVoices(func(v *voice) bool { return v.channel == channel })
+	case 121: // Reset All Controllers
+		state.volume = 100
+		state.expression = 127
+	case 123: // All Notes Off
+		for _, v := range s.voices {
+			if v.channel == channel && !v.released {
+				s.release(v)
+			}
+		}
+	}
+	return nil
+}
+
+// 当前发声的声部数
+func (s *Synth) ActiveVoices() int {
+	return len(s.voices)
+}
+
+// 将接下来的采样混入 buffer
+func (s *Synth) Render(buffer []float64) {
+	sampleRate := float64(s.options.SampleRate)
+
+	for _, v := range s.voices {
+		for i := range buffer {
+			var level float64
+			if v.released {
+				level = v.envelope.releaseLevelAt(v.releaseLevel, float64(v.releaseAge)/sampleRate)
+				v.releaseAge++
+			} else {
+				level = v.envelope.levelAt(float64(v.age) / sampleRate)
+			}
+			v.age++
+
+			var value float64
+			if v.waveform == Noise {
+				value = s.nextNoise()
+			} else {
+				value = oscillate(v.waveform, v.phase)
+				v.phase += v.step
+				if v.phase >= 1 {
+					v.phase -= math.Floor(v.phase)
+				}
+			}
+
+			buffer[i] += value * level * v.amplitude * s.options.Gain
+		}
+	}
+
+	s.removeVoices(func(v *voice) bool { return s.finished(v) })
+}
+
+func (s *Synth) release(v *voice) {
+	v.releaseLevel = v.envelope.levelAt(float64(v.age) / float64(s.options.SampleRate))
+	v.released = true
+	v.releaseAge = 0
+}
+
+// 声部是否已完全静音
+func (s *Synth) finished(v *voice) bool {
+	sampleRate := float64(s.options.SampleRate)
+	if v.released {
+		return float64(v.releaseAge)/sampleRate >= v.envelope.Release
+	}
+	return v.envelope.Sustain == 0 && float64(v.age)/sampleRate >= v.envelope.Attack+v.envelope.Decay
+}
+
+func (s *Synth) removeVoices(match func(v *voice) bool) {
+	kept := s.voices[:0]
+	for _, v := range s.voices {
+		if !match(v) {
+			kept = append(kept, v)
+		}
+	}
+	s.voices = kept
+}
+
+// xorshift 伪随机噪声，保证渲染结果可复现
+func (s *Synth) nextNoise() float64 {
+	s.noise ^= s.noise << 13
+	s.noise ^= s.noise >> 17
+	s.noise ^= s.noise << 5
+	return float64(s.noise)/float64(math.MaxUint32)*2 - 1
+}
+
+// 离线渲染事件序列，返回单声道采样
+func RenderEvents(events []io.Event, bpm float64, options Options) ([]float64, error) {
+	synth, err := New(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return RenderSequence(synth, events, bpm, synth.options.SampleRate)
+}
+
+// 事件发送器与采样生成器，内置合成器与SoundFont渲染器共用同一渲染流程
+type Renderer interface {
+	io.MIDIEventSender
+	Render(buffer []float64)
+	ActiveVoices() int
+}
+
+// 按事件时间逐段渲染，结束后渲染尾音直到所有声部静音
+func RenderSequence(renderer Renderer, events []io.Event, bpm float64, sampleRate int) ([]float64, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("没有事件可渲染")
+	}
+	if bpm <= 0 {
+		return nil, fmt.Errorf("无效的BPM: %.2f", bpm)
+	}
+
+	sorted := make([]io.Event, len(events))
+	copy(sorted, events)
+	io.SortEvents(sorted)
+
+	secondsPerBeat := 60 / bpm
+	lastFrame := int(math.Round(sorted[len(sorted)-1].Time * secondsPerBeat * float64(sampleRate)))
+	samples := make([]float64, 0, lastFrame+sampleRate)
+
+	position := 0
+	for _, event := range sorted {
+		frame := int(math.Round(event.Time * secondsPerBeat * float64(sampleRate)))
+		if frame > position {
+			samples = renderFrames(renderer, samples, frame-position)
+			position = frame
+		}
+
+		if err := io.SendEvent(renderer, event); err != nil {
+			return nil, err
+		}
+	}
+
+	// 尾音，最多渲染10秒
+	const maxTailSeconds = 10
+	chunk := sampleRate / 10
+	for i := 0; renderer.ActiveVoices() > 0 && i < maxTailSeconds*10; i++ {
+		samples = renderFrames(renderer, samples, chunk)
+	}
+
+	normalize(samples)
+	return samples, nil
+}
+
+func renderFrames(renderer Renderer, samples []float64, frames int) []float64 {
+	start := len(samples)
+	samples = append(samples, make([]float64, frames)...)
+	renderer.Render(samples[start:])
+	return samples
+}
+
+// 峰值超过满刻度时整体缩小，避免削波
+func normalize(samples []float64) {
+	peak := 0.0
+	for _, s := range samples {
+		peak = math.Max(peak, math.Abs(s))
+	}
+
+	const ceiling = 0.98
+	if peak <= ceiling {
+		return
+	}
+
+	scale := ceiling / peak
+	for i := range samples {
+		samples[i] *= scale
+	}
+}
@@ -0,0 +1,67 @@
+package synth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+)
+
+// 将单声道浮点采样 (-1~1) 编码为PCM WAV
+func EncodeWAV(samples []float64, sampleRate int, bitDepth int) ([]byte, error) {
+	if bitDepth != 16 && bitDepth != 24 {
+		return nil, fmt.Errorf("不支持的位深: %d (可选: 16, 24)", bitDepth)
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("无效的采样率: %d", sampleRate)
+	}
+
+	const channels = 1
+	bytesPerSample := bitDepth / 8
+	dataSize := len(samples) * bytesPerSample * channels
+
+	var buf bytes.Buffer
+	buf.Grow(44 + dataSize)
+
+	// RIFF头
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+
+	// fmt块
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*bytesPerSample*channels))
+	binary.Write(&buf, binary.LittleEndian, uint16(bytesPerSample*channels))
+	binary.Write(&buf, binary.LittleEndian, uint16(bitDepth))
+
+	// data块
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+
+	maxValue := float64(int(1)<<(bitDepth-1) - 1)
+	sample := make([]byte, bytesPerSample)
+	for _, s := range samples {
+		s = math.Max(-1, math.Min(1, s))
+		value := int32(math.Round(s * maxValue))
+		for i := 0; i < bytesPerSample; i++ {
+			sample[i] = byte(value >> (8 * i))
+		}
+		buf.Write(sample)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// 写入WAV文件
+func WriteWAVFile(filename string, samples []float64, sampleRate int, bitDepth int) error {
+	data, err := EncodeWAV(samples, sampleRate, bitDepth)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
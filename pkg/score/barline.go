@@ -0,0 +1,155 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"fmt"
+	"math"
+)
+
+// 小节线 - 不占时间，用于检查每小节的长度是否符合拍号
+type Barline struct {
+	ID     string
+	Source string // 书写位置，如 song.crock:12:5，用于报告错误
+}
+
+var _ Playable = (*Barline)(nil)
+
+func (b *Barline) GetID() string {
+	if b.ID != "" {
+		return b.ID
+	}
+	return "barline"
+}
+
+func (b *Barline) GetType() PlayableType {
+	return BARLINE_TYPE
+}
+
+func (b *Barline) Duration(context PlayContext) float64 {
+	return 0
+}
+
+func (b *Barline) GenerateEvents(startTime float64, context PlayContext) []Event {
+	return []Event{}
+}
+
+// 构造函数
+func NewBarline(source string) *Barline {
+	return &Barline{Source: source}
+}
+
+func (b *Barline) DetailedString(indent string) string {
+	if b.Source == "" {
+		return "Barline\n"
+	}
+	return fmt.Sprintf("Barline (%s)\n", b.Source)
+}
+
+// 小节长度与拍号不符
+type BarError struct {
+	Bar    int     // 小节号，从1开始
+	Source string  // 结束该小节的小节线的位置
+	Diff   float64 // 实际长度减去拍号要求的长度，以四分音符为一拍
+}
+
+func (e BarError) Error() string {
+	amount := formatWholeNotes(math.Abs(e.Diff))
+	message := fmt.Sprintf("第%d小节少了 %s", e.Bar, amount)
+	if e.Diff > 0 {
+		message = fmt.Sprintf("第%d小节多了 %s", e.Bar, amount)
+	}
+	if e.Source != "" {
+		return fmt.Sprintf("%s - %s", e.Source, message)
+	}
+	return message
+}
+
+// 按全音符的分数显示长度，如 1/8、3/16
+func formatWholeNotes(beats float64) string {
+	whole := beats / 4
+	for denominator := 1; denominator <= 256; denominator++ {
+		numerator := whole * float64(denominator)
+		if math.Abs(numerator-math.Round(numerator)) < 1e-6 {
+			if denominator == 1 {
+				return fmt.Sprintf("%d", int(math.Round(numerator)))
+			}
+			return fmt.Sprintf("%d/%d", int(math.Round(numerator)), denominator)
+		}
+	}
+	return fmt.Sprintf("%.3g拍", beats)
+}
+
+// 检查每条小节线之前的小节长度是否等于拍号要求的长度。
+// 只检查以小节线结束的小节；并行的轨道各自计数，反复按展开后的顺序计数
+func (s *Score) CheckBars() []BarError {
+	if s.RootElement == nil {
+		return nil
+	}
+
+	checker := &barChecker{meter: s.Time, reported: map[*Barline]bool{}}
+	cursor := barCursor{bar: 1}
+	checker.walk(s.RootElement, s.createPlayContext(), &cursor)
+	return checker.errors
+}
+
+type barChecker struct {
+	meter    core.TimeSignature
+	errors   []BarError
+	reported map[*Barline]bool // 反复展开后同一条小节线只报告一次
+}
+
+// 一个声部中的当前位置
+type barCursor struct {
+	bar    int     // 当前小节号
+	offset float64 // 距上一条小节线的长度
+}
+
+func (c *barChecker) walk(element Playable, context PlayContext, cursor *barCursor) {
+	switch e := element.(type) {
+	case *Barline:
+		diff := cursor.offset - c.meter.BarLength()
+		if math.Abs(diff) > 1e-6 && !c.reported[e] {
+			c.errors = append(c.errors, BarError{Bar: cursor.bar, Source: e.Source, Diff: diff})
+			c.reported[e] = true
+		}
+		cursor.bar++
+		cursor.offset = 0
+
+	case *Section:
+		sectionContext := context.WithContainerSettings(e.ContainerParams)
+		for _, child := range e.Elements {
+			c.walk(child, sectionContext, cursor)
+		}
+
+	case *Track:
+		// 每个子元素是一个从当前位置开始的声部，之后从最长的声部结束处继续
+		trackContext := context.WithContainerSettings(e.ContainerParams)
+		end := *cursor
+		longest := -1.0
+		for _, child := range e.Elements {
+			voice := *cursor
+			c.walk(child, trackContext, &voice)
+			if duration := child.Duration(trackContext); duration > longest {
+				longest = duration
+				end = voice
+			}
+		}
+		*cursor = end
+
+	case *Repeat:
+		for _, child := range e.Unroll() {
+			c.walk(child, context, cursor)
+		}
+
+	case *Hairpin:
+		for _, child := range e.Elements {
+			c.walk(child, context, cursor)
+		}
+
+	case *Transform:
+		c.walk(e.Element, e.childContext(context), cursor)
+
+	default:
+		cursor.offset += element.Duration(context)
+	}
+}
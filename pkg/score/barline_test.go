@@ -0,0 +1,117 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"strings"
+	"testing"
+)
+
+// 依次写出的小节线编号为 1:1、1:2...，便于在错误中定位
+type barlines struct{ count int }
+
+func (b *barlines) next() *Barline {
+	b.count++
+	return NewBarline("1:" + string(rune('0'+b.count)))
+}
+
+func describeBarErrors(errors []BarError) string {
+	messages := make([]string, len(errors))
+	for i, err := range errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func TestCheckBars(t *testing.T) {
+	quarter := func() Playable { return noteElement(core.C, 4, 1) }
+	half := func() Playable { return noteElement(core.C, 4, 2) }
+	whole := func() Playable { return noteElement(core.C, 4, 4) }
+
+	tests := []struct {
+		name  string
+		meter string
+		build func(bar *barlines) Playable
+		want  string
+	}{
+		{"小节长度正确", "3/4", func(bar *barlines) Playable {
+			return sectionOf("s", quarter(), quarter(), quarter(), bar.next(), half(), quarter(), bar.next())
+		}, ""},
+		{"少了", "3/4", func(bar *barlines) Playable {
+			return sectionOf("s", quarter(), quarter(), quarter(), bar.next(), half(), bar.next())
+		}, "1:2 - 第2小节少了 1/4"},
+		{"多了", "4/4", func(bar *barlines) Playable {
+			return sectionOf("s", whole(), noteElement(core.C, 4, 0.5), bar.next())
+		}, "1:1 - 第1小节多了 1/8"},
+		{"最后一小节不检查", "4/4", func(bar *barlines) Playable {
+			return sectionOf("s", whole(), bar.next(), quarter())
+		}, ""},
+		{"段落顺序累加", "2/4", func(bar *barlines) Playable {
+			return sectionOf("s", sectionOf("verse", quarter()), sectionOf("chorus", quarter(), bar.next(), quarter(), bar.next()))
+		}, "1:2 - 第2小节少了 1/4"},
+		{"连音按缩放后的长度", "2/4", func(bar *barlines) Playable {
+			return sectionOf("s", groupOf(1, noteElement(core.C, 4, 0.5), noteElement(core.D, 4, 0.5), noteElement(core.E, 4, 0.5)), quarter(), bar.next())
+		}, ""},
+		{"反复按展开后计数，同一条小节线只报告一次", "4/4", func(bar *barlines) Playable {
+			return sectionOf("s", repeatOf(3, []Playable{half(), bar.next()}, nil))
+		}, "1:1 - 第1小节少了 1/2"},
+		{"跳房子结尾中的小节线", "4/4", func(bar *barlines) Playable {
+			return sectionOf("s", repeatOf(2, []Playable{whole(), bar.next()}, map[int][]Playable{2: {half(), bar.next()}}))
+		}, "1:2 - 第3小节少了 1/2"},
+		{"6/8 拍", "6/8", func(bar *barlines) Playable {
+			return sectionOf("s", noteElement(core.C, 4, 1.5), noteElement(core.D, 4, 1.5), bar.next(), half(), bar.next())
+		}, "1:2 - 第2小节少了 1/4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meter, err := core.ParseTimeSignature(tt.meter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scoreObj := scoreOf(tt.build(&barlines{}))
+			scoreObj.Time = meter
+
+			if got := describeBarErrors(scoreObj.CheckBars()); got != tt.want {
+				t.Errorf("得到 %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBarsParallelTracks(t *testing.T) {
+	// 轨道中的元素各自计数，之后从最长的声部继续
+	bar := &barlines{}
+	upper := sectionOf("upper", noteElement(core.C, 4, 4), bar.next(), noteElement(core.C, 4, 4), bar.next())
+	lower := sectionOf("lower", noteElement(core.C, 3, 2), bar.next())
+
+	track := NewTrack("piano")
+	track.AddElement(upper)
+	track.AddElement(lower)
+
+	scoreObj := scoreOf(sectionOf("s", track, noteElement(core.C, 4, 2), bar.next()))
+	got := describeBarErrors(scoreObj.CheckBars())
+	if want := "1:3 - 第1小节少了 1/2; 1:4 - 第3小节少了 1/2"; got != want {
+		t.Errorf("得到 %q，期望 %q", got, want)
+	}
+}
+
+func TestFormatWholeNotes(t *testing.T) {
+	tests := []struct {
+		beats float64
+		want  string
+	}{
+		{4, "1"},
+		{8, "2"},
+		{1, "1/4"},
+		{1.5, "3/8"},
+		{0.75, "3/16"},
+		{1.0 / 3, "1/12"},
+		{0.001, "0.001拍"},
+	}
+
+	for _, tt := range tests {
+		if got := formatWholeNotes(tt.beats); got != tt.want {
+			t.Errorf("formatWholeNotes(%v) = %s，期望 %s", tt.beats, got, tt.want)
+		}
+	}
+}
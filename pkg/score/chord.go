@@ -9,6 +9,13 @@ import (
 type ChordElement struct {
     ID    string
     Chord core.Chord
+    Tie   bool // 以延音线连到下一个和弦的相同音高
+
+    // 排列方式：先转位决定低音，再按所在容器的 voicing 排列，最后 drop
+    Inversion int // 转位次数，1 为第一转位
+    Drop      int // 从上往下数第几个音降低八度，如 2 为 drop2
+
+    Articulations []core.Articulation // 演奏法：重音、断音等
     
     // 可选覆盖设置
     VolumeOverride     *int
@@ -38,24 +45,25 @@ func (ce *ChordElement) GetType() PlayableType {
 func (ce *ChordElement) Duration(context PlayContext) float64 {
     // 修正：使用和弦中第一个音符的节拍，或默认值
     if len(ce.Chord.Notes) > 0 {
-        return float64(ce.Chord.Notes[0].Beat)
+        return context.Scale(float64(ce.Chord.Notes[0].Beat))
     }
-    return 1.0 // 默认一拍
+    return context.Scale(1.0) // 默认一拍
 }
 
 func (ce *ChordElement) GenerateEvents(startTime float64, context PlayContext) []Event {
     events := []Event{}
-    velocity := ce.calculateVelocity(context)
+    velocity := ce.calculateVelocity(context, startTime)
     channel := ce.calculateChannel(context)
-    duration := ce.Duration(context)
+    velocity, duration := articulate(velocity, ce.Duration(context), ce.Tie, ce.Articulations)
+    context.hold(startTime, ce.Duration(context), ce.Articulations)
     
     // 修正：为和弦中每个音符生成事件
-    for _, note := range ce.Chord.Notes {
+    for _, note := range ce.Voiced(context).Notes {
         if len(note.MIDINote) == 0 {
             continue // 跳过无效音符
         }
         
-        midiNote := note.MIDINote[0]
+        midiNote := context.TransposeNote(note.MIDINote[0])
         
         // NOTE_ON 事件
         events = append(events, Event{
@@ -79,12 +87,30 @@ func (ce *ChordElement) GenerateEvents(startTime float64, context PlayContext) [
             Channel:       channel,
             Velocity:      0,
             SourceElement: ce.GetID(), // 修正：使用ce而不是ne
+            Tie:           ce.Tie,
         })
     }
     
     return events
 }
 
+// 按调号、排列方式、转位和 drop 得到实际发声的和弦
+func (ce *ChordElement) Voiced(context PlayContext) core.Chord {
+    chord := context.CurrentKey.ApplyChord(ce.Chord)
+    if context.CurrentVoicing == core.DefaultVoicing && ce.Inversion == 0 && ce.Drop == 0 {
+        return chord
+    }
+
+    if ce.Inversion > 0 {
+        chord = chord.Invert(ce.Inversion)
+    }
+    chord = chord.Voice(context.CurrentVoicing)
+    if ce.Drop > 0 {
+        chord = chord.Drop(ce.Drop)
+    }
+    return chord
+}
+
 // 实现Element接口
 func (ce *ChordElement) SetVolumeOverride(volume int) {
     ce.VolumeOverride = &volume
@@ -99,17 +125,20 @@ func (ce *ChordElement) SetChannelOverride(channel int) {
 }
 
 // 辅助方法
-func (ce *ChordElement) calculateVelocity(context PlayContext) uint8 {
+func (ce *ChordElement) calculateVelocity(context PlayContext, beat float64) uint8 {
     if ce.VolumeOverride != nil {
-        return uint8(*ce.VolumeOverride)
+        return context.ScaleVelocity(uint8(*ce.VolumeOverride))
+    }
+    if velocity, ok := context.dynamicVelocity(beat); ok {
+        return context.ScaleVelocity(velocity)
     }
     
     // 从和弦中第一个音符获取velocity
     if len(ce.Chord.Notes) > 0 && ce.Chord.Notes[0].Velocity > 0 {
-        return ce.Chord.Notes[0].Velocity
+        return context.ScaleVelocity(ce.Chord.Notes[0].Velocity)
     }
     
-    return uint8(context.CurrentVolume)
+    return context.ScaleVelocity(uint8(context.CurrentVolume))
 }
 
 func (ce *ChordElement) calculateChannel(context PlayContext) int {
@@ -150,6 +179,15 @@ func (ne *ChordElement) DetailedString(indent string) string {
     result += fmt.Sprintf("%s  ID: %s\n", indent, ne.GetID())
 
     result += fmt.Sprintf("%s  时长: %.3f拍\n", indent, ne.Duration(PlayContext{}))
+    if ne.Inversion > 0 {
+        result += fmt.Sprintf("%s  转位: %d\n", indent, ne.Inversion)
+    }
+    if ne.Drop > 0 {
+        result += fmt.Sprintf("%s  Drop: %d\n", indent, ne.Drop)
+    }
+    if len(ne.Articulations) > 0 {
+        result += fmt.Sprintf("%s  演奏法: %s\n", indent, formatArticulations(ne.Articulations))
+    }
     
     // 显示覆盖参数
     if ne.VolumeOverride != nil || ne.InstrumentOverride != nil || ne.ChannelOverride != nil {
@@ -1,6 +1,11 @@
 package score
 
-import "catRock/pkg/core"
+import (
+	"catRock/pkg/core"
+	"catRock/pkg/sequencer"
+	"math"
+	"sort"
+)
 
 // 播放上下文
 type PlayContext struct {
@@ -13,6 +18,23 @@ type PlayContext struct {
 	CurrentVolume     int
 	CurrentInstrument core.InstrumentID
 	CurrentChannel    int
+	CurrentVoicing    core.Voicing
+	CurrentKey        core.Key
+
+	// 力度记号：当前力度 (0 为未标记)、演奏方式和正在进行的渐强渐弱
+	CurrentDynamic core.Dynamic
+	DynamicsMode   core.DynamicsMode
+	swell          *dynamicSwell
+
+	// 时值缩放比例（连音、模板的 augment/diminish），0 视为 1
+	TimeScale float64
+
+	// 模板变换：移调半音数与力度缩放比例（0 视为 1）
+	Transpose     int
+	VelocityScale float64
+
+	// 生成事件时记录容器的速度变化和延长记号，为空时不记录
+	Tempo *tempoRecorder
 
 	// 循环检测
 	ElementStack []string
@@ -27,10 +49,147 @@ func NewPlayContext(bpm float64, volume int) PlayContext {
 		CurrentVolume:     volume,
 		CurrentInstrument: 1, // 默认乐器ID
 		CurrentChannel:    1,
+		TimeScale:         1,
 		ElementStack:      []string{},
 	}
 }
 
+// 按当前缩放比例换算时值
+func (pc PlayContext) Scale(beats float64) float64 {
+	if pc.TimeScale == 0 {
+		return beats
+	}
+	return beats * pc.TimeScale
+}
+
+// 在当前缩放比例上再乘以 factor，嵌套的连音逐层相乘
+func (pc PlayContext) WithTimeScale(factor float64) PlayContext {
+	context := pc
+	context.TimeScale = pc.Scale(factor)
+	return context
+}
+
+// 在当前移调上再移 semitones 个半音
+func (pc PlayContext) WithTranspose(semitones int) PlayContext {
+	context := pc
+	context.Transpose += semitones
+	return context
+}
+
+// 在当前力度缩放比例上再乘以 factor
+func (pc PlayContext) WithVelocityScale(factor float64) PlayContext {
+	context := pc
+	if pc.VelocityScale == 0 {
+		context.VelocityScale = factor
+	} else {
+		context.VelocityScale = pc.VelocityScale * factor
+	}
+	return context
+}
+
+// 按当前移调换算MIDI音高，限制在 0-127
+func (pc PlayContext) TransposeNote(midiNote byte) byte {
+	return clampMIDI(int(midiNote)+pc.Transpose, 0)
+}
+
+// 按当前力度缩放比例换算力度，限制在 1-127
+func (pc PlayContext) ScaleVelocity(velocity uint8) uint8 {
+	if pc.VelocityScale == 0 || velocity == 0 {
+		return velocity
+	}
+	return clampMIDI(int(math.Round(float64(velocity)*pc.VelocityScale)), 1)
+}
+
+func clampMIDI(value int, min int) byte {
+	if value < min {
+		return byte(min)
+	}
+	if value > 127 {
+		return 127
+	}
+	return byte(value)
+}
+
+// 记录从 beat 拍开始的速度，与该拍已生效的速度相同时忽略
+func (pc PlayContext) setTempo(beat float64, bpm float64) {
+	if pc.Tempo == nil || bpm <= 0 || pc.Tempo.tempo.TempoAt(beat) == bpm {
+		return
+	}
+	pc.Tempo.tempo.SetTempo(beat, bpm)
+}
+
+// 记录容器开始时设置的速度或速度渐变，返回在容器结束的拍恢复外层速度的函数。
+// 外层的渐变没有走完时从结束的拍继续渐变，而不是停在进入时的速度
+func (pc PlayContext) enterTempo(beat float64, params ContainerParams) func(end float64) {
+	if pc.Tempo == nil || params.BPM == nil {
+		return func(float64) {}
+	}
+
+	outer := pc.Tempo.tempo.ChangeAt(beat)
+	restore := func(end float64) {
+		// 容器内的速度与外层相同时没有记下变化点，无需恢复
+		if pc.Tempo.tempo.ChangeAt(end) == outer {
+			return
+		}
+		pc.Tempo.tempo.Resume(end, outer)
+	}
+
+	if params.BPMRamp != nil {
+		pc.Tempo.tempo.SetRamp(beat, params.BPMRamp.Beats, params.BPMRamp.From, *params.BPM)
+		return restore
+	}
+	pc.setTempo(beat, *params.BPM)
+	return restore
+}
+
+// 记录带延长记号的元素所占的拍
+func (pc PlayContext) hold(beat, length float64, articulations []core.Articulation) {
+	if pc.Tempo == nil || length <= 0 {
+		return
+	}
+	if factor := core.ArticulationHold(articulations); factor > 1 {
+		pc.Tempo.holds = append(pc.Tempo.holds, tempoHold{start: beat, end: beat + length, factor: factor})
+	}
+}
+
+// 生成事件时收集的速度变化和延长记号
+type tempoRecorder struct {
+	tempo *sequencer.TempoMap
+	holds []tempoHold
+}
+
+// 延长记号：把 [start, end) 拍的演奏时间延长为 factor 倍
+type tempoHold struct {
+	start, end float64
+	factor     float64
+}
+
+func newTempoRecorder(bpm float64) *tempoRecorder {
+	return &tempoRecorder{tempo: sequencer.NewTempoMap(bpm)}
+}
+
+// 把延长记号写入速度表。并行声部同时出现的延长记号只延长一次，重叠部分取最大的倍数
+func (r *tempoRecorder) finish() *sequencer.TempoMap {
+	sort.Slice(r.holds, func(i, j int) bool { return r.holds[i].start < r.holds[j].start })
+
+	var merged []tempoHold
+	for _, hold := range r.holds {
+		last := len(merged) - 1
+		if last >= 0 && hold.start < merged[last].end {
+			merged[last].end = math.Max(merged[last].end, hold.end)
+			merged[last].factor = math.Max(merged[last].factor, hold.factor)
+			continue
+		}
+		merged = append(merged, hold)
+	}
+
+	for _, hold := range merged {
+		r.tempo.Stretch(hold.start, hold.end-hold.start, hold.factor)
+	}
+	r.holds = nil
+	return r.tempo
+}
+
 // 应用容器设置
 func (pc PlayContext) WithContainerSettings(params ContainerParams) PlayContext {
 	context := pc
@@ -47,14 +206,33 @@ func (pc PlayContext) WithContainerSettings(params ContainerParams) PlayContext
 	if params.Channel != nil {
 		context.CurrentChannel = *params.Channel
 	}
+	if params.Voicing != nil {
+		context.CurrentVoicing = *params.Voicing
+	}
+	if params.Key != nil {
+		context.CurrentKey = *params.Key
+	}
+	if params.Dynamics != nil {
+		context.DynamicsMode = *params.Dynamics
+	}
 
 	return context
 }
 
+// 速度渐变：容器开始后 Beats 拍内从 From 线性变化到容器的 BPM
+type TempoRamp struct {
+	From  float64 `json:"from"`
+	Beats float64 `json:"beats"`
+}
+
 // 通用容器参数
 type ContainerParams struct {
 	BPM        *float64
+	BPMRamp    *TempoRamp
 	Volume     *int
 	Instrument *core.InstrumentID
 	Channel    *int
+	Voicing    *core.Voicing
+	Key        *core.Key
+	Dynamics   *core.DynamicsMode
 }
@@ -0,0 +1,267 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"fmt"
+	"math"
+)
+
+// 力度记号 - 不占时间，改变同一顺序容器中之后元素的力度
+type DynamicMark struct {
+	ID      string
+	Dynamic core.Dynamic
+}
+
+var _ Playable = (*DynamicMark)(nil)
+
+func (d *DynamicMark) GetID() string {
+	if d.ID != "" {
+		return d.ID
+	}
+	return fmt.Sprintf("dynamic_%s", d.Dynamic)
+}
+
+func (d *DynamicMark) GetType() PlayableType {
+	return DYNAMIC_TYPE
+}
+
+func (d *DynamicMark) Duration(context PlayContext) float64 {
+	return 0
+}
+
+// 表情模式下发送对应的表情控制器值，力度模式下由之后的音符体现
+func (d *DynamicMark) GenerateEvents(startTime float64, context PlayContext) []Event {
+	if context.DynamicsMode != core.ExpressionDynamics {
+		return []Event{}
+	}
+	return []Event{expressionEvent(startTime, d.Dynamic.Velocity(), context, d.GetID())}
+}
+
+// 之后元素使用的上下文
+func (d *DynamicMark) apply(context PlayContext) PlayContext {
+	context.CurrentDynamic = d.Dynamic
+	context.swell = nil
+	return context
+}
+
+// 构造函数
+func NewDynamicMark(dynamic core.Dynamic) *DynamicMark {
+	return &DynamicMark{Dynamic: dynamic}
+}
+
+func (d *DynamicMark) DetailedString(indent string) string {
+	return fmt.Sprintf("Dynamic %s\n", d.Dynamic)
+}
+
+// 渐强/渐弱 - 顺序播放子元素，力度在整个范围内逐渐升高或降低 Steps 级
+type Hairpin struct {
+	ID       string
+	Steps    int // 正数为渐强 (cresc)，负数为渐弱 (dim)
+	Elements []Playable
+}
+
+var _ Playable = (*Hairpin)(nil)
+
+// 表情模式下渐变的表情控制器每隔多少拍发送一次
+const expressionStep = 0.25
+
+func (h *Hairpin) GetID() string {
+	if h.ID != "" {
+		return h.ID
+	}
+	if h.Steps < 0 {
+		return fmt.Sprintf("dim_%d", -h.Steps)
+	}
+	return fmt.Sprintf("cresc_%d", h.Steps)
+}
+
+func (h *Hairpin) GetType() PlayableType {
+	return HAIRPIN_TYPE
+}
+
+func (h *Hairpin) Duration(context PlayContext) float64 {
+	totalDuration := 0.0
+	for _, element := range h.Elements {
+		totalDuration += element.Duration(context)
+	}
+	return totalDuration
+}
+
+func (h *Hairpin) GenerateEvents(startTime float64, context PlayContext) []Event {
+	events := []Event{}
+	from, to := h.levels(context)
+	end := startTime + h.Duration(context)
+
+	// 之前没有力度记号时从未标记时的响度开始
+	swell := &dynamicSwell{start: startTime, end: end, from: float64(from.Velocity()), to: float64(to.Velocity())}
+	if context.CurrentDynamic == 0 {
+		swell.from = float64(context.unmarkedLevel())
+	}
+
+	childContext := context
+	childContext.swell = swell
+
+	currentTime := startTime
+	last := -1
+	for _, element := range h.Elements {
+		duration := element.Duration(childContext)
+		// 表情模式逐个元素发送渐变，遇到含有力度记号的元素后不再发送
+		if containsDynamicMark(element) {
+			childContext.swell = nil
+		}
+		if childContext.DynamicsMode == core.ExpressionDynamics && childContext.swell == swell {
+			var ramp []Event
+			ramp, last = h.expressionRamp(swell, currentTime, currentTime+duration, last, childContext)
+			events = append(events, ramp...)
+		}
+		events = append(events, element.GenerateEvents(currentTime, childContext)...)
+		currentTime += duration
+		childContext = afterElement(element, childContext)
+	}
+	if childContext.DynamicsMode == core.ExpressionDynamics && childContext.swell == swell {
+		if value := int(math.Round(swell.to)); value != last {
+			events = append(events, expressionEvent(end, uint8(value), childContext, h.GetID()))
+		}
+	}
+
+	return events
+}
+
+// 渐变开始和结束时的力度记号；之前没有力度记号时从与未标记时的响度最接近的开始
+func (h *Hairpin) levels(context PlayContext) (core.Dynamic, core.Dynamic) {
+	from := context.CurrentDynamic
+	if from == 0 {
+		from = core.NearestDynamic(int(context.unmarkedLevel()))
+	}
+	return from, from.Step(h.Steps)
+}
+
+// 渐变结束后，之后的元素使用目标力度
+func (h *Hairpin) after(context PlayContext) PlayContext {
+	_, to := h.levels(context)
+	return NewDynamicMark(to).apply(context)
+}
+
+// 在 [from, to) 拍内按固定间隔发送渐变的表情控制器值，与上一个值 last 相同时跳过；返回最后发送的值
+func (h *Hairpin) expressionRamp(swell *dynamicSwell, from, to float64, last int, context PlayContext) ([]Event, int) {
+	events := []Event{}
+	first := swell.start + math.Ceil((from-swell.start)/expressionStep-1e-9)*expressionStep
+	for beat := first; beat < to-1e-9; beat += expressionStep {
+		value := int(math.Round(swell.at(beat)))
+		if value != last {
+			events = append(events, expressionEvent(beat, uint8(value), context, h.GetID()))
+			last = value
+		}
+	}
+	return events, last
+}
+
+// 构造函数
+func NewHairpin(steps int) *Hairpin {
+	return &Hairpin{Steps: steps, Elements: []Playable{}}
+}
+
+func (h *Hairpin) AddElement(element Playable) {
+	h.Elements = append(h.Elements, element)
+}
+
+func (h *Hairpin) DetailedString(indent string) string {
+	name := "渐强"
+	if h.Steps < 0 {
+		name = "渐弱"
+	}
+	result := fmt.Sprintf("Hairpin %s %d级 {\n", name, int(math.Abs(float64(h.Steps))))
+	result += fmt.Sprintf("%s  ID: %s\n", indent, h.GetID())
+	result += fmt.Sprintf("%s  时长: %.3f拍\n", indent, h.Duration(PlayContext{}))
+	if len(h.Elements) > 0 {
+		result += fmt.Sprintf("%s  元素 (%d个):\n", indent, len(h.Elements))
+		for i, element := range h.Elements {
+			result += fmt.Sprintf("%s    [%d] %s", indent, i, element.DetailedString(indent+"      "))
+		}
+	}
+	result += fmt.Sprintf("%s}\n", indent)
+	return result
+}
+
+// 渐强渐弱的范围：力度在 [start, end) 拍内从 from 线性变化到 to
+type dynamicSwell struct {
+	start, end float64
+	from, to   float64
+}
+
+func (s *dynamicSwell) at(beat float64) float64 {
+	if s.end <= s.start {
+		return s.to
+	}
+	progress := math.Min(1, math.Max(0, (beat-s.start)/(s.end-s.start)))
+	return s.from + (s.to-s.from)*progress
+}
+
+// 元素中是否含有力度记号 (不含嵌套轨道)
+func containsDynamicMark(element Playable) bool {
+	var children []Playable
+	switch e := element.(type) {
+	case *DynamicMark:
+		return true
+	case *Section:
+		children = e.Elements
+	case *GroupElement:
+		children = e.GetElements()
+	case *Hairpin:
+		children = e.Elements
+	case *Repeat:
+		children = e.Unroll()
+	case *Transform:
+		children = []Playable{e.Element}
+	}
+	for _, child := range children {
+		if containsDynamicMark(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// 顺序播放时，元素对之后元素的力度的影响
+func afterElement(element Playable, context PlayContext) PlayContext {
+	switch e := element.(type) {
+	case *DynamicMark:
+		return e.apply(context)
+	case *Hairpin:
+		return e.after(context)
+	}
+	return context
+}
+
+// 没有力度记号时的响度：音符的默认力度，或表情控制器的默认值
+func (pc PlayContext) unmarkedLevel() uint8 {
+	if pc.DynamicsMode == core.ExpressionDynamics {
+		return 127
+	}
+	return 100 // 与 core.NewNote 的默认力度相同
+}
+
+// 力度模式下某一拍开始的音符由力度记号决定的力度；没有力度记号或为表情模式时返回 false
+func (pc PlayContext) dynamicVelocity(beat float64) (uint8, bool) {
+	if pc.DynamicsMode != core.VelocityDynamics {
+		return 0, false
+	}
+	if pc.swell != nil {
+		return uint8(math.Round(pc.swell.at(beat))), true
+	}
+	if pc.CurrentDynamic != 0 {
+		return pc.CurrentDynamic.Velocity(), true
+	}
+	return 0, false
+}
+
+func expressionEvent(time float64, value uint8, context PlayContext, source string) Event {
+	return Event{
+		Time:          time,
+		Type:          CONTROL_EVENT,
+		Action:        EXPRESSION_CHANGE,
+		Data:          value,
+		Channel:       context.CurrentChannel,
+		SourceElement: source,
+	}
+}
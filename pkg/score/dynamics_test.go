@@ -0,0 +1,91 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// 把音符力度写成 "音名~力度"，表情控制器写成 "cc@拍=值"
+func describeDynamics(t *testing.T, scoreObj *Score) string {
+	t.Helper()
+	events, err := NewPlayEngine(scoreObj).GenerateEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parts []string
+	for _, event := range events {
+		switch event.Action {
+		case NOTE_ON:
+			midi := int(event.Data.(byte))
+			parts = append(parts, fmt.Sprintf("%s%d~%d", pitchNames[midi%12], midi/12-5, event.Velocity))
+		case EXPRESSION_CHANGE:
+			parts = append(parts, fmt.Sprintf("cc@%.4g=%d", event.Time, event.Data.(uint8)))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func hairpinOf(steps int, elements ...Playable) *Hairpin {
+	hairpin := NewHairpin(steps)
+	for _, element := range elements {
+		hairpin.AddElement(element)
+	}
+	return hairpin
+}
+
+func TestVelocityDynamics(t *testing.T) {
+	c := func() Playable { return noteElement(core.C, 4, 1) }
+	d := func() Playable { return noteElement(core.D, 4, 1) }
+	e := func() Playable { return noteElement(core.E, 4, 1) }
+	mark := func(dynamic core.Dynamic) Playable { return NewDynamicMark(dynamic) }
+
+	tests := []struct {
+		name string
+		root Playable
+		want string
+	}{
+		{"没有力度记号", sectionOf("s", c(), d()), "C4~100 D4~100"},
+		{"力度记号作用于之后的音符", sectionOf("s", c(), mark(core.DynamicP), d(), e()), "C4~100 D4~49 E4~49"},
+		// p (49) 经 3 拍渐强到 mf (80)，按音符开始的位置取值
+		{"渐强", sectionOf("s", mark(core.DynamicP), hairpinOf(2, c(), d(), e()), c()), "C4~49 D4~59 E4~70 C4~80"},
+		// 未标记时从默认力度 100 开始，目标为最接近的 f 降低两级
+		{"没有起始记号的渐弱", sectionOf("s", hairpinOf(-2, c(), d()), e()), "C4~100 D4~82 E4~64"},
+		{"超出范围时停在 fff", sectionOf("s", mark(core.DynamicFF), hairpinOf(3, c()), d()), "C4~112 D4~127"},
+		{"渐变中的力度记号结束渐变", sectionOf("s", mark(core.DynamicP), hairpinOf(2, c(), mark(core.DynamicFF), d())), "C4~49 D4~112"},
+		{"嵌套段落中的记号影响之后的元素", sectionOf("s", sectionOf("intro", mark(core.DynamicMF), c()), d()), "C4~80 D4~100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeDynamics(t, scoreOf(tt.root)); got != tt.want {
+				t.Errorf("得到 %s，期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpressionDynamics(t *testing.T) {
+	// 表情模式下音符力度不变，渐变每 expressionStep 拍发送一次表情控制器；
+	// 渐变开始时重发起始值，因为嵌套轨道的通道可能没有收到之前的力度记号
+	section := sectionOf("s", NewDynamicMark(core.DynamicP),
+		hairpinOf(2, noteElement(core.C, 4, 0.5), noteElement(core.D, 4, 0.5)), noteElement(core.E, 4, 1))
+	section.SetDynamicsMode(core.ExpressionDynamics)
+
+	var notes, controls []string
+	for _, part := range strings.Fields(describeDynamics(t, scoreOf(section))) {
+		if strings.HasPrefix(part, "cc@") {
+			controls = append(controls, part)
+		} else {
+			notes = append(notes, part)
+		}
+	}
+	if got, want := strings.Join(notes, " "), "C4~100 D4~100 E4~100"; got != want {
+		t.Errorf("音符为 %s，期望 %s", got, want)
+	}
+	if got, want := strings.Join(controls, " "), "cc@0=49 cc@0=49 cc@0.25=57 cc@0.5=65 cc@0.75=72 cc@1=80"; got != want {
+		t.Errorf("表情控制器为 %s，期望 %s", got, want)
+	}
+}
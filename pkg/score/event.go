@@ -12,6 +12,8 @@ type Event struct {
     Channel  int
     Velocity uint8
     SourceElement string
+    Track    string // 所属轨道ID（嵌套轨道取最内层）
+    Tie      bool   // NOTE_OFF 以延音线连到同一时刻同音高的 NOTE_ON
 }
 
 func (e *Event) String() string {
@@ -25,17 +27,20 @@ func (e *Event) String() string {
         actionName = "PROGRAM_CHANGE"
     case VOLUME_CHANGE:
         actionName = "VOLUME_CHANGE"
+    case EXPRESSION_CHANGE:
+        actionName = "EXPRESSION_CHANGE"
     default:
         actionName = fmt.Sprintf("ACTION_%d", e.Action)
     }
     
-    return fmt.Sprintf("[%.3fs] %s Ch:%d Data:%v Vel:%d Dur:%.3f Src:%s",
+    // Time 和 Duration 以拍为单位；需要 小节:拍 时用 TimeSignature.Position
+    return fmt.Sprintf("[beat %.3f] %s Ch:%d Data:%v Vel:%d Dur:%.3f Src:%s",
         e.Time, actionName, e.Channel, e.Data, e.Velocity, e.Duration, e.SourceElement)
 }
 
 // 添加详细字符串方法
 func (e *Event) DetailedString() string {
-    result := fmt.Sprintf("Event at %.3fs:\n", e.Time)
+    result := fmt.Sprintf("Event at beat %.3f:\n", e.Time)
     result += fmt.Sprintf("  Action: %s\n", e.getActionName())
     result += fmt.Sprintf("  Channel: %d\n", e.Channel)
     result += fmt.Sprintf("  Data: %v\n", e.Data)
@@ -63,6 +68,8 @@ func (e *Event) getActionName() string {
         return "PROGRAM_CHANGE"
     case VOLUME_CHANGE:
         return "VOLUME_CHANGE"
+    case EXPRESSION_CHANGE:
+        return "EXPRESSION_CHANGE"
     default:
         return fmt.Sprintf("UNKNOWN_%d", e.Action)
     }
@@ -84,4 +91,5 @@ const (
     NOTE_OFF
     VOLUME_CHANGE
     PROGRAM_CHANGE
+    EXPRESSION_CHANGE // 表情控制器 CC11
 )
@@ -0,0 +1,753 @@
+package score
+
+import (
+	"bytes"
+	"catRock/pkg/core"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// JSON格式版本号，结构变更时递增
+//
+//	1: 音符、和弦、休止符与容器
+//	2: 延音线 (tie)
+//	3: 和弦转位与 drop (inversion、drop)，容器的 voicing
+//	4: 反复与跳房子结尾 (repeat、endings)
+//	5: 变换 (transform)
+//	6: 演奏法 (articulations)
+//	7: 调号 (key) 与按调号升降的音符 (followKey)
+//	8: 拍号 (time) 与小节线 (barline)
+//	9: 速度渐变 (bpmRamp)
+//	10: 力度记号 (dynamic、hairpin) 与力度模式 (dynamics)
+const JSONSchemaVersion = 10
+
+// 仍能读取的最低版本：新增字段都可省略，旧文档按默认值补齐
+const minJSONSchemaVersion = 1
+
+// JSON中的元素类型名
+const (
+	jsonTypeNote      = "note"
+	jsonTypeChord     = "chord"
+	jsonTypeRest      = "rest"
+	jsonTypeGroup     = "group"
+	jsonTypeSection   = "section"
+	jsonTypeTrack     = "track"
+	jsonTypeRepeat    = "repeat"
+	jsonTypeTransform = "transform"
+	jsonTypeBarline   = "barline"
+	jsonTypeDynamic   = "dynamic"
+	jsonTypeHairpin   = "hairpin"
+)
+
+// Score的JSON表示
+type ScoreJSON struct {
+	Version        int                    `json:"version"`
+	Title          string                 `json:"title"`
+	Composer       string                 `json:"composer"`
+	Year           int                    `json:"year"`
+	BPM            float64                `json:"bpm"`
+	Volume         int                    `json:"volume"`
+	Time           string                 `json:"time,omitempty"`
+	Key            string                 `json:"key,omitempty"`
+	Dynamics       string                 `json:"dynamics,omitempty"`
+	GlobalSettings map[string]interface{} `json:"globalSettings,omitempty"`
+	Root           *PlayableJSON          `json:"root"`
+}
+
+// Playable树节点的JSON表示，按Type决定哪些字段有效
+type PlayableJSON struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+
+	// section / track
+	Name   string      `json:"name,omitempty"`
+	Params *ParamsJSON `json:"params,omitempty"`
+
+	// note / chord
+	Note      *NoteJSON   `json:"note,omitempty"`
+	Notes     []NoteJSON  `json:"notes,omitempty"`
+	Overrides *ParamsJSON `json:"overrides,omitempty"`
+	Tie       bool        `json:"tie,omitempty"` // 以延音线连到下一个元素
+
+	// note / chord：演奏法名称，如 "accent"、"staccato"
+	Articulations []string `json:"articulations,omitempty"`
+
+	// chord：转位与 drop
+	Inversion int `json:"inversion,omitempty"`
+	Drop      int `json:"drop,omitempty"`
+
+	// rest
+	Rest *RestJSON `json:"rest,omitempty"`
+
+	// group：可选的整体时值
+	Duration *float64 `json:"duration,omitempty"`
+
+	// section / track / group / repeat
+	Elements []*PlayableJSON `json:"elements,omitempty"`
+
+	// repeat：反复次数与跳房子结尾
+	Times   int           `json:"times,omitempty"`
+	Endings []*EndingJSON `json:"endings,omitempty"`
+
+	// transform：唯一的子元素放在 elements 中
+	Transpose     int     `json:"transpose,omitempty"`
+	TimeScale     float64 `json:"timeScale,omitempty"`
+	VelocityScale float64 `json:"velocityScale,omitempty"`
+
+	// barline：书写位置
+	Source string `json:"source,omitempty"`
+
+	// dynamic：力度记号名称，如 "mf"
+	Dynamic string `json:"dynamic,omitempty"`
+
+	// hairpin：渐强 (正) 或渐弱 (负) 的级数，子元素放在 elements 中
+	Steps int `json:"steps,omitempty"`
+}
+
+type EndingJSON struct {
+	Number   int             `json:"number"`
+	Elements []*PlayableJSON `json:"elements"`
+}
+
+// 容器参数与元素覆盖参数共用的结构，未设置的字段省略。
+// octave_mode 只在解析源码时起作用，导出的音符已是绝对八度，因此不保存
+type ParamsJSON struct {
+	BPM        *float64   `json:"bpm,omitempty"`
+	BPMRamp    *TempoRamp `json:"bpmRamp,omitempty"` // 从 from 经 beats 拍渐变到 bpm
+	Volume     *int       `json:"volume,omitempty"`
+	Instrument *int       `json:"instrument,omitempty"`
+	Channel    *int       `json:"channel,omitempty"`
+	Voicing    string     `json:"voicing,omitempty"`
+	Key        string     `json:"key,omitempty"`
+	Dynamics   string     `json:"dynamics,omitempty"`
+}
+
+type NoteJSON struct {
+	Name       string  `json:"name"`
+	Octave     int     `json:"octave"`
+	Accidental int     `json:"accidental,omitempty"`
+	FollowKey  bool    `json:"followKey,omitempty"`
+	MIDINote   []int   `json:"midi"`
+	Beat       float64 `json:"beat"`
+	TrackID    int     `json:"trackId,omitempty"`
+	Channel    uint8   `json:"channel,omitempty"`
+	Instrument int     `json:"instrument,omitempty"`
+	Velocity   uint8   `json:"velocity,omitempty"`
+}
+
+type RestJSON struct {
+	Beat     float64 `json:"beat"`
+	Position int     `json:"position,omitempty"`
+}
+
+// JSON导出
+func (s *Score) exportJSON(options ExportOptions) error {
+	if options.FileName == "" {
+		return fmt.Errorf("未指定导出文件名")
+	}
+
+	data, err := s.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(options.FileName, data, 0644); err != nil {
+		return fmt.Errorf("写入JSON文件失败: %v", err)
+	}
+	return nil
+}
+
+// 序列化为带缩进的JSON
+func (s *Score) ToJSON() ([]byte, error) {
+	doc := ScoreJSON{
+		Version:        JSONSchemaVersion,
+		Title:          s.Title,
+		Composer:       s.Composer,
+		Year:           s.Year,
+		BPM:            s.BPM,
+		Volume:         s.Volume,
+		GlobalSettings: s.GlobalSettings,
+	}
+	if s.Time != (core.TimeSignature{}) {
+		doc.Time = s.Time.String()
+	}
+	if s.Key != (core.Key{}) {
+		doc.Key = s.Key.String()
+	}
+	if s.Dynamics != core.VelocityDynamics {
+		doc.Dynamics = s.Dynamics.String()
+	}
+
+	if s.RootElement != nil {
+		root, err := playableToJSON(s.RootElement)
+		if err != nil {
+			return nil, err
+		}
+		doc.Root = root
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("JSON序列化失败: %v", err)
+	}
+	return data, nil
+}
+
+// 从JSON重建Score
+func ParseScoreJSON(data []byte) (*Score, error) {
+	// 先检查版本，更新的版本中的新字段不报成未知字段
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %v", err)
+	}
+	if header.Version < minJSONSchemaVersion || header.Version > JSONSchemaVersion {
+		return nil, fmt.Errorf("不支持的JSON格式版本: %d (支持版本: %d-%d)", header.Version, minJSONSchemaVersion, JSONSchemaVersion)
+	}
+
+	// 无法识别的字段直接报错，避免设置被悄悄丢弃
+	var doc ScoreJSON
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %v", err)
+	}
+
+	s := &Score{
+		Title:          doc.Title,
+		Composer:       doc.Composer,
+		Year:           doc.Year,
+		BPM:            doc.BPM,
+		Volume:         doc.Volume,
+		GlobalSettings: doc.GlobalSettings,
+	}
+	if s.GlobalSettings == nil {
+		s.GlobalSettings = make(map[string]interface{})
+	}
+	if doc.Time != "" {
+		time, err := core.ParseTimeSignature(doc.Time)
+		if err != nil {
+			return nil, err
+		}
+		s.Time = time
+	}
+	if doc.Key != "" {
+		key, err := core.ParseKey(doc.Key)
+		if err != nil {
+			return nil, err
+		}
+		s.Key = key
+	}
+	if doc.Dynamics != "" {
+		mode, err := core.ParseDynamicsMode(doc.Dynamics)
+		if err != nil {
+			return nil, err
+		}
+		s.Dynamics = mode
+	}
+
+	if doc.Root != nil {
+		root, err := playableFromJSON(doc.Root, "root")
+		if err != nil {
+			return nil, err
+		}
+		s.RootElement = root
+	}
+
+	return s, nil
+}
+
+// 从JSON文件加载Score
+func LoadScoreJSON(filename string) (*Score, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取失败: %v", err)
+	}
+	return ParseScoreJSON(data)
+}
+
+func playableToJSON(element Playable) (*PlayableJSON, error) {
+	switch e := element.(type) {
+	case *NoteElement:
+		note := noteToJSON(e.Note)
+		return &PlayableJSON{
+			Type:          jsonTypeNote,
+			ID:            e.ID,
+			Note:          &note,
+			Overrides:     overridesToJSON(e.VolumeOverride, e.InstrumentOverride, e.ChannelOverride),
+			Tie:           e.Tie,
+			Articulations: articulationsToJSON(e.Articulations),
+		}, nil
+
+	case *ChordElement:
+		notes := make([]NoteJSON, len(e.Chord.Notes))
+		for i, note := range e.Chord.Notes {
+			notes[i] = noteToJSON(note)
+		}
+		return &PlayableJSON{
+			Type:          jsonTypeChord,
+			ID:            e.ID,
+			Notes:         notes,
+			Overrides:     overridesToJSON(e.VolumeOverride, e.InstrumentOverride, e.ChannelOverride),
+			Tie:           e.Tie,
+			Inversion:     e.Inversion,
+			Drop:          e.Drop,
+			Articulations: articulationsToJSON(e.Articulations),
+		}, nil
+
+	case *RestElement:
+		return &PlayableJSON{
+			Type: jsonTypeRest,
+			ID:   e.ID,
+			Rest: &RestJSON{Beat: float64(e.Rest.Beat), Position: e.Rest.Position},
+		}, nil
+
+	case *GroupElement:
+		elements, err := playablesToJSON(e.elements)
+		if err != nil {
+			return nil, err
+		}
+		return &PlayableJSON{
+			Type:     jsonTypeGroup,
+			ID:       e.ID,
+			Duration: e.duration,
+			Elements: elements,
+		}, nil
+
+	case *Section:
+		elements, err := playablesToJSON(e.Elements)
+		if err != nil {
+			return nil, err
+		}
+		return &PlayableJSON{
+			Type:     jsonTypeSection,
+			ID:       e.ID,
+			Name:     e.Name,
+			Params:   containerParamsToJSON(e.ContainerParams),
+			Elements: elements,
+		}, nil
+
+	case *Track:
+		elements, err := playablesToJSON(e.Elements)
+		if err != nil {
+			return nil, err
+		}
+		return &PlayableJSON{
+			Type:     jsonTypeTrack,
+			ID:       e.ID,
+			Name:     e.Name,
+			Params:   containerParamsToJSON(e.ContainerParams),
+			Elements: elements,
+		}, nil
+
+	case *Transform:
+		child, err := playableToJSON(e.Element)
+		if err != nil {
+			return nil, err
+		}
+		return &PlayableJSON{
+			Type:          jsonTypeTransform,
+			ID:            e.ID,
+			Elements:      []*PlayableJSON{child},
+			Transpose:     e.Transpose,
+			TimeScale:     e.TimeScale,
+			VelocityScale: e.VelocityScale,
+		}, nil
+
+	case *Repeat:
+		elements, err := playablesToJSON(e.Elements)
+		if err != nil {
+			return nil, err
+		}
+		endings := make([]*EndingJSON, 0, len(e.Endings))
+		for _, ending := range e.Endings {
+			endingElements, err := playablesToJSON(ending.Elements)
+			if err != nil {
+				return nil, err
+			}
+			endings = append(endings, &EndingJSON{Number: ending.Number, Elements: endingElements})
+		}
+		return &PlayableJSON{
+			Type:     jsonTypeRepeat,
+			ID:       e.ID,
+			Times:    e.Times,
+			Elements: elements,
+			Endings:  endings,
+		}, nil
+
+	case *Barline:
+		return &PlayableJSON{Type: jsonTypeBarline, ID: e.ID, Source: e.Source}, nil
+
+	case *DynamicMark:
+		return &PlayableJSON{Type: jsonTypeDynamic, ID: e.ID, Dynamic: e.Dynamic.String()}, nil
+
+	case *Hairpin:
+		elements, err := playablesToJSON(e.Elements)
+		if err != nil {
+			return nil, err
+		}
+		return &PlayableJSON{
+			Type:     jsonTypeHairpin,
+			ID:       e.ID,
+			Steps:    e.Steps,
+			Elements: elements,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("无法序列化的元素类型: %T", element)
+	}
+}
+
+func playablesToJSON(elements []Playable) ([]*PlayableJSON, error) {
+	result := make([]*PlayableJSON, 0, len(elements))
+	for _, element := range elements {
+		node, err := playableToJSON(element)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, node)
+	}
+	return result, nil
+}
+
+func playableFromJSON(node *PlayableJSON, path string) (Playable, error) {
+	if node == nil {
+		return nil, fmt.Errorf("%s: 元素为空", path)
+	}
+
+	switch node.Type {
+	case jsonTypeNote:
+		if node.Note == nil {
+			return nil, fmt.Errorf("%s: note 缺少 note 字段", path)
+		}
+		note, err := noteFromJSON(*node.Note)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		articulations, err := articulationsFromJSON(node.Articulations)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		element := &NoteElement{ID: node.ID, Note: note, Tie: node.Tie, Articulations: articulations}
+		element.VolumeOverride, element.InstrumentOverride, element.ChannelOverride, err = overridesFromJSON(node.Overrides)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return element, nil
+
+	case jsonTypeChord:
+		notes := make([]core.Note, len(node.Notes))
+		for i, noteJSON := range node.Notes {
+			note, err := noteFromJSON(noteJSON)
+			if err != nil {
+				return nil, fmt.Errorf("%s.notes[%d]: %v", path, i, err)
+			}
+			notes[i] = note
+		}
+		articulations, err := articulationsFromJSON(node.Articulations)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		element := &ChordElement{ID: node.ID, Chord: core.NewChord(notes), Tie: node.Tie, Inversion: node.Inversion, Drop: node.Drop}
+		element.Articulations = articulations
+		element.VolumeOverride, element.InstrumentOverride, element.ChannelOverride, err = overridesFromJSON(node.Overrides)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return element, nil
+
+	case jsonTypeRest:
+		if node.Rest == nil {
+			return nil, fmt.Errorf("%s: rest 缺少 rest 字段", path)
+		}
+		rest := core.Rest{Beat: core.BeatValue(node.Rest.Beat), Position: node.Rest.Position}
+		return &RestElement{ID: node.ID, Rest: rest}, nil
+
+	case jsonTypeGroup:
+		group := NewGroupElement()
+		group.ID = node.ID
+		if node.Duration != nil {
+			duration := *node.Duration
+			group.duration = &duration
+		}
+		elements, err := playablesFromJSON(node.Elements, path)
+		if err != nil {
+			return nil, err
+		}
+		group.AddElements(elements...)
+		return group, nil
+
+	case jsonTypeSection:
+		section := NewSection(node.Name)
+		section.ID = node.ID
+		params, err := containerParamsFromJSON(node.Params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		section.ContainerParams = params
+		elements, err := playablesFromJSON(node.Elements, path)
+		if err != nil {
+			return nil, err
+		}
+		section.Elements = elements
+		return section, nil
+
+	case jsonTypeTrack:
+		track := NewTrack(node.Name)
+		track.ID = node.ID
+		params, err := containerParamsFromJSON(node.Params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		track.ContainerParams = params
+		elements, err := playablesFromJSON(node.Elements, path)
+		if err != nil {
+			return nil, err
+		}
+		track.Elements = elements
+		return track, nil
+
+	case jsonTypeTransform:
+		if len(node.Elements) != 1 {
+			return nil, fmt.Errorf("%s: transform 需要恰好一个子元素，得到 %d 个", path, len(node.Elements))
+		}
+		if node.TimeScale < 0 || node.VelocityScale < 0 {
+			return nil, fmt.Errorf("%s: transform 的缩放比例不能为负数", path)
+		}
+		child, err := playableFromJSON(node.Elements[0], path+".elements[0]")
+		if err != nil {
+			return nil, err
+		}
+		transform := NewTransform(child)
+		transform.ID = node.ID
+		transform.Transpose = node.Transpose
+		transform.TimeScale = node.TimeScale
+		transform.VelocityScale = node.VelocityScale
+		return transform, nil
+
+	case jsonTypeRepeat:
+		if node.Times < 1 {
+			return nil, fmt.Errorf("%s: 反复次数无效: %d", path, node.Times)
+		}
+		repeat := NewRepeat(node.Times)
+		repeat.ID = node.ID
+		elements, err := playablesFromJSON(node.Elements, path)
+		if err != nil {
+			return nil, err
+		}
+		repeat.Elements = elements
+		for i, ending := range node.Endings {
+			if ending == nil {
+				return nil, fmt.Errorf("%s.endings[%d]: 结尾为空", path, i)
+			}
+			endingElements, err := playablesFromJSON(ending.Elements, fmt.Sprintf("%s.endings[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			repeat.AddEnding(ending.Number, endingElements)
+		}
+		return repeat, nil
+
+	case jsonTypeBarline:
+		return &Barline{ID: node.ID, Source: node.Source}, nil
+
+	case jsonTypeDynamic:
+		dynamic, err := core.ParseDynamic(node.Dynamic)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return &DynamicMark{ID: node.ID, Dynamic: dynamic}, nil
+
+	case jsonTypeHairpin:
+		if node.Steps == 0 {
+			return nil, fmt.Errorf("%s: hairpin 缺少 steps 字段", path)
+		}
+		hairpin := NewHairpin(node.Steps)
+		hairpin.ID = node.ID
+		elements, err := playablesFromJSON(node.Elements, path)
+		if err != nil {
+			return nil, err
+		}
+		hairpin.Elements = elements
+		return hairpin, nil
+
+	default:
+		return nil, fmt.Errorf("%s: 未知元素类型 %q", path, node.Type)
+	}
+}
+
+func playablesFromJSON(nodes []*PlayableJSON, path string) ([]Playable, error) {
+	elements := make([]Playable, 0, len(nodes))
+	for i, node := range nodes {
+		element, err := playableFromJSON(node, fmt.Sprintf("%s.elements[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+func noteToJSON(note core.Note) NoteJSON {
+	midiNote := make([]int, len(note.MIDINote))
+	for i, value := range note.MIDINote {
+		midiNote[i] = int(value)
+	}
+
+	return NoteJSON{
+		Name:       note.Name.String(),
+		Octave:     note.Octave,
+		Accidental: int(note.Accidental),
+		FollowKey:  note.FollowKey,
+		MIDINote:   midiNote,
+		Beat:       float64(note.Beat),
+		TrackID:    note.TrackID,
+		Channel:    note.Channel,
+		Instrument: note.Instrument,
+		Velocity:   note.Velocity,
+	}
+}
+
+func noteFromJSON(noteJSON NoteJSON) (core.Note, error) {
+	name, ok := parseBaseNoteName(noteJSON.Name)
+	if !ok {
+		return core.Note{}, fmt.Errorf("无效的音符名: %q", noteJSON.Name)
+	}
+
+	if len(noteJSON.MIDINote) == 0 {
+		return core.Note{}, fmt.Errorf("音符 %s%d 缺少 midi 字段", noteJSON.Name, noteJSON.Octave)
+	}
+
+	midiNote := make([]byte, len(noteJSON.MIDINote))
+	for i, value := range noteJSON.MIDINote {
+		if value < 0 || value > 255 {
+			return core.Note{}, fmt.Errorf("MIDI音符值无效: %d", value)
+		}
+		midiNote[i] = byte(value)
+	}
+
+	return core.Note{
+		Name:       name,
+		Octave:     noteJSON.Octave,
+		Accidental: core.Accidental(noteJSON.Accidental),
+		FollowKey:  noteJSON.FollowKey,
+		MIDINote:   midiNote,
+		Beat:       core.BeatValue(noteJSON.Beat),
+		TrackID:    noteJSON.TrackID,
+		Channel:    noteJSON.Channel,
+		Instrument: noteJSON.Instrument,
+		Velocity:   noteJSON.Velocity,
+	}, nil
+}
+
+func articulationsToJSON(articulations []core.Articulation) []string {
+	if len(articulations) == 0 {
+		return nil
+	}
+	names := make([]string, len(articulations))
+	for i, articulation := range articulations {
+		names[i] = articulation.String()
+	}
+	return names
+}
+
+func articulationsFromJSON(names []string) ([]core.Articulation, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	articulations := make([]core.Articulation, len(names))
+	for i, name := range names {
+		articulation, err := core.ParseArticulation(name)
+		if err != nil {
+			return nil, err
+		}
+		articulations[i] = articulation
+	}
+	return articulations, nil
+}
+
+func parseBaseNoteName(name string) (core.BaseNoteName, bool) {
+	for n := core.C; n <= core.B; n++ {
+		if n.String() == name {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func containerParamsToJSON(params ContainerParams) *ParamsJSON {
+	if params.BPM == nil && params.BPMRamp == nil && params.Volume == nil && params.Instrument == nil && params.Channel == nil && params.Voicing == nil && params.Key == nil && params.Dynamics == nil {
+		return nil
+	}
+
+	result := &ParamsJSON{BPM: params.BPM, BPMRamp: params.BPMRamp, Volume: params.Volume, Channel: params.Channel}
+	if params.Instrument != nil {
+		instrument := int(*params.Instrument)
+		result.Instrument = &instrument
+	}
+	if params.Voicing != nil {
+		result.Voicing = params.Voicing.String()
+	}
+	if params.Key != nil {
+		result.Key = params.Key.String()
+	}
+	if params.Dynamics != nil {
+		result.Dynamics = params.Dynamics.String()
+	}
+	return result
+}
+
+func containerParamsFromJSON(params *ParamsJSON) (ContainerParams, error) {
+	if params == nil {
+		return ContainerParams{}, nil
+	}
+
+	result := ContainerParams{BPM: params.BPM, BPMRamp: params.BPMRamp, Volume: params.Volume, Channel: params.Channel}
+	if params.Instrument != nil {
+		instrument := core.InstrumentID(*params.Instrument)
+		result.Instrument = &instrument
+	}
+	if params.Voicing != "" {
+		voicing, err := core.ParseVoicing(params.Voicing)
+		if err != nil {
+			return ContainerParams{}, err
+		}
+		result.Voicing = &voicing
+	}
+	if params.Key != "" {
+		key, err := core.ParseKey(params.Key)
+		if err != nil {
+			return ContainerParams{}, err
+		}
+		result.Key = &key
+	}
+	if params.Dynamics != "" {
+		mode, err := core.ParseDynamicsMode(params.Dynamics)
+		if err != nil {
+			return ContainerParams{}, err
+		}
+		result.Dynamics = &mode
+	}
+	return result, nil
+}
+
+func overridesToJSON(volume *int, instrument *core.InstrumentID, channel *int) *ParamsJSON {
+	return containerParamsToJSON(ContainerParams{Volume: volume, Instrument: instrument, Channel: channel})
+}
+
+// 元素只能覆盖音量、乐器和通道，写了其他参数时报错而不是忽略
+func overridesFromJSON(params *ParamsJSON) (*int, *core.InstrumentID, *int, error) {
+	if params == nil {
+		return nil, nil, nil, nil
+	}
+	if params.BPM != nil || params.BPMRamp != nil || params.Voicing != "" || params.Key != "" || params.Dynamics != "" {
+		return nil, nil, nil, fmt.Errorf("元素的 overrides 只支持 volume、instrument、channel")
+	}
+	overrides, err := containerParamsFromJSON(params)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return overrides.Volume, overrides.Instrument, overrides.Channel, nil
+}
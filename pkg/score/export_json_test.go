@@ -0,0 +1,162 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 覆盖各种元素类型和容器参数的乐谱，用来检查 JSON 往返
+func roundTripScore(t *testing.T) *Score {
+	t.Helper()
+	key, err := core.ParseKey("Eb_major")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meter, err := core.ParseTimeSignature("3/4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accented := noteElement(core.E, 4, 1)
+	accented.Articulations = []core.Articulation{core.Accent, core.Staccato}
+	accented.SetVolumeOverride(70)
+	accented.SetChannelOverride(3)
+
+	followed := testNote(core.A, 4, 1)
+	followed.FollowKey = true
+
+	chord := NewChordElement(core.NewChord([]core.Note{testNote(core.C, 4, 2), testNote(core.E, 4, 2), testNote(core.G, 4, 2), testNote(core.B, 4, 2)}))
+	chord.Inversion = 1
+	chord.Drop = 2
+	chord.Articulations = []core.Articulation{core.Fermata}
+
+	transform := NewTransform(sectionOf("motif", noteElement(core.C, 4, 0.5), noteElement(core.D, 4, 0.5)))
+	transform.Transpose = 5
+	transform.TimeScale = 2
+	transform.VelocityScale = 0.8
+
+	hairpin := NewHairpin(2)
+	for _, name := range []core.BaseNoteName{core.C, core.D, core.E} {
+		hairpin.AddElement(noteElement(name, 4, 1))
+	}
+
+	melody := sectionOf("melody",
+		tiedNote(core.C, 4, 1), noteElement(core.C, 4, 2), NewBarline("1:1"),
+		accented, NewNoteElement(followed), restElement(1), NewBarline("1:2"),
+		groupOf(1, noteElement(core.C, 4, 0.5), noteElement(core.D, 4, 0.5), noteElement(core.E, 4, 0.5)),
+		repeatOf(2, []Playable{noteElement(core.F, 4, 1)}, map[int][]Playable{1: {noteElement(core.G, 4, 1)}, 2: {noteElement(core.A, 4, 1)}}),
+		transform, NewDynamicMark(core.DynamicP), hairpin, NewDynamicMark(core.DynamicF))
+	melody.SetTempoRamp(80, 120, 4)
+	melody.SetVolume(90)
+
+	harmony := sectionOf("harmony", chord, noteElement(core.G, 3, 4))
+	harmony.SetVoicing(core.OpenVoicing)
+	harmony.SetInstrument(33)
+	harmony.SetChannel(1)
+	harmony.SetDynamicsMode(core.ExpressionDynamics)
+
+	root := NewTrack("band")
+	root.AddElement(melody)
+	root.AddElement(harmony)
+	root.SetBPM(100)
+
+	scoreObj := scoreOf(root)
+	scoreObj.Composer = "test"
+	scoreObj.Key = key
+	scoreObj.Time = meter
+	return scoreObj
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	original := roundTripScore(t)
+	data, err := original.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ParseScoreJSON(data)
+	if err != nil {
+		t.Fatalf("读取导出的 JSON 失败: %v\n%s", err, data)
+	}
+
+	// 再次导出的内容应与第一次完全相同
+	again, err := loaded.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("再次导出的 JSON 不同:\n%s\n期望:\n%s", again, data)
+	}
+
+	originalEngine, loadedEngine := NewPlayEngine(original), NewPlayEngine(loaded)
+	want, err := originalEngine.GenerateEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadedEngine.GenerateEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("得到 %d 个事件，期望 %d 个", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Fatalf("第 %d 个事件为 %+v，期望 %+v", i, got[i], want[i])
+		}
+	}
+	if got, want := loadedEngine.TempoMap().Changes(), originalEngine.TempoMap().Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("速度表为 %v，期望 %v", got, want)
+	}
+}
+
+func TestParseScoreJSONErrors(t *testing.T) {
+	note := `{"type":"note","note":{"name":"C","octave":4,"midi":[108],"beat":1}}`
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"版本过高", fmt.Sprintf(`{"version":%d,"root":%s}`, JSONSchemaVersion+1, note), fmt.Sprintf("不支持的JSON格式版本: %d", JSONSchemaVersion+1)},
+		{"版本为0", `{"version":0,"root":` + note + `}`, "不支持的JSON格式版本: 0"},
+		{"未知字段", `{"version":10,"tempo":120,"root":` + note + `}`, "JSON解析失败"},
+		{"元素覆盖速度", `{"version":10,"root":{"type":"note","note":{"name":"C","octave":4,"midi":[108],"beat":1},"overrides":{"bpm":90}}}`,
+			"元素的 overrides 只支持 volume、instrument、channel"},
+		{"音符缺少MIDI值", `{"version":10,"root":{"type":"note","note":{"name":"C","octave":4,"beat":1}}}`, "缺少 midi 字段"},
+		{"未知元素类型", `{"version":10,"root":{"type":"glissando"}}`, "glissando"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScoreJSON([]byte(tt.data))
+			if err == nil {
+				t.Fatalf("期望错误 %q，实际没有错误", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误 %q 中没有 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScoreJSONOldVersion(t *testing.T) {
+	// 版本 1 的文档没有后来加入的字段，按默认值读取
+	data := `{"version":1,"title":"old","bpm":90,"volume":100,"root":{"type":"section","name":"s","elements":[
+		{"type":"note","note":{"name":"C","octave":4,"midi":[108],"beat":1}},
+		{"type":"rest","rest":{"beat":1}},
+		{"type":"note","note":{"name":"D","octave":4,"midi":[110],"beat":2}}]}}`
+
+	scoreObj, err := ParseScoreJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scoreObj.BPM != 90 {
+		t.Errorf("BPM = %v，期望 90", scoreObj.BPM)
+	}
+	if got, want := describeNotes(t, scoreObj), "C4@0/1 D4@2/2"; got != want {
+		t.Errorf("得到 %s，期望 %s", got, want)
+	}
+}
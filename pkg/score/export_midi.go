@@ -0,0 +1,265 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"fmt"
+	"math"
+	"sort"
+
+	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// 默认MIDI分辨率（每四分音符的tick数）
+const DefaultPPQ = 480
+
+// 速度渐变写入MIDI文件时每段的拍数
+const midiTempoStep = 0.25
+
+// 不属于任何轨道的事件所使用的轨道名
+const untitledTrackName = "main"
+
+// MIDI导出：写出Type 1标准MIDI文件
+func (s *Score) exportMIDI(options ExportOptions) error {
+	if options.FileName == "" {
+		return fmt.Errorf("未指定导出文件名")
+	}
+
+	ppq, err := ppqFromOptions(options.Options)
+	if err != nil {
+		return err
+	}
+
+	file, err := s.BuildSMF(ppq)
+	if err != nil {
+		return err
+	}
+
+	if err := file.WriteFile(options.FileName); err != nil {
+		return fmt.Errorf("写入MIDI文件失败: %v", err)
+	}
+	return nil
+}
+
+// 构建标准MIDI文件：第一条为指挥轨，其后每个Track一条MTrk
+func (s *Score) BuildSMF(ppq int) (*smf.SMF, error) {
+	if ppq <= 0 || ppq > math.MaxInt16 {
+		return nil, fmt.Errorf("无效的PPQ: %d", ppq)
+	}
+
+	engine := NewPlayEngine(s)
+	events, err := engine.GenerateEvents()
+	if err != nil {
+		return nil, fmt.Errorf("生成事件失败: %v", err)
+	}
+
+	file := smf.NewSMF1()
+	file.TimeFormat = smf.MetricTicks(ppq)
+
+	// 指挥轨：曲名、拍号和速度表中的每个速度变化
+	var conductor smf.Track
+	meter := core.CommonTime
+	if s.Time != (core.TimeSignature{}) {
+		meter = s.Time
+	}
+	conductor.Add(0, smf.MetaTrackSequenceName(s.Title))
+	conductor.Add(0, smf.MetaMeter(uint8(meter.Beats), uint8(meter.BeatType)))
+	var lastTick uint32
+	for _, change := range engine.TempoMap().Steps(midiTempoStep) {
+		tick := beatsToTicks(change.Beat, ppq)
+		conductor.Add(tick-lastTick, smf.MetaTempo(change.BPM))
+		lastTick = tick
+	}
+	conductor.Close(0)
+	if err := file.Add(conductor); err != nil {
+		return nil, fmt.Errorf("添加指挥轨失败: %v", err)
+	}
+
+	// 按轨道分组事件
+	names := map[string]string{}
+	order := []string{}
+	for _, track := range collectTracks(s.RootElement) {
+		if _, exists := names[track.GetID()]; !exists {
+			names[track.GetID()] = track.Name
+			order = append(order, track.GetID())
+		}
+	}
+
+	grouped := map[string][]Event{}
+	for _, event := range events {
+		grouped[event.Track] = append(grouped[event.Track], event)
+	}
+	if len(grouped[""]) > 0 {
+		names[""] = untitledTrackName
+		order = append([]string{""}, order...)
+	}
+
+	for _, trackID := range order {
+		trackEvents := grouped[trackID]
+		if len(trackEvents) == 0 {
+			continue
+		}
+
+		track, err := buildSMFTrack(names[trackID], trackEvents, ppq)
+		if err != nil {
+			return nil, fmt.Errorf("轨道 %s 转换失败: %v", names[trackID], err)
+		}
+		if err := file.Add(track); err != nil {
+			return nil, fmt.Errorf("添加轨道 %s 失败: %v", names[trackID], err)
+		}
+	}
+
+	return file, nil
+}
+
+// 将一个轨道的事件转换为MTrk
+func buildSMFTrack(name string, events []Event, ppq int) (smf.Track, error) {
+	var track smf.Track
+	track.Add(0, smf.MetaTrackSequenceName(name))
+
+	sorted := make([]Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := beatsToTicks(sorted[i].Time, ppq), beatsToTicks(sorted[j].Time, ppq)
+		if ti != tj {
+			return ti < tj
+		}
+		// 同一tick：控制事件 → NOTE_OFF → NOTE_ON，避免同音高连奏被提前截断
+		return smfActionOrder(sorted[i].Action) < smfActionOrder(sorted[j].Action)
+	})
+
+	var lastTick uint32
+	for _, event := range sorted {
+		msg, err := eventToMIDIMessage(event)
+		if err != nil {
+			return nil, err
+		}
+		if msg == nil {
+			continue
+		}
+
+		tick := beatsToTicks(event.Time, ppq)
+		track.Add(tick-lastTick, msg)
+		lastTick = tick
+	}
+
+	track.Close(0)
+	return track, nil
+}
+
+// 将单个事件转换为MIDI消息，返回nil表示该事件无需写出
+func eventToMIDIMessage(event Event) (midi.Message, error) {
+	channel := uint8(event.Channel)
+
+	switch event.Action {
+	case NOTE_ON:
+		if midiNote, ok := event.Data.(uint8); ok {
+			return midi.NoteOn(channel, midiNote, event.Velocity), nil
+		}
+		return nil, fmt.Errorf("NOTE_ON事件数据类型错误")
+
+	case NOTE_OFF:
+		if midiNote, ok := event.Data.(uint8); ok {
+			return midi.NoteOff(channel, midiNote), nil
+		}
+		return nil, fmt.Errorf("NOTE_OFF事件数据类型错误")
+
+	case VOLUME_CHANGE:
+		if volume, ok := event.Data.(uint8); ok {
+			return midi.ControlChange(channel, 7, volume), nil // CC7 = 主音量
+		}
+		return nil, fmt.Errorf("VOLUME_CHANGE事件数据类型错误")
+
+	case EXPRESSION_CHANGE:
+		if expression, ok := event.Data.(uint8); ok {
+			return midi.ControlChange(channel, 11, expression), nil // CC11 = 表情
+		}
+		return nil, fmt.Errorf("EXPRESSION_CHANGE事件数据类型错误")
+
+	case PROGRAM_CHANGE:
+		if program, ok := event.Data.(core.InstrumentID); ok {
+			// 鼓组由通道决定，不发送程序变更
+			if core.IsDrumKit(program) {
+				return nil, nil
+			}
+			return midi.ProgramChange(channel, core.GetMIDIProgram(program)), nil
+		}
+		return nil, fmt.Errorf("PROGRAM_CHANGE事件数据类型错误")
+
+	default:
+		return nil, nil
+	}
+}
+
+func smfActionOrder(action EventAction) int {
+	switch action {
+	case PROGRAM_CHANGE, VOLUME_CHANGE, EXPRESSION_CHANGE:
+		return 0
+	case NOTE_OFF:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// 拍数转tick，负数时间（如提前的程序变更）归零
+func beatsToTicks(beats float64, ppq int) uint32 {
+	if beats <= 0 {
+		return 0
+	}
+	return uint32(math.Round(beats * float64(ppq)))
+}
+
+func ppqFromOptions(options map[string]interface{}) (int, error) {
+	value, ok := options["ppq"]
+	if !ok {
+		return DefaultPPQ, nil
+	}
+
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("ppq 参数类型错误: %T", value)
+	}
+}
+
+// 按树序收集所有轨道（包括嵌套轨道）
+func collectTracks(element Playable) []*Track {
+	tracks := []*Track{}
+
+	switch e := element.(type) {
+	case *Track:
+		tracks = append(tracks, e)
+		for _, child := range e.Elements {
+			tracks = append(tracks, collectTracks(child)...)
+		}
+	case *Section:
+		for _, child := range e.Elements {
+			tracks = append(tracks, collectTracks(child)...)
+		}
+	case *GroupElement:
+		for _, child := range e.GetElements() {
+			tracks = append(tracks, collectTracks(child)...)
+		}
+	case *Hairpin:
+		for _, child := range e.Elements {
+			tracks = append(tracks, collectTracks(child)...)
+		}
+	case *Transform:
+		tracks = append(tracks, collectTracks(e.Element)...)
+	case *Repeat:
+		for _, child := range e.Elements {
+			tracks = append(tracks, collectTracks(child)...)
+		}
+		for _, ending := range e.Endings {
+			for _, child := range ending.Elements {
+				tracks = append(tracks, collectTracks(child)...)
+			}
+		}
+	}
+
+	return tracks
+}
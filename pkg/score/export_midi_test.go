@@ -0,0 +1,157 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gitlab.com/gomidi/midi/v2/smf"
+)
+
+// 把一条 MTrk 写成 "绝对tick 消息"，用逗号分隔；结束标记省略
+func describeSMFTrack(track smf.Track) string {
+	var parts []string
+	var tick uint32
+	for _, event := range track {
+		tick += event.Delta
+		message := event.Message
+
+		var channel, key, velocity, controller, value, program, num, denom uint8
+		var bpm float64
+		var text string
+		var description string
+		switch {
+		case message.GetMetaTrackName(&text):
+			description = "name:" + text
+		case message.GetMetaMeter(&num, &denom):
+			description = fmt.Sprintf("meter:%d/%d", num, denom)
+		case message.GetMetaTempo(&bpm):
+			description = fmt.Sprintf("tempo:%.4g", bpm)
+		case message.GetNoteStart(&channel, &key, &velocity):
+			description = fmt.Sprintf("on:%d/%d/%d", channel, key, velocity)
+		case message.GetNoteEnd(&channel, &key):
+			description = fmt.Sprintf("off:%d/%d", channel, key)
+		case message.GetControlChange(&channel, &controller, &value):
+			description = fmt.Sprintf("cc:%d/%d/%d", channel, controller, value)
+		case message.GetProgramChange(&channel, &program):
+			description = fmt.Sprintf("program:%d/%d", channel, program)
+		default:
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", tick, description))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func buildSMF(t *testing.T, scoreObj *Score, ppq int) []string {
+	t.Helper()
+	file, err := scoreObj.BuildSMF(ppq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := file.Format(); got != 1 {
+		t.Errorf("格式为 %d，期望 1", got)
+	}
+	if got := file.TimeFormat; got != smf.MetricTicks(ppq) {
+		t.Errorf("时间格式为 %v，期望 %d ppq", got, ppq)
+	}
+
+	tracks := make([]string, len(file.Tracks))
+	for i, track := range file.Tracks {
+		tracks[i] = describeSMFTrack(track)
+	}
+	return tracks
+}
+
+func TestBuildSMFTracks(t *testing.T) {
+	piano := NewTrack("piano")
+	piano.SetInstrument(5)
+	piano.SetVolume(90)
+	// 同音高连奏：同一 tick 先 NOTE_OFF 再 NOTE_ON
+	piano.AddElement(sectionOf("melody", noteElement(core.C, 4, 1), noteElement(core.C, 4, 1)))
+
+	drums := NewTrack("drums")
+	drums.SetInstrument(128)
+	drums.AddElement(sectionOf("beat", restElement(1), noteElement(core.C, 3, 0.5)))
+
+	root := NewTrack("band")
+	root.AddElement(piano)
+	root.AddElement(drums)
+
+	scoreObj := scoreOf(root)
+	scoreObj.Title = "song"
+	meter, err := core.ParseTimeSignature("3/4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scoreObj.Time = meter
+
+	want := []string{
+		"0 name:song, 0 meter:3/4, 0 tempo:120",
+		"0 name:piano, 0 program:1/5, 0 cc:1/7/90, 0 on:1/108/100, 480 off:1/108, 480 on:1/108/100, 960 off:1/108",
+		// 鼓组不发送程序变更，固定使用通道 9
+		"0 name:drums, 480 on:9/96/100, 720 off:9/96",
+	}
+	got := buildSMF(t, scoreObj, 480)
+	if len(got) != len(want) {
+		t.Fatalf("得到 %d 条轨道，期望 %d 条:\n%s", len(got), len(want), strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("第 %d 条轨道:\n得到 %s\n期望 %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBuildSMFUntitledTrack(t *testing.T) {
+	// 不在任何 Track 中的事件写入名为 main 的轨道，默认拍号为 4/4
+	got := buildSMF(t, scoreOf(sectionOf("s", noteElement(core.E, 4, 2))), 96)
+	want := []string{
+		"0 name:test, 0 meter:4/4, 0 tempo:120",
+		"0 name:main, 0 on:1/112/100, 192 off:1/112",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("得到\n%s\n期望\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildSMFTempoMap(t *testing.T) {
+	// 速度渐变按 midiTempoStep 拍分段写入指挥轨，每段的速度使经过的时间与渐变一致；
+	// 段落结束后恢复全曲速度
+	section := sectionOf("s", noteElement(core.C, 4, 1), noteElement(core.D, 4, 1))
+	section.SetTempoRamp(60, 100, 1)
+
+	got := buildSMF(t, scoreOf(section), 480)
+	if want := "0 name:test, 0 meter:4/4, 0 tempo:64.87, 120 tempo:74.89, 240 tempo:84.9, 360 tempo:94.91, 480 tempo:100, 960 tempo:120"; got[0] != want {
+		t.Errorf("指挥轨:\n得到 %s\n期望 %s", got[0], want)
+	}
+}
+
+func TestBuildSMFInvalidPPQ(t *testing.T) {
+	for _, ppq := range []int{0, -1, 40000} {
+		if _, err := scoreOf(sectionOf("s", noteElement(core.C, 4, 1))).BuildSMF(ppq); err == nil {
+			t.Errorf("PPQ %d 应当报错", ppq)
+		}
+	}
+}
+
+func TestPPQFromOptions(t *testing.T) {
+	tests := []struct {
+		options map[string]interface{}
+		want    int
+		wantErr bool
+	}{
+		{nil, DefaultPPQ, false},
+		{map[string]interface{}{"ppq": 960}, 960, false},
+		{map[string]interface{}{"ppq": 240.0}, 240, false},
+		{map[string]interface{}{"ppq": "960"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ppqFromOptions(tt.options)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ppqFromOptions(%v) = %d, %v，期望 %d", tt.options, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,997 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+)
+
+// MusicXML文档头
+const musicXMLDoctype = `<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 4.0 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">` + "\n"
+
+// divisions 上限，无法整除的时值按此精度取整
+const maxXMLDivisions = 10080
+
+// core.NewNote 以MIDI 60作为C0，乐谱八度需减去该偏移
+const midiOctaveOffset = 5
+
+// MusicXML导出：每个Track一个<part>
+func (s *Score) exportXML(options ExportOptions) error {
+	if options.FileName == "" {
+		return fmt.Errorf("未指定导出文件名")
+	}
+
+	data, err := s.ToMusicXML()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(options.FileName, data, 0644); err != nil {
+		return fmt.Errorf("写入MusicXML文件失败: %v", err)
+	}
+	return nil
+}
+
+// 生成MusicXML 4.0 partwise文档
+func (s *Score) ToMusicXML() ([]byte, error) {
+	if s.RootElement == nil {
+		return nil, fmt.Errorf("没有可导出的音乐元素")
+	}
+
+	// 用事件确定每个轨道的乐器和通道
+	engine := NewPlayEngine(s)
+	events, err := engine.GenerateEvents()
+	if err != nil {
+		return nil, fmt.Errorf("生成事件失败: %v", err)
+	}
+
+	builder := newXMLBuilder()
+	builder.context.CurrentKey = s.Key
+	builder.layoutRoot(s.RootElement)
+	if len(builder.parts) == 0 {
+		return nil, fmt.Errorf("没有可导出的音符")
+	}
+
+	timeBeats, timeBeatType := core.CommonTime.Beats, core.CommonTime.BeatType
+	if s.Time != (core.TimeSignature{}) {
+		timeBeats, timeBeatType = s.Time.Beats, s.Time.BeatType
+	}
+	measureLength := float64(timeBeats) * 4 / float64(timeBeatType)
+	divisions := builder.divisions()
+
+	doc := xmlScorePartwise{
+		Version: "4.0",
+		Work:    &xmlWork{Title: s.Title},
+		Identification: &xmlIdentification{
+			Encoding: xmlEncoding{Software: "CatRock"},
+		},
+	}
+	if s.Composer != "" {
+		doc.Identification.Creators = []xmlCreator{{Type: "composer", Value: s.Composer}}
+	}
+
+	totalLength := builder.length()
+	measureCount := int(math.Ceil(totalLength/measureLength - 1e-9))
+	if measureCount < 1 {
+		measureCount = 1
+	}
+
+	for i, part := range builder.parts {
+		partID := fmt.Sprintf("P%d", i+1)
+		instrument, channel := partInstrument(events, part.trackID)
+
+		doc.PartList.ScoreParts = append(doc.PartList.ScoreParts, xmlScorePart{
+			ID:       partID,
+			PartName: part.name,
+			ScoreInstrument: xmlScoreInstrument{
+				ID:   partID + "-I1",
+				Name: core.GetInstrumentName(instrument),
+			},
+			MIDIInstrument: xmlMIDIInstrument{
+				ID:      partID + "-I1",
+				Channel: channel%16 + 1,
+				Program: int(core.GetMIDIProgram(instrument)) + 1,
+			},
+		})
+
+		xmlPartElem := xmlPart{ID: partID}
+		for m := 0; m < measureCount; m++ {
+			measure := xmlMeasure{Number: m + 1}
+
+			if m == 0 {
+				measure.Items = append(measure.Items, xmlAttributes{
+					Divisions: divisions,
+					Key:       newXMLKey(part.key),
+					Time:      xmlTime{Beats: timeBeats, BeatType: timeBeatType},
+					Clef:      part.clef(instrument),
+				})
+				if i == 0 {
+					measure.Items = append(measure.Items, tempoDirection(s.BPM))
+				}
+			}
+
+			start := float64(m) * measureLength
+			measure.Items = append(measure.Items, part.measureItems(start, start+measureLength, divisions)...)
+			xmlPartElem.Measures = append(xmlPartElem.Measures, measure)
+		}
+		doc.Parts = append(doc.Parts, xmlPartElem)
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("MusicXML序列化失败: %v", err)
+	}
+
+	return append([]byte(xml.Header+musicXMLDoctype), body...), nil
+}
+
+// 取轨道第一个程序变更和第一个音符的通道
+func partInstrument(events []Event, trackID string) (core.InstrumentID, int) {
+	instrument := core.InstrumentID(1)
+	channel := -1
+	foundProgram := false
+
+	for _, event := range events {
+		if event.Track != trackID {
+			continue
+		}
+		if event.Action == PROGRAM_CHANGE && !foundProgram {
+			if program, ok := event.Data.(core.InstrumentID); ok {
+				instrument = program
+				foundProgram = true
+			}
+		}
+		if event.Action == NOTE_ON && channel < 0 {
+			channel = event.Channel
+		}
+	}
+
+	if channel < 0 {
+		channel = 0
+	}
+	return instrument, channel
+}
+
+func tempoDirection(bpm float64) xmlDirection {
+	return xmlDirection{
+		Placement: "above",
+		DirectionType: xmlDirectionType{
+			Metronome: &xmlMetronome{BeatUnit: "quarter", PerMinute: fmt.Sprintf("%g", bpm)},
+		},
+		Sound: &xmlSound{Tempo: fmt.Sprintf("%g", bpm)},
+	}
+}
+
+// ---- 布局：把Playable树展开为带绝对时间的记谱条目 ----
+
+// 记谱条目：一个音符、和弦或休止符
+type xmlItem struct {
+	start        float64     // 实际开始时间（拍）
+	duration     float64     // 实际时长（拍）
+	notes        []core.Note // 为空表示休止符
+	actual       int         // 连音：actual个音符占normal个的时间
+	normal       int
+	tupletStarts []int // 在此条目开始的连音组编号
+	tupletStops  []int // 在此条目结束的连音组编号
+	tie          bool  // 以延音线连到下一个条目
+	tieStarts    []int // 连到下一个条目的音高
+	tieStops     []int // 从上一个条目连过来的音高
+
+	articulations []core.Articulation
+
+	dynamic     core.Dynamic // 写在此条目之前的力度记号，0 为没有
+	wedgeStarts []xmlWedge   // 从此条目开始的渐强/渐弱
+	wedgeStops  []int        // 在此条目结束的渐强/渐弱编号
+}
+
+func (item xmlItem) end() float64 {
+	return item.start + item.duration
+}
+
+// 单个声部，按时间顺序排列
+type xmlVoice []xmlItem
+
+type xmlPartLayout struct {
+	trackID string
+	name    string
+	key     core.Key // 第一小节的调号
+	voices  []xmlVoice
+}
+
+type xmlBuilder struct {
+	parts []*xmlPartLayout
+	byID  map[string]*xmlPartLayout
+
+	context PlayContext // 当前容器与变换的设置 (调号、排列方式、移调、时值缩放)
+
+	pendingDynamic core.Dynamic // 尚未写出的力度记号，标在当前声部的下一个条目上
+	hairpins       int          // 当前嵌套的渐强/渐弱层数，用作 wedge 编号
+}
+
+func newXMLBuilder() *xmlBuilder {
+	return &xmlBuilder{byID: map[string]*xmlPartLayout{}}
+}
+
+func (b *xmlBuilder) part(trackID string, name string) *xmlPartLayout {
+	if part, ok := b.byID[trackID]; ok {
+		return part
+	}
+	part := &xmlPartLayout{trackID: trackID, name: name, key: b.context.CurrentKey}
+	b.byID[trackID] = part
+	b.parts = append(b.parts, part)
+	return part
+}
+
+func (b *xmlBuilder) layoutRoot(root Playable) {
+	if track, ok := root.(*Track); ok {
+		b.layoutTrack(track, 0)
+		return
+	}
+
+	// 根元素不是轨道时，不属于任何轨道的内容归入 main 声部
+	main := b.part("", untitledTrackName)
+	voice := xmlVoice{}
+	b.pendingDynamic = 0
+	b.layout(root, 0, 1, 0, &voice)
+	if voice.hasNotes() {
+		voice.linkTies()
+		main.voices = append(main.voices, voice)
+	}
+
+	if len(main.voices) == 0 {
+		delete(b.byID, "")
+		b.parts = b.parts[1:]
+	}
+}
+
+// 轨道的每个直接子元素为一个并行声部
+func (b *xmlBuilder) layoutTrack(track *Track, start float64) {
+	defer b.enterContainer(track.ContainerParams)()
+	part := b.part(track.GetID(), track.Name)
+
+	// 嵌套轨道结束后，外层声部的力度记号继续有效
+	outerDynamic := b.pendingDynamic
+	defer func() { b.pendingDynamic = outerDynamic }()
+
+	for _, element := range track.Elements {
+		voice := xmlVoice{}
+		b.pendingDynamic = 0
+		b.layout(element, start, 1, 0, &voice)
+		if voice.hasNotes() {
+			voice.linkTies()
+			part.voices = append(part.voices, voice)
+		}
+	}
+}
+
+// 顺序展开元素，返回实际占用的拍数
+func (b *xmlBuilder) layout(element Playable, start float64, scale float64, depth int, voice *xmlVoice) float64 {
+	context := b.context
+
+	switch e := element.(type) {
+	case *NoteElement:
+		duration := e.Duration(context) * scale
+		item := newXMLItem(start, duration, scale, []core.Note{context.CurrentKey.Apply(e.Note).Transpose(context.Transpose)})
+		item.tie = e.Tie
+		item.articulations = e.Articulations
+		item.dynamic = b.takeDynamic()
+		*voice = append(*voice, item)
+		return duration
+
+	case *ChordElement:
+		notes := []core.Note{}
+		for _, note := range e.Voiced(context).Notes {
+			if len(note.MIDINote) > 0 {
+				notes = append(notes, note.Transpose(context.Transpose))
+			}
+		}
+		duration := e.Duration(context) * scale
+		item := newXMLItem(start, duration, scale, notes)
+		item.tie = e.Tie
+		item.articulations = e.Articulations
+		item.dynamic = b.takeDynamic()
+		*voice = append(*voice, item)
+		return duration
+
+	case *RestElement:
+		duration := e.Duration(context) * scale
+		item := newXMLItem(start, duration, scale, nil)
+		item.dynamic = b.takeDynamic()
+		*voice = append(*voice, item)
+		return duration
+
+	case *DynamicMark:
+		b.pendingDynamic = e.Dynamic
+		return 0
+
+	case *Hairpin:
+		b.hairpins++
+		number := b.hairpins
+		defer func() { b.hairpins-- }()
+
+		first := len(*voice)
+		current := start
+		for _, child := range e.Elements {
+			current += b.layout(child, current, scale, depth, voice)
+		}
+
+		if len(*voice) > first {
+			wedge := xmlWedge{Type: "crescendo", Number: number}
+			if e.Steps < 0 {
+				wedge.Type = "diminuendo"
+			}
+			(*voice)[first].wedgeStarts = append((*voice)[first].wedgeStarts, wedge)
+			last := len(*voice) - 1
+			(*voice)[last].wedgeStops = append((*voice)[last].wedgeStops, number)
+		}
+		return current - start
+
+	case *GroupElement:
+		childScale := scale
+		isTuplet := false
+		if e.duration != nil {
+			// 原始总时长不含外层的时值缩放
+			unscaled := context
+			unscaled.TimeScale = 1
+			original := 0.0
+			for _, child := range e.elements {
+				original += child.Duration(unscaled)
+			}
+			if original > 0 && math.Abs(*e.duration-original) > 1e-9 {
+				childScale = scale * (*e.duration / original)
+				isTuplet = true
+			}
+		}
+
+		first := len(*voice)
+		current := start
+		for _, child := range e.elements {
+			current += b.layout(child, current, childScale, depth+1, voice)
+		}
+
+		if isTuplet && len(*voice) > first {
+			(*voice)[first].tupletStarts = append((*voice)[first].tupletStarts, depth+1)
+			last := len(*voice) - 1
+			(*voice)[last].tupletStops = append((*voice)[last].tupletStops, depth+1)
+		}
+		return current - start
+
+	case *Section:
+		defer b.enterContainer(e.ContainerParams)()
+		current := start
+		for _, child := range e.Elements {
+			current += b.layout(child, current, scale, depth, voice)
+		}
+		return current - start
+
+	case *Transform:
+		// 变换的时值缩放不是连音，通过上下文作用在子元素的时长上
+		outer := b.context
+		b.context = e.childContext(b.context)
+		defer func() { b.context = outer }()
+		return b.layout(e.Element, start, scale, depth, voice)
+
+	case *Repeat:
+		// 反复按展开后的顺序排版
+		current := start
+		for _, child := range e.Unroll() {
+			current += b.layout(child, current, scale, depth, voice)
+		}
+		return current - start
+
+	case *Track:
+		// 嵌套轨道单独成为一个声部，当前声部只占用其时长
+		b.layoutTrack(e, start)
+		return e.Duration(context) * scale
+
+	default:
+		return element.Duration(context) * scale
+	}
+}
+
+// 取出尚未写出的力度记号
+func (b *xmlBuilder) takeDynamic() core.Dynamic {
+	dynamic := b.pendingDynamic
+	b.pendingDynamic = 0
+	return dynamic
+}
+
+// 进入容器时应用其设置，返回恢复外层设置的函数
+func (b *xmlBuilder) enterContainer(params ContainerParams) func() {
+	outer := b.context
+	b.context = b.context.WithContainerSettings(params)
+	return func() { b.context = outer }
+}
+
+func newXMLItem(start float64, duration float64, scale float64, notes []core.Note) xmlItem {
+	item := xmlItem{start: start, duration: duration, notes: notes, actual: 1, normal: 1}
+	if math.Abs(scale-1) > 1e-9 {
+		item.actual, item.normal = tupletRatio(scale)
+	}
+	return item
+}
+
+// 将缩放比例转换为连音比例：actual个音符占normal个的时间
+func tupletRatio(scale float64) (int, int) {
+	for actual := 2; actual <= 32; actual++ {
+		normal := math.Round(scale * float64(actual))
+		if normal > 0 && math.Abs(normal/float64(actual)-scale) < 1e-6 {
+			return actual, int(normal)
+		}
+	}
+	return 1, 1
+}
+
+// 为显式延音线标记首尾：只连接紧邻的下一个条目中相同的音高
+func (v xmlVoice) linkTies() {
+	for i := 0; i+1 < len(v); i++ {
+		current, next := &v[i], &v[i+1]
+		if !current.tie || math.Abs(current.end()-next.start) > 1e-9 {
+			continue
+		}
+		for _, note := range current.notes {
+			for _, other := range next.notes {
+				if note.MIDINote[0] == other.MIDINote[0] {
+					current.tieStarts = append(current.tieStarts, int(note.MIDINote[0]))
+					next.tieStops = append(next.tieStops, int(note.MIDINote[0]))
+				}
+			}
+		}
+	}
+}
+
+func containsPitch(pitches []int, pitch byte) bool {
+	for _, p := range pitches {
+		if p == int(pitch) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v xmlVoice) hasNotes() bool {
+	for _, item := range v {
+		if len(item.notes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *xmlBuilder) length() float64 {
+	length := 0.0
+	for _, part := range b.parts {
+		for _, voice := range part.voices {
+			for _, item := range voice {
+				length = math.Max(length, item.end())
+			}
+		}
+	}
+	return length
+}
+
+// 选择能整除所有时值的最小divisions
+func (b *xmlBuilder) divisions() int {
+	divisions := 1
+	for _, part := range b.parts {
+		for _, voice := range part.voices {
+			for _, item := range voice {
+				for _, value := range []float64{item.start, item.duration} {
+					divisions = lcm(divisions, denominatorOf(value))
+					if divisions >= maxXMLDivisions {
+						return maxXMLDivisions
+					}
+				}
+			}
+		}
+	}
+	return divisions
+}
+
+func denominatorOf(value float64) int {
+	for den := 1; den < maxXMLDivisions; den++ {
+		scaled := value * float64(den)
+		if math.Abs(scaled-math.Round(scaled)) < 1e-6 {
+			return den
+		}
+	}
+	return maxXMLDivisions
+}
+
+func lcm(a, b int) int {
+	x, y := a, b
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return a / x * b
+}
+
+// 按平均音高选择谱号
+func (p *xmlPartLayout) clef(instrument core.InstrumentID) xmlClef {
+	if core.IsDrumKit(instrument) {
+		return xmlClef{Sign: "percussion"}
+	}
+
+	total, count := 0, 0
+	for _, voice := range p.voices {
+		for _, item := range voice {
+			for _, note := range item.notes {
+				_, _, octave := notePitch(note)
+				total += octave*12 + int(note.MIDINote[0])%12
+				count++
+			}
+		}
+	}
+
+	if count > 0 && total/count < 48 {
+		return xmlClef{Sign: "F", Line: 4}
+	}
+	return xmlClef{Sign: "G", Line: 2}
+}
+
+// 生成一个小节内所有声部的内容
+func (p *xmlPartLayout) measureItems(start float64, end float64, divisions int) []interface{} {
+	items := []interface{}{}
+	position := start
+
+	for index, voice := range p.voices {
+		voiceNumber := index + 1
+		inMeasure := []xmlItem{}
+		for _, item := range voice {
+			if item.end() > start+1e-9 && item.start < end-1e-9 {
+				inMeasure = append(inMeasure, item)
+			}
+		}
+
+		// 第一个声部总是填满整个小节，其他声部只在有内容时输出
+		if index > 0 && len(inMeasure) == 0 {
+			continue
+		}
+
+		if position > start+1e-9 {
+			items = append(items, xmlBackup{Duration: toDivisions(position-start, divisions)})
+		}
+
+		cursor := start
+		for _, item := range inMeasure {
+			pieceStart := math.Max(item.start, start)
+			pieceEnd := math.Min(item.end(), end)
+
+			if pieceStart > cursor+1e-9 {
+				items = append(items, restNotes(cursor, pieceStart, voiceNumber, divisions)...)
+			}
+
+			if math.Abs(pieceStart-item.start) < 1e-9 {
+				items = append(items, item.startDirections(voiceNumber)...)
+			}
+			items = append(items, itemNotes(item, pieceStart, pieceEnd, voiceNumber, divisions)...)
+			if math.Abs(pieceEnd-item.end()) < 1e-9 {
+				items = append(items, item.stopDirections(voiceNumber)...)
+			}
+			cursor = pieceEnd
+		}
+
+		if cursor < end-1e-9 {
+			items = append(items, restNotes(cursor, end, voiceNumber, divisions)...)
+		}
+		position = end
+	}
+
+	return items
+}
+
+// 条目之前的力度记号和渐强/渐弱开始
+func (item xmlItem) startDirections(voice int) []interface{} {
+	result := []interface{}{}
+	if item.dynamic != 0 {
+		result = append(result, xmlDirection{
+			Placement:     "below",
+			DirectionType: xmlDirectionType{Dynamics: &xmlDynamics{Mark: "<" + item.dynamic.String() + "/>"}},
+			Voice:         voice,
+		})
+	}
+	for _, wedge := range item.wedgeStarts {
+		wedge := wedge
+		result = append(result, xmlDirection{
+			Placement:     "below",
+			DirectionType: xmlDirectionType{Wedge: &wedge},
+			Voice:         voice,
+		})
+	}
+	return result
+}
+
+// 条目之后的渐强/渐弱结束
+func (item xmlItem) stopDirections(voice int) []interface{} {
+	result := []interface{}{}
+	for _, number := range item.wedgeStops {
+		result = append(result, xmlDirection{
+			Placement:     "below",
+			DirectionType: xmlDirectionType{Wedge: &xmlWedge{Type: "stop", Number: number}},
+			Voice:         voice,
+		})
+	}
+	return result
+}
+
+// 填充空白的休止符
+func restNotes(start float64, end float64, voice int, divisions int) []interface{} {
+	gap := xmlItem{start: start, duration: end - start, actual: 1, normal: 1}
+	return itemNotes(gap, start, end, voice, divisions)
+}
+
+// 把条目在[pieceStart, pieceEnd)内的部分转换为<note>，必要时拆分并加连音线
+func itemNotes(item xmlItem, pieceStart float64, pieceEnd float64, voice int, divisions int) []interface{} {
+	ratio := float64(item.normal) / float64(item.actual)
+	pieces := splitNoteValue((pieceEnd - pieceStart) / ratio)
+
+	result := []interface{}{}
+	current := pieceStart
+	for i, piece := range pieces {
+		actualDuration := piece.beats * ratio
+		tieFromPrevious := current > item.start+1e-9
+		tieToNext := i < len(pieces)-1 || pieceEnd < item.end()-1e-9
+
+		first := i == 0 && math.Abs(pieceStart-item.start) < 1e-9
+		last := i == len(pieces)-1 && math.Abs(pieceEnd-item.end()) < 1e-9
+
+		base := xmlNote{
+			Duration: toDivisions(actualDuration, divisions),
+			Voice:    voice,
+			Type:     piece.typeName,
+		}
+		for d := 0; d < piece.dots; d++ {
+			base.Dots = append(base.Dots, xmlEmpty{})
+		}
+		if item.actual != item.normal {
+			base.TimeModification = &xmlTimeModification{ActualNotes: item.actual, NormalNotes: item.normal}
+		}
+
+		notations := xmlNotations{}
+		if first {
+			for _, number := range item.tupletStarts {
+				notations.Tuplets = append(notations.Tuplets, xmlTuplet{Type: "start", Number: number})
+			}
+		}
+		if last {
+			for _, number := range item.tupletStops {
+				notations.Tuplets = append(notations.Tuplets, xmlTuplet{Type: "stop", Number: number})
+			}
+		}
+		// 演奏法标在延音的第一个音符上
+		if first && len(item.tieStops) == 0 && len(item.articulations) > 0 {
+			notations.Articulations = xmlArticulationsOf(item.articulations)
+			if core.ArticulationHold(item.articulations) > 1 {
+				notations.Fermata = &xmlEmpty{}
+			}
+		}
+
+		if len(item.notes) == 0 {
+			note := base
+			note.Rest = &xmlEmpty{}
+			if len(notations.Tuplets) > 0 {
+				note.Notations = &notations
+			}
+			result = append(result, note)
+		} else {
+			for n, source := range item.notes {
+				note := base
+				if n > 0 {
+					note.Chord = &xmlEmpty{}
+				}
+				step, alter, octave := notePitch(source)
+				note.Pitch = &xmlPitch{Step: step, Alter: alter, Octave: octave}
+
+				noteNotations := notations
+				if tieFromPrevious || (first && containsPitch(item.tieStops, source.MIDINote[0])) {
+					note.Ties = append(note.Ties, xmlTie{Type: "stop"})
+					noteNotations.Tied = append(noteNotations.Tied, xmlTie{Type: "stop"})
+				}
+				if tieToNext || (last && containsPitch(item.tieStarts, source.MIDINote[0])) {
+					note.Ties = append(note.Ties, xmlTie{Type: "start"})
+					noteNotations.Tied = append(noteNotations.Tied, xmlTie{Type: "start"})
+				}
+				if len(noteNotations.Tied) > 0 || len(noteNotations.Tuplets) > 0 || noteNotations.Articulations != nil || noteNotations.Fermata != nil {
+					note.Notations = &noteNotations
+				}
+				result = append(result, note)
+			}
+		}
+
+		current += actualDuration
+	}
+
+	return result
+}
+
+// 可记谱的时值（以四分音符为1拍）
+type noteValue struct {
+	beats    float64
+	typeName string
+	dots     int
+}
+
+var noteValues = []noteValue{
+	{4, "whole", 0},
+	{3, "half", 1},
+	{2, "half", 0},
+	{1.5, "quarter", 1},
+	{1, "quarter", 0},
+	{0.75, "eighth", 1},
+	{0.5, "eighth", 0},
+	{0.375, "16th", 1},
+	{0.25, "16th", 0},
+	{0.1875, "32nd", 1},
+	{0.125, "32nd", 0},
+	{0.0625, "64th", 0},
+}
+
+// 把时值拆分为可记谱的音符时值之和
+func splitNoteValue(beats float64) []noteValue {
+	pieces := []noteValue{}
+	remaining := beats
+
+	for remaining > 1e-6 {
+		found := false
+		for _, value := range noteValues {
+			if value.beats <= remaining+1e-6 {
+				pieces = append(pieces, value)
+				remaining -= value.beats
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			// 比64分音符还短的余量并入最后一个音符
+			if len(pieces) == 0 {
+				pieces = append(pieces, noteValue{remaining, "64th", 0})
+			} else {
+				pieces[len(pieces)-1].beats += remaining
+			}
+			break
+		}
+	}
+
+	return pieces
+}
+
+func toDivisions(beats float64, divisions int) int {
+	return int(math.Round(beats * float64(divisions)))
+}
+
+// 各音名对应的记谱音级和升降
+var noteSpellings = map[core.BaseNoteName]struct {
+	step  string
+	alter int
+}{
+	core.C: {"C", 0}, core.Cs: {"C", 1}, core.D: {"D", 0}, core.Ds: {"D", 1},
+	core.E: {"E", 0}, core.F: {"F", 0}, core.Fs: {"F", 1}, core.G: {"G", 0},
+	core.Gs: {"G", 1}, core.A: {"A", 0}, core.As: {"A", 1}, core.B: {"B", 0},
+}
+
+var stepPitchClasses = map[string]int{"C": 0, "D": 2, "E": 4, "F": 5, "G": 7, "A": 9, "B": 11}
+
+// 音符的记谱拼写：优先使用音符自带的音名和变音，与音高不符时按升号拼写
+func notePitch(note core.Note) (string, int, int) {
+	midiNote := int(note.MIDINote[0])
+	pitchClass := midiNote % 12
+
+	spelling, ok := noteSpellings[note.Name]
+	if ok {
+		alter := spelling.alter + int(note.Accidental)
+		if ((stepPitchClasses[spelling.step]+alter)%12+12)%12 == pitchClass {
+			octave := (midiNote-alter-stepPitchClasses[spelling.step])/12 - midiOctaveOffset
+			return spelling.step, alter, octave
+		}
+	}
+
+	fallback := noteSpellings[core.BaseNoteName(pitchClass)]
+	octave := (midiNote-fallback.alter-stepPitchClasses[fallback.step])/12 - midiOctaveOffset
+	return fallback.step, fallback.alter, octave
+}
+
+// ---- MusicXML 元素 ----
+
+type xmlScorePartwise struct {
+	XMLName        xml.Name           `xml:"score-partwise"`
+	Version        string             `xml:"version,attr"`
+	Work           *xmlWork           `xml:"work,omitempty"`
+	Identification *xmlIdentification `xml:"identification,omitempty"`
+	PartList       xmlPartList        `xml:"part-list"`
+	Parts          []xmlPart          `xml:"part"`
+}
+
+type xmlWork struct {
+	Title string `xml:"work-title"`
+}
+
+type xmlIdentification struct {
+	Creators []xmlCreator `xml:"creator"`
+	Encoding xmlEncoding  `xml:"encoding"`
+}
+
+type xmlCreator struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlEncoding struct {
+	Software string `xml:"software"`
+}
+
+type xmlPartList struct {
+	ScoreParts []xmlScorePart `xml:"score-part"`
+}
+
+type xmlScorePart struct {
+	ID              string             `xml:"id,attr"`
+	PartName        string             `xml:"part-name"`
+	ScoreInstrument xmlScoreInstrument `xml:"score-instrument"`
+	MIDIInstrument  xmlMIDIInstrument  `xml:"midi-instrument"`
+}
+
+type xmlScoreInstrument struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"instrument-name"`
+}
+
+type xmlMIDIInstrument struct {
+	ID      string `xml:"id,attr"`
+	Channel int    `xml:"midi-channel"`
+	Program int    `xml:"midi-program"`
+}
+
+type xmlPart struct {
+	ID       string       `xml:"id,attr"`
+	Measures []xmlMeasure `xml:"measure"`
+}
+
+type xmlMeasure struct {
+	Number int           `xml:"number,attr"`
+	Items  []interface{} // xmlAttributes / xmlDirection / xmlNote / xmlBackup，按顺序输出
+}
+
+type xmlAttributes struct {
+	XMLName   xml.Name `xml:"attributes"`
+	Divisions int      `xml:"divisions"`
+	Key       xmlKey   `xml:"key"`
+	Time      xmlTime  `xml:"time"`
+	Clef      xmlClef  `xml:"clef"`
+}
+
+type xmlKey struct {
+	Fifths int    `xml:"fifths"`
+	Mode   string `xml:"mode,omitempty"`
+}
+
+func newXMLKey(key core.Key) xmlKey {
+	if key.Minor {
+		return xmlKey{Fifths: key.Fifths, Mode: "minor"}
+	}
+	return xmlKey{Fifths: key.Fifths, Mode: "major"}
+}
+
+type xmlTime struct {
+	Beats    int `xml:"beats"`
+	BeatType int `xml:"beat-type"`
+}
+
+type xmlClef struct {
+	Sign string `xml:"sign"`
+	Line int    `xml:"line,omitempty"`
+}
+
+type xmlDirection struct {
+	XMLName       xml.Name         `xml:"direction"`
+	Placement     string           `xml:"placement,attr,omitempty"`
+	DirectionType xmlDirectionType `xml:"direction-type"`
+	Voice         int              `xml:"voice,omitempty"`
+	Sound         *xmlSound        `xml:"sound,omitempty"`
+}
+
+type xmlDirectionType struct {
+	Metronome *xmlMetronome `xml:"metronome,omitempty"`
+	Dynamics  *xmlDynamics  `xml:"dynamics,omitempty"`
+	Wedge     *xmlWedge     `xml:"wedge,omitempty"`
+}
+
+// 力度记号，如 <mf/>
+type xmlDynamics struct {
+	Mark string `xml:",innerxml"`
+}
+
+type xmlWedge struct {
+	Type   string `xml:"type,attr"`
+	Number int    `xml:"number,attr,omitempty"`
+}
+
+type xmlMetronome struct {
+	BeatUnit  string `xml:"beat-unit"`
+	PerMinute string `xml:"per-minute"`
+}
+
+type xmlSound struct {
+	Tempo string `xml:"tempo,attr,omitempty"`
+}
+
+type xmlBackup struct {
+	XMLName  xml.Name `xml:"backup"`
+	Duration int      `xml:"duration"`
+}
+
+type xmlNote struct {
+	XMLName          xml.Name             `xml:"note"`
+	Chord            *xmlEmpty            `xml:"chord,omitempty"`
+	Pitch            *xmlPitch            `xml:"pitch,omitempty"`
+	Rest             *xmlEmpty            `xml:"rest,omitempty"`
+	Duration         int                  `xml:"duration"`
+	Ties             []xmlTie             `xml:"tie"`
+	Voice            int                  `xml:"voice"`
+	Type             string               `xml:"type"`
+	Dots             []xmlEmpty           `xml:"dot"`
+	TimeModification *xmlTimeModification `xml:"time-modification,omitempty"`
+	Notations        *xmlNotations        `xml:"notations,omitempty"`
+}
+
+type xmlEmpty struct{}
+
+type xmlPitch struct {
+	Step   string `xml:"step"`
+	Alter  int    `xml:"alter,omitempty"`
+	Octave int    `xml:"octave"`
+}
+
+type xmlTie struct {
+	Type string `xml:"type,attr"`
+}
+
+type xmlTimeModification struct {
+	ActualNotes int `xml:"actual-notes"`
+	NormalNotes int `xml:"normal-notes"`
+}
+
+type xmlNotations struct {
+	Tied          []xmlTie          `xml:"tied"`
+	Tuplets       []xmlTuplet       `xml:"tuplet"`
+	Articulations *xmlArticulations `xml:"articulations,omitempty"`
+	Fermata       *xmlEmpty         `xml:"fermata,omitempty"`
+}
+
+type xmlArticulations struct {
+	Accent        *xmlEmpty `xml:"accent,omitempty"`
+	StrongAccent  *xmlEmpty `xml:"strong-accent,omitempty"`
+	Staccato      *xmlEmpty `xml:"staccato,omitempty"`
+	Tenuto        *xmlEmpty `xml:"tenuto,omitempty"`
+	Staccatissimo *xmlEmpty `xml:"staccatissimo,omitempty"`
+}
+
+// 演奏法标记，没有需要写在 articulations 中的演奏法时为 nil (延长记号单独写在 notations 中)
+func xmlArticulationsOf(articulations []core.Articulation) *xmlArticulations {
+	result := &xmlArticulations{}
+	for _, articulation := range articulations {
+		switch articulation {
+		case core.Accent:
+			result.Accent = &xmlEmpty{}
+		case core.Marcato:
+			result.StrongAccent = &xmlEmpty{}
+		case core.Tenuto:
+			result.Tenuto = &xmlEmpty{}
+		case core.Staccato:
+			result.Staccato = &xmlEmpty{}
+		case core.Staccatissimo:
+			result.Staccatissimo = &xmlEmpty{}
+		}
+	}
+	if *result == (xmlArticulations{}) {
+		return nil
+	}
+	return result
+}
+
+type xmlTuplet struct {
+	Type   string `xml:"type,attr"`
+	Number int    `xml:"number,attr"`
+}
@@ -0,0 +1,80 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"strings"
+	"testing"
+)
+
+func TestNotePitch(t *testing.T) {
+	spelled := func(name core.BaseNoteName, octave int, accidental core.Accidental) core.Note {
+		return core.NewNote(core.NewNoteParams{Name: name, Octave: octave, Accidental: accidental})
+	}
+	eFlat := spelled(core.E, 4, core.Flat)
+
+	tests := []struct {
+		name   string
+		note   core.Note
+		step   string
+		alter  int
+		octave int
+	}{
+		{"自然音", spelled(core.C, 4, core.Natural), "C", 0, 4},
+		{"降号拼写", eFlat, "E", -1, 4},
+		{"升号拼写", spelled(core.D, 4, core.Sharp), "D", 1, 4},
+		{"Cb 的八度按音级计算", spelled(core.C, 5, core.Flat), "C", -1, 5},
+		{"B# 的八度按音级计算", spelled(core.B, 3, core.Sharp), "B", 1, 3},
+		{"重降", spelled(core.B, 4, core.DoubleFlat), "B", -2, 4},
+		{"按MIDI创建的音符用升号", core.NewNoteFromMIDI(int(eFlat.MIDINote[0]), core.Quarter), "D", 1, 4},
+		{"拼写与音高不符时按升号", core.Note{Name: core.E, Accidental: core.Flat, MIDINote: []byte{eFlat.MIDINote[0] + 1}}, "E", 0, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, alter, octave := notePitch(tt.note)
+			if step != tt.step || alter != tt.alter || octave != tt.octave {
+				t.Errorf("得到 %s %+d 八度%d，期望 %s %+d 八度%d", step, alter, octave, tt.step, tt.alter, tt.octave)
+			}
+		})
+	}
+}
+
+func TestMusicXMLKeySpelling(t *testing.T) {
+	key, err := core.ParseKey("Eb_major")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 跟随调号的 A 记为 A 降，显式还原的 E 记为 E 本位
+	followed := testNote(core.A, 4, 1)
+	followed.FollowKey = true
+	natural := testNote(core.E, 4, 1)
+
+	scoreObj := scoreOf(sectionOf("s", NewNoteElement(followed), NewNoteElement(natural)))
+	scoreObj.Key = key
+
+	data, err := scoreObj.ToMusicXML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml := compactXML(string(data))
+
+	for _, want := range []string{
+		"<key><fifths>-3</fifths><mode>major</mode></key>",
+		"<pitch><step>A</step><alter>-1</alter><octave>4</octave></pitch>",
+		"<pitch><step>E</step><octave>4</octave></pitch>",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("MusicXML 中缺少 %s\n%s", want, xml)
+		}
+	}
+}
+
+// 去掉缩进和换行，便于按片段比较
+func compactXML(xml string) string {
+	var builder strings.Builder
+	for _, line := range strings.Split(xml, "\n") {
+		builder.WriteString(strings.TrimSpace(line))
+	}
+	return builder.String()
+}
@@ -67,7 +67,7 @@ func (g *GroupElement) ElementCount() int {
 func (g *GroupElement) Duration(context PlayContext) float64 {
     if g.duration != nil {
         // 如果指定了组时值，返回指定值
-        return *g.duration
+        return context.Scale(*g.duration)
     }
     
     // 否则计算所有元素的总时长（串行播放）
@@ -82,57 +82,36 @@ func (g *GroupElement) GenerateEvents(startTime float64, context PlayContext) []
     var events []Event
     currentTime := startTime
     
-    if g.duration != nil {
-        // 有指定组时值 - 需要分配时间
-        return g.generateEventsWithGroupDuration(startTime, context)
-    } else {
-        // 无指定组时值 - 简单串行播放
-        for _, element := range g.elements {
-            elementEvents := element.GenerateEvents(currentTime, context)
-            events = append(events, elementEvents...)
-            currentTime += element.Duration(context)
-        }
+    // 有指定组时值时按比例缩放子元素，实现连音
+    childContext := g.childContext(context)
+    for _, element := range g.elements {
+        elementEvents := element.GenerateEvents(currentTime, childContext)
+        events = append(events, elementEvents...)
+        currentTime += element.Duration(childContext)
+        childContext = afterElement(element, childContext)
     }
     
     return events
 }
 
-// 带组时值的事件生成
-func (g *GroupElement) generateEventsWithGroupDuration(startTime float64, context PlayContext) []Event {
-    if len(g.elements) == 0 || g.duration == nil {
-        return []Event{}
+// 子元素使用的上下文：组时值与子元素原始总时长之比乘入缩放比例
+func (g *GroupElement) childContext(context PlayContext) PlayContext {
+    if g.duration == nil {
+        return context
     }
     
-    var events []Event
-    groupDuration := *g.duration
-    
-    // 计算每个元素的原始时长
+    // 原始总时长不受外层缩放影响
+    unscaled := context
+    unscaled.TimeScale = 1
     totalOriginalDuration := 0.0
-    elementDurations := make([]float64, len(g.elements))
-    
-    for i, element := range g.elements {
-        elementDurations[i] = element.Duration(context)
-        totalOriginalDuration += elementDurations[i]
+    for _, element := range g.elements {
+        totalOriginalDuration += element.Duration(unscaled)
     }
-    
-    // 按比例分配组时值
-    currentTime := startTime
-    for i, element := range g.elements {
-        if totalOriginalDuration > 0 {
-            // 按原始时长比例分配
-            scaledDuration := (elementDurations[i] / totalOriginalDuration) * groupDuration
-            
-            // 创建新的上下文，调整时值
-            newContext := context
-            // 这里可能需要调整上下文来影响元素的实际播放时长
-            
-            elementEvents := element.GenerateEvents(currentTime, newContext)
-            events = append(events, elementEvents...)
-            currentTime += scaledDuration
-        }
+    if totalOriginalDuration <= 0 {
+        return context
     }
     
-    return events
+    return context.WithTimeScale(*g.duration / totalOriginalDuration)
 }
 
 // 调试和显示
@@ -0,0 +1,101 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"math"
+	"testing"
+)
+
+// 依次包含 elements 的分组，duration 为 0 时不指定组时值
+func groupOf(duration float64, elements ...Playable) *GroupElement {
+	group := NewGroupElement()
+	if duration > 0 {
+		group = NewGroupElementWithDuration(duration)
+	}
+	group.AddElements(elements...)
+	return group
+}
+
+func TestTuplets(t *testing.T) {
+	eighths := func() []Playable {
+		return []Playable{noteElement(core.C, 4, 0.5), noteElement(core.D, 4, 0.5), noteElement(core.E, 4, 0.5)}
+	}
+
+	tests := []struct {
+		name     string
+		group    *GroupElement
+		duration float64
+		want     string
+	}{
+		{"普通分组", groupOf(0, eighths()...), 1.5,
+			"C4@0/0.5 D4@0.5/0.5 E4@1/0.5"},
+		{"三连音", groupOf(1, eighths()...), 1,
+			"C4@0/0.3333 D4@0.3333/0.3333 E4@0.6667/0.3333"},
+		{"五连音", groupOf(1, noteElement(core.C, 4, 0.25), noteElement(core.D, 4, 0.25), noteElement(core.E, 4, 0.25),
+			noteElement(core.F, 4, 0.25), noteElement(core.G, 4, 0.25)), 1,
+			"C4@0/0.2 D4@0.2/0.2 E4@0.4/0.2 F4@0.6/0.2 G4@0.8/0.2"},
+		{"连音中的休止符", groupOf(1, noteElement(core.C, 4, 0.5), restElement(0.5), noteElement(core.E, 4, 0.5)), 1,
+			"C4@0/0.3333 E4@0.6667/0.3333"},
+		{"连音中的和弦", groupOf(1, NewChordElement(core.NewChord([]core.Note{testNote(core.C, 4, 0.5), testNote(core.E, 4, 0.5)})),
+			noteElement(core.D, 4, 0.5), noteElement(core.E, 4, 0.5)), 1,
+			"C4@0/0.3333 E4@0/0.3333 D4@0.3333/0.3333 E4@0.6667/0.3333"},
+		{"嵌套连音比例相乘", groupOf(1, groupOf(1, eighths()...), noteElement(core.F, 4, 1)), 1,
+			"C4@0/0.1667 D4@0.1667/0.1667 E4@0.3333/0.1667 F4@0.5/0.5"},
+		{"不等长的连音", groupOf(3, noteElement(core.C, 4, 1), noteElement(core.D, 4, 1)), 3,
+			"C4@0/1.5 D4@1.5/1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context := NewPlayContext(120, 100)
+			if got := tt.group.Duration(context); math.Abs(got-tt.duration) > 1e-9 {
+				t.Errorf("时长 = %v，期望 %v", got, tt.duration)
+			}
+			if got := describeNotes(t, scoreOf(sectionOf("s", tt.group))); got != tt.want {
+				t.Errorf("得到 %s\n期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTupletFollowedByNotes(t *testing.T) {
+	root := sectionOf("s",
+		groupOf(1, noteElement(core.C, 4, 0.5), noteElement(core.D, 4, 0.5), noteElement(core.E, 4, 0.5)),
+		noteElement(core.F, 4, 1))
+	if got, want := describeNotes(t, scoreOf(root)), "C4@0/0.3333 D4@0.3333/0.3333 E4@0.6667/0.3333 F4@1/1"; got != want {
+		t.Errorf("得到 %s，期望 %s", got, want)
+	}
+}
+
+func TestTupletInsideTransform(t *testing.T) {
+	// augment(2) 与连音的缩放比例相乘，组时值也随之放大
+	transform := NewTransform(groupOf(1, noteElement(core.C, 4, 0.5), noteElement(core.D, 4, 0.5), noteElement(core.E, 4, 0.5)))
+	transform.TimeScale = 2
+
+	if got := transform.Duration(NewPlayContext(120, 100)); got != 2 {
+		t.Errorf("时长 = %v，期望 2", got)
+	}
+	if got, want := describeNotes(t, scoreOf(sectionOf("s", transform))), "C4@0/0.6667 D4@0.6667/0.6667 E4@1.333/0.6667"; got != want {
+		t.Errorf("得到 %s，期望 %s", got, want)
+	}
+}
+
+func TestTupletRatio(t *testing.T) {
+	tests := []struct {
+		scale  float64
+		actual int
+		normal int
+	}{
+		{2.0 / 3, 3, 2},
+		{4.0 / 5, 5, 4},
+		{3.0 / 2, 2, 3},
+		{4.0 / 7, 7, 4},
+		{1.0 / 3, 3, 1},
+	}
+
+	for _, tt := range tests {
+		if actual, normal := tupletRatio(tt.scale); actual != tt.actual || normal != tt.normal {
+			t.Errorf("tupletRatio(%v) = %d:%d，期望 %d:%d", tt.scale, actual, normal, tt.actual, tt.normal)
+		}
+	}
+}
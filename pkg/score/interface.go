@@ -24,6 +24,12 @@ const (
 
     SECTION_TYPE
     TRACK_TYPE
+
+    REPEAT_TYPE
+    TRANSFORM_TYPE
+    BARLINE_TYPE
+    DYNAMIC_TYPE
+    HAIRPIN_TYPE
 )
 
 // 容器接口 - Section和Track的共同接口
@@ -31,9 +37,13 @@ type Container interface {
     Playable
     AddElement(element Playable)
     SetBPM(bpm float64)
+    SetTempoRamp(from, to, beats float64)
     SetVolume(volume int)
     SetInstrument(instrument int)
     SetChannel(channel int)
+    SetVoicing(voicing core.Voicing)
+    SetKey(key core.Key)
+    SetDynamicsMode(mode core.DynamicsMode)
 }
 
 // 元素接口 - Note、Chord、Rest的共同接口
@@ -3,12 +3,17 @@ package score
 import (
     "catRock/pkg/core"
     "fmt"
+    "math"
+    "strings"
 )
 
 // 音符播放元素
 type NoteElement struct {
     ID   string
     Note core.Note
+    Tie  bool // 以延音线连到下一个同音高的音符
+
+    Articulations []core.Articulation // 演奏法：重音、断音等
     
     // 可选覆盖设置
     VolumeOverride     *int
@@ -31,14 +36,15 @@ func (ne *NoteElement) GetType() PlayableType {
 }
 
 func (ne *NoteElement) Duration(context PlayContext) float64 {
-    return float64(ne.Note.Beat)
+    return context.Scale(float64(ne.Note.Beat))
 }
 
 func (ne *NoteElement) GenerateEvents(startTime float64, context PlayContext) []Event {
-    velocity := ne.calculateVelocity(context)
+    velocity := ne.calculateVelocity(context, startTime)
     channel := ne.calculateChannel(context)
-    midiNote := ne.Note.MIDINote[0]
-    duration := ne.Duration(context)
+    midiNote := context.TransposeNote(context.CurrentKey.Apply(ne.Note).MIDINote[0])
+    velocity, duration := articulate(velocity, ne.Duration(context), ne.Tie, ne.Articulations)
+    context.hold(startTime, ne.Duration(context), ne.Articulations)
     
     return []Event{
         {
@@ -60,6 +66,7 @@ func (ne *NoteElement) GenerateEvents(startTime float64, context PlayContext) []
             Channel:       channel,
             Velocity:      0,
             SourceElement: ne.GetID(),
+            Tie:           ne.Tie,
         },
     }
 }
@@ -78,14 +85,39 @@ func (ne *NoteElement) SetChannelOverride(channel int) {
 }
 
 // 辅助方法
-func (ne *NoteElement) calculateVelocity(context PlayContext) uint8 {
+func (ne *NoteElement) calculateVelocity(context PlayContext, beat float64) uint8 {
     if ne.VolumeOverride != nil {
-        return uint8(*ne.VolumeOverride)
+        return context.ScaleVelocity(uint8(*ne.VolumeOverride))
+    }
+    if velocity, ok := context.dynamicVelocity(beat); ok {
+        return context.ScaleVelocity(velocity)
     }
     if ne.Note.Velocity > 0 {
-        return ne.Note.Velocity
+        return context.ScaleVelocity(ne.Note.Velocity)
+    }
+    return context.ScaleVelocity(uint8(context.CurrentVolume))
+}
+
+// 按演奏法调整力度和发声长度，以延音线连到下一个音符时保持满时值
+func articulate(velocity uint8, duration float64, tie bool, articulations []core.Articulation) (uint8, float64) {
+    if len(articulations) == 0 {
+        return velocity, duration
+    }
+    velocityScale, gate := core.ArticulationEffect(articulations)
+    velocity = clampMIDI(int(math.Round(float64(velocity)*velocityScale)), 1)
+    if !tie {
+        duration *= gate
     }
-    return uint8(context.CurrentVolume)
+    return velocity, duration
+}
+
+// 演奏法的书写形式，如 "^ *"
+func formatArticulations(articulations []core.Articulation) string {
+    symbols := make([]string, len(articulations))
+    for i, articulation := range articulations {
+        symbols[i] = articulation.Symbol()
+    }
+    return strings.Join(symbols, " ")
 }
 
 func (ne *NoteElement) calculateChannel(context PlayContext) int {
@@ -114,6 +146,12 @@ func (ne *NoteElement) DetailedString(indent string) string {
     result += fmt.Sprintf("%s  MIDI: %v\n", indent, ne.Note.MIDINote)
     result += fmt.Sprintf("%s  时长: %.3f拍\n", indent, ne.Duration(PlayContext{}))
     result += fmt.Sprintf("%s  节拍: %.3f\n", indent, float64(ne.Note.Beat))
+    if ne.Tie {
+        result += fmt.Sprintf("%s  延音线: 连到下一个音符\n", indent)
+    }
+    if len(ne.Articulations) > 0 {
+        result += fmt.Sprintf("%s  演奏法: %s\n", indent, formatArticulations(ne.Articulations))
+    }
     
     // 显示覆盖参数
     if ne.VolumeOverride != nil || ne.InstrumentOverride != nil || ne.ChannelOverride != nil {
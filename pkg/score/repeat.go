@@ -0,0 +1,130 @@
+package score
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 反复 - 把主体顺序播放 Times 遍的容器，可带跳房子结尾
+type Repeat struct {
+	ID       string
+	Times    int
+	Elements []Playable // 每一遍都播放的主体
+	Endings  []*Ending  // 按编号在对应的一遍接在主体之后
+}
+
+// 跳房子结尾：第 Number 遍播放完主体后接着播放的内容
+type Ending struct {
+	Number   int
+	Elements []Playable
+}
+
+var _ Playable = (*Repeat)(nil)
+
+func (r *Repeat) GetID() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return fmt.Sprintf("repeat_%d_times", r.Times)
+}
+
+func (r *Repeat) GetType() PlayableType {
+	return REPEAT_TYPE
+}
+
+// 第 pass 遍 (从1开始) 的结尾，没有时返回 nil
+func (r *Repeat) ending(pass int) *Ending {
+	for _, ending := range r.Endings {
+		if ending.Number == pass {
+			return ending
+		}
+	}
+	return nil
+}
+
+// 展开后依次播放的元素
+func (r *Repeat) Unroll() []Playable {
+	elements := []Playable{}
+	for pass := 1; pass <= r.Times; pass++ {
+		elements = append(elements, r.Elements...)
+		if ending := r.ending(pass); ending != nil {
+			elements = append(elements, ending.Elements...)
+		}
+	}
+	return elements
+}
+
+func (r *Repeat) Duration(context PlayContext) float64 {
+	totalDuration := 0.0
+	for _, element := range r.Unroll() {
+		totalDuration += element.Duration(context)
+	}
+	return totalDuration
+}
+
+func (r *Repeat) GenerateEvents(startTime float64, context PlayContext) []Event {
+	events := []Event{}
+	currentTime := startTime
+
+	for _, element := range r.Unroll() {
+		events = append(events, element.GenerateEvents(currentTime, context)...)
+		currentTime += element.Duration(context)
+		context = afterElement(element, context)
+	}
+
+	return events
+}
+
+// 构造函数
+func NewRepeat(times int) *Repeat {
+	return &Repeat{
+		Times:    times,
+		Elements: []Playable{},
+		Endings:  []*Ending{},
+	}
+}
+
+func (r *Repeat) AddElement(element Playable) {
+	r.Elements = append(r.Elements, element)
+}
+
+func (r *Repeat) AddEnding(number int, elements []Playable) {
+	r.Endings = append(r.Endings, &Ending{Number: number, Elements: elements})
+}
+
+// 展开顺序的简要描述，如 "主体+结尾1 → 主体+结尾2"
+func (r *Repeat) passSummary() string {
+	passes := []string{}
+	for pass := 1; pass <= r.Times; pass++ {
+		summary := "主体"
+		if r.ending(pass) != nil {
+			summary += fmt.Sprintf("+结尾%d", pass)
+		}
+		passes = append(passes, summary)
+	}
+	return strings.Join(passes, " → ")
+}
+
+func (r *Repeat) DetailedString(indent string) string {
+	result := fmt.Sprintf("Repeat x%d {\n", r.Times)
+	result += fmt.Sprintf("%s  ID: %s\n", indent, r.GetID())
+	result += fmt.Sprintf("%s  时长: %.3f拍 (展开后)\n", indent, r.Duration(PlayContext{}))
+	result += fmt.Sprintf("%s  展开顺序: %s\n", indent, r.passSummary())
+
+	if len(r.Elements) > 0 {
+		result += fmt.Sprintf("%s  主体 (%d个):\n", indent, len(r.Elements))
+		for i, element := range r.Elements {
+			result += fmt.Sprintf("%s    [%d] %s", indent, i, element.DetailedString(indent+"      "))
+		}
+	}
+
+	for _, ending := range r.Endings {
+		result += fmt.Sprintf("%s  结尾 %d (%d个):\n", indent, ending.Number, len(ending.Elements))
+		for i, element := range ending.Elements {
+			result += fmt.Sprintf("%s    [%d] %s", indent, i, element.DetailedString(indent+"      "))
+		}
+	}
+
+	result += fmt.Sprintf("%s}\n", indent)
+	return result
+}
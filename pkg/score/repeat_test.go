@@ -0,0 +1,109 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"testing"
+)
+
+// 反复 times 遍的主体，endings 为各结尾编号对应的元素
+func repeatOf(times int, body []Playable, endings map[int][]Playable) *Repeat {
+	repeat := NewRepeat(times)
+	for _, element := range body {
+		repeat.AddElement(element)
+	}
+	for number := 1; number <= times; number++ {
+		if elements, ok := endings[number]; ok {
+			repeat.AddEnding(number, elements)
+		}
+	}
+	return repeat
+}
+
+func TestRepeatUnroll(t *testing.T) {
+	c, d := noteElement(core.C, 4, 1), noteElement(core.D, 4, 1)
+	e, f := noteElement(core.E, 4, 1), noteElement(core.F, 4, 2)
+
+	tests := []struct {
+		name     string
+		repeat   *Repeat
+		unrolled []Playable
+		summary  string
+		want     string
+	}{
+		{"没有结尾", repeatOf(3, []Playable{c}, nil), []Playable{c, c, c},
+			"主体 → 主体 → 主体", "C4@0/1 C4@1/1 C4@2/1"},
+		{"跳房子结尾", repeatOf(2, []Playable{c, d}, map[int][]Playable{1: {e}, 2: {f}}), []Playable{c, d, e, c, d, f},
+			"主体+结尾1 → 主体+结尾2", "C4@0/1 D4@1/1 E4@2/1 C4@3/1 D4@4/1 F4@5/2"},
+		{"部分遍数有结尾", repeatOf(3, []Playable{c}, map[int][]Playable{2: {d}}), []Playable{c, c, d, c},
+			"主体 → 主体+结尾2 → 主体", "C4@0/1 C4@1/1 D4@2/1 C4@3/1"},
+		{"只反复一遍", repeatOf(1, []Playable{c, d}, map[int][]Playable{1: {e}}), []Playable{c, d, e},
+			"主体+结尾1", "C4@0/1 D4@1/1 E4@2/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unrolled := tt.repeat.Unroll()
+			if len(unrolled) != len(tt.unrolled) {
+				t.Fatalf("展开为 %d 个元素，期望 %d 个", len(unrolled), len(tt.unrolled))
+			}
+			for i := range unrolled {
+				if unrolled[i] != tt.unrolled[i] {
+					t.Errorf("第 %d 个元素为 %s，期望 %s", i, unrolled[i].GetID(), tt.unrolled[i].GetID())
+				}
+			}
+
+			if got := tt.repeat.passSummary(); got != tt.summary {
+				t.Errorf("展开顺序 = %q，期望 %q", got, tt.summary)
+			}
+
+			duration := 0.0
+			for _, element := range tt.unrolled {
+				duration += element.Duration(NewPlayContext(120, 100))
+			}
+			if got := tt.repeat.Duration(NewPlayContext(120, 100)); got != duration {
+				t.Errorf("时长 = %v，期望 %v", got, duration)
+			}
+
+			if got := describeNotes(t, scoreOf(sectionOf("s", tt.repeat))); got != tt.want {
+				t.Errorf("得到 %s\n期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNestedRepeat(t *testing.T) {
+	inner := repeatOf(2, []Playable{noteElement(core.D, 4, 0.5)}, nil)
+	outer := repeatOf(2, []Playable{noteElement(core.C, 4, 1), inner}, map[int][]Playable{2: {noteElement(core.E, 4, 1)}})
+
+	want := "C4@0/1 D4@1/0.5 D4@1.5/0.5 C4@2/1 D4@3/0.5 D4@3.5/0.5 E4@4/1 F4@5/1"
+	if got := describeNotes(t, scoreOf(sectionOf("s", outer, noteElement(core.F, 4, 1)))); got != want {
+		t.Errorf("得到 %s\n期望 %s", got, want)
+	}
+}
+
+func TestRepeatCarriesDynamics(t *testing.T) {
+	// 主体末尾的力度记号作用于下一遍的开头
+	repeat := repeatOf(2, []Playable{noteElement(core.C, 4, 1), NewDynamicMark(core.DynamicF), noteElement(core.D, 4, 1)}, nil)
+	events, err := NewPlayEngine(scoreOf(sectionOf("s", repeat))).GenerateEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var velocities []uint8
+	for _, event := range events {
+		if event.Action == NOTE_ON {
+			velocities = append(velocities, event.Velocity)
+		}
+	}
+	forte := core.DynamicF.Velocity()
+	want := []uint8{100, forte, forte, forte}
+	if len(velocities) != len(want) {
+		t.Fatalf("力度 %v，期望 %v", velocities, want)
+	}
+	for i := range want {
+		if velocities[i] != want[i] {
+			t.Errorf("力度 %v，期望 %v", velocities, want)
+			break
+		}
+	}
+}
@@ -25,7 +25,7 @@ func (re *RestElement) GetType() PlayableType {
 }
 
 func (re *RestElement) Duration(context PlayContext) float64 {
-	return re.Rest.Duration()
+	return context.Scale(re.Rest.Duration())
 }
 
 func (re *RestElement) GenerateEvents(startTime float64, context PlayContext) []Event {
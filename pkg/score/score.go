@@ -3,6 +3,8 @@ package score
 import (
 	"catRock/pkg/core"
 	"catRock/pkg/io"
+	"catRock/pkg/sequencer"
+	"context"
 	"fmt"
 	"sort"
 	"time"
@@ -18,6 +20,10 @@ type Score struct {
 	// 播放设置
 	BPM    float64
 	Volume int
+	Key    core.Key           // 全局调号，零值为 C 大调
+	Time   core.TimeSignature // 拍号，零值为 4/4
+
+	Dynamics core.DynamicsMode // 力度记号的演奏方式，零值为改变音符力度
 
 	// 根元素 - 整个作品的入口
 	RootElement Playable
@@ -31,6 +37,7 @@ type PlayEngine struct {
 	score   *Score
 	context PlayContext
 	events  []Event
+	tempo   *sequencer.TempoMap // 生成事件时记录的速度表
 }
 
 // 构造函数
@@ -59,6 +66,18 @@ func (s *Score) SetVolume(volume int) {
 	}
 }
 
+func (s *Score) SetKey(key core.Key) {
+	s.Key = key
+}
+
+func (s *Score) SetTime(time core.TimeSignature) {
+	s.Time = time
+}
+
+func (s *Score) SetDynamicsMode(mode core.DynamicsMode) {
+	s.Dynamics = mode
+}
+
 func (s *Score) SetRootElement(element Playable) {
 	s.RootElement = element
 }
@@ -85,6 +104,9 @@ func (s *Score) DetailedString(indent string) string {
 	result += fmt.Sprintf("%s  年份: %d\n", indent, s.Year)
 	result += fmt.Sprintf("%s  BPM: %.1f\n", indent, s.BPM)
 	result += fmt.Sprintf("%s  音量: %d\n", indent, s.Volume)
+	result += fmt.Sprintf("%s  调号: %s\n", indent, s.Key)
+	result += fmt.Sprintf("%s  拍号: %s\n", indent, s.Time)
+	result += fmt.Sprintf("%s  力度模式: %s\n", indent, s.Dynamics)
 	result += fmt.Sprintf("%s  时长: %.2f秒\n", indent, s.GetDuration())
 
 	if len(s.GlobalSettings) > 0 {
@@ -107,7 +129,10 @@ func (s *Score) DetailedString(indent string) string {
 
 // 创建播放上下文
 func (s *Score) createPlayContext() PlayContext {
-	return NewPlayContext(s.BPM, s.Volume)
+	context := NewPlayContext(s.BPM, s.Volume)
+	context.CurrentKey = s.Key
+	context.DynamicsMode = s.Dynamics
+	return context
 }
 
 // 播放
@@ -137,29 +162,25 @@ func (pe *PlayEngine) PlayEventsWithIO(ioDevice io.IO, events []Event) error {
 		return fmt.Errorf("IO设备未连接")
 	}
 
-	// 计算每拍的毫秒数
-	millisecondsPerBeat := 60000.0 / pe.score.BPM
-
-	startTime := time.Now()
+	seq := sequencer.New(ioDevice, pe.TempoMap(), sequencer.DefaultOptions())
+	if err := seq.Play(context.Background(), ToIOEvents(events)); err != nil {
+		return fmt.Errorf("执行事件失败: %v", err)
+	}
 
-	for _, event := range events {
-		// 计算事件的实际播放时间
-		eventTime := time.Duration(event.Time*millisecondsPerBeat) * time.Millisecond
-		targetTime := startTime.Add(eventTime)
-
-		// 等待到事件时间
-		now := time.Now()
-		if targetTime.After(now) {
-			time.Sleep(targetTime.Sub(now))
-		}
+	return nil
+}
 
-		// 执行事件
-		if err := pe.executeEventWithIO(ioDevice, event); err != nil {
-			return fmt.Errorf("执行事件失败: %v", err)
-		}
+// 播放使用的速度表：生成事件后包含段落和音轨设置的速度变化，之前只有全局速度
+func (pe *PlayEngine) TempoMap() *sequencer.TempoMap {
+	if pe.tempo == nil {
+		return sequencer.NewTempoMap(pe.score.BPM)
 	}
+	return pe.tempo
+}
 
-	return nil
+// 创建可暂停、定位和循环的走带控制
+func (pe *PlayEngine) NewTransport(sender io.MIDIEventSender, events []Event) *sequencer.Transport {
+	return sequencer.NewTransport(sender, pe.TempoMap(), ToIOEvents(events), sequencer.DefaultOptions())
 }
 
 // 异步播放事件
@@ -177,46 +198,63 @@ func (pe *PlayEngine) PlayEventsWithIOAsync(ioDevice io.IO, events []Event) (<-c
 	return errChan, nil
 }
 
-// 执行单个事件
-func (pe *PlayEngine) executeEventWithIO(ioDevice io.IO, event Event) error {
-	switch event.Action {
-	case NOTE_ON:
-		if midiNote, ok := event.Data.(uint8); ok {
-			return ioDevice.SendNoteOn(uint8(event.Channel), midiNote, event.Velocity)
-		}
-		return fmt.Errorf("NOTE_ON事件数据类型错误")
+// 转换为底层MIDI事件，供调度器和离线渲染器使用
+func ToIOEvents(events []Event) []io.Event {
+	result := make([]io.Event, 0, len(events))
 
-	case NOTE_OFF:
-		if midiNote, ok := event.Data.(uint8); ok {
-			return ioDevice.SendNoteOff(uint8(event.Channel), midiNote, 0)
+	for _, event := range events {
+		ioEvent := io.Event{
+			Time:    event.Time,
+			Channel: uint8(event.Channel),
 		}
-		return fmt.Errorf("NOTE_OFF事件数据类型错误")
 
-	case VOLUME_CHANGE:
-		if volume, ok := event.Data.(uint8); ok {
-			return ioDevice.SendControlChange(uint8(event.Channel), 7, volume) // CC7 = 主音量
-		}
-		return fmt.Errorf("VOLUME_CHANGE事件数据类型错误")
-
-	case PROGRAM_CHANGE:
-		if program, ok := event.Data.(core.InstrumentID); ok {
-			if core.IsDrumKit(program) {
-				if event.Channel != 9 {
-					fmt.Printf("WARNING: 鼓组程序变更事件不应在非鼓组通道发送 - 通道:%d, 程序:%d\n", event.Channel, program)
-					return nil // 跳过非鼓组通道的程序变更
-				}
-				return nil
+		switch event.Action {
+		case NOTE_ON, NOTE_OFF:
+			midiNote, ok := event.Data.(uint8)
+			if !ok {
+				continue
+			}
+			ioEvent.Type = io.NOTE_ON_EVENT
+			if event.Action == NOTE_OFF {
+				ioEvent.Type = io.NOTE_OFF_EVENT
 			}
+			ioEvent.Data1 = midiNote
+			ioEvent.Data2 = event.Velocity
 
-			midiProgram := core.GetMIDIProgram(program)
-			return ioDevice.SendProgramChange(uint8(event.Channel), midiProgram)
+		case VOLUME_CHANGE:
+			volume, ok := event.Data.(uint8)
+			if !ok {
+				continue
+			}
+			ioEvent.Type = io.CONTROL_CHANGE_EVENT
+			ioEvent.Data1 = 7 // CC7 = 主音量
+			ioEvent.Data2 = volume
+
+		case EXPRESSION_CHANGE:
+			expression, ok := event.Data.(uint8)
+			if !ok {
+				continue
+			}
+			ioEvent.Type = io.CONTROL_CHANGE_EVENT
+			ioEvent.Data1 = 11 // CC11 = 表情
+			ioEvent.Data2 = expression
+
+		case PROGRAM_CHANGE:
+			program, ok := event.Data.(core.InstrumentID)
+			if !ok || core.IsDrumKit(program) {
+				continue
+			}
+			ioEvent.Type = io.PROGRAM_CHANGE_EVENT
+			ioEvent.Data1 = core.GetMIDIProgram(program)
+
+		default:
+			continue
 		}
-		return fmt.Errorf("PROGRAM_CHANGE事件数据类型错误")
 
-	default:
-		// 忽略未知事件类型
-		return nil
+		result = append(result, ioEvent)
 	}
+
+	return result
 }
 
 // 播放引擎方法
@@ -233,8 +271,15 @@ func (pe *PlayEngine) GenerateEvents() ([]Event, error) {
 		return nil, fmt.Errorf("没有可播放的音乐元素")
 	}
 
-	// 生成所有事件
-	events := pe.score.RootElement.GenerateEvents(0.0, pe.context)
+	// 生成所有事件，同时记录各容器的速度和延长记号
+	recorder := newTempoRecorder(pe.score.BPM)
+	context := pe.context
+	context.Tempo = recorder
+	events := pe.score.RootElement.GenerateEvents(0.0, context)
+	pe.tempo = recorder.finish()
+
+	// 合并延音线
+	events = mergeTies(events)
 
 	// 排序事件
 	pe.events = pe.sortEvents(events)
@@ -265,6 +310,58 @@ func (pe *PlayEngine) sortEvents(events []Event) []Event {
 	return events
 }
 
+// 合并延音线：带 Tie 标记的 NOTE_OFF 与同一时刻、同一轨道通道音高的 NOTE_ON 相抵，
+// 前一个音符的时长延长为两者之和，相连的音符只产生一对 NOTE_ON/NOTE_OFF
+func mergeTies(events []Event) []Event {
+	type noteKey struct {
+		track   string
+		channel int
+		note    interface{}
+	}
+
+	// 同一时刻 NOTE_OFF 在前，保证先登记延音再匹配接续的 NOTE_ON
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Time != events[j].Time {
+			return events[i].Time < events[j].Time
+		}
+		return events[i].Action == NOTE_OFF && events[j].Action != NOTE_OFF
+	})
+
+	removed := make([]bool, len(events))
+	heads := map[noteKey]int{} // 发声中音符的首个 NOTE_ON
+	tied := map[noteKey]int{}  // 等待接续的 NOTE_OFF
+
+	for i, event := range events {
+		key := noteKey{event.Track, event.Channel, event.Data}
+
+		switch event.Action {
+		case NOTE_OFF:
+			if event.Tie {
+				tied[key] = i
+			}
+
+		case NOTE_ON:
+			if j, ok := tied[key]; ok && events[j].Time == event.Time {
+				delete(tied, key)
+				removed[i], removed[j] = true, true
+				if head, ok := heads[key]; ok {
+					events[head].Duration += event.Duration
+				}
+				continue
+			}
+			heads[key] = i
+		}
+	}
+
+	result := make([]Event, 0, len(events))
+	for i, event := range events {
+		if !removed[i] {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
 // 播放统计信息
 type PlayStats struct {
 	TotalDuration float64
@@ -336,18 +433,3 @@ func (s *Score) Export(options ExportOptions) error {
 	}
 }
 
-// 导出实现（占位符）
-func (s *Score) exportMIDI(options ExportOptions) error {
-	// TODO: 实现MIDI导出
-	return fmt.Errorf("MIDI导出尚未实现")
-}
-
-func (s *Score) exportJSON(options ExportOptions) error {
-	// TODO: 实现JSON导出
-	return fmt.Errorf("JSON导出尚未实现")
-}
-
-func (s *Score) exportXML(options ExportOptions) error {
-	// TODO: 实现XML导出
-	return fmt.Errorf("XML导出尚未实现")
-}
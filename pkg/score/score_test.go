@@ -0,0 +1,145 @@
+package score
+
+import (
+	"catRock/pkg/core"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var pitchNames = []string{"C", "Cs", "D", "Ds", "E", "F", "Fs", "G", "Gs", "A", "As", "B"}
+
+// 指定音名、八度和拍数的音符
+func testNote(name core.BaseNoteName, octave int, beats float64) core.Note {
+	return core.NewNote(core.NewNoteParams{Name: name, Octave: octave, Beat: core.BeatValue(beats)})
+}
+
+func noteElement(name core.BaseNoteName, octave int, beats float64) *NoteElement {
+	return NewNoteElement(testNote(name, octave, beats))
+}
+
+func tiedNote(name core.BaseNoteName, octave int, beats float64) *NoteElement {
+	element := noteElement(name, octave, beats)
+	element.Tie = true
+	return element
+}
+
+func restElement(beats float64) *RestElement {
+	return NewRestElement(core.NewRest(core.BeatValue(beats)))
+}
+
+// 依次包含 elements 的段落；嵌套的段落名称不能相同，否则被当作循环引用跳过
+func sectionOf(name string, elements ...Playable) *Section {
+	section := NewSection(name)
+	for _, element := range elements {
+		section.AddElement(element)
+	}
+	return section
+}
+
+func scoreOf(root Playable) *Score {
+	scoreObj := NewScore("test")
+	scoreObj.RootElement = root
+	return scoreObj
+}
+
+// 把 NOTE_ON 事件写成 "音名@拍/时长"，如 "C4@0/1"，同一时刻按音高排列
+func describeNotes(t *testing.T, scoreObj *Score) string {
+	t.Helper()
+	events, err := NewPlayEngine(scoreObj).GenerateEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var notes []Event
+	for _, event := range events {
+		if event.Action == NOTE_ON {
+			notes = append(notes, event)
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Time != notes[j].Time {
+			return notes[i].Time < notes[j].Time
+		}
+		return notes[i].Data.(byte) < notes[j].Data.(byte)
+	})
+
+	parts := make([]string, len(notes))
+	for i, event := range notes {
+		midi := int(event.Data.(byte))
+		parts[i] = fmt.Sprintf("%s%d@%.4g/%.4g", pitchNames[midi%12], midi/12-5, event.Time, event.Duration)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestMergeTies(t *testing.T) {
+	tiedChord := func(tie bool, notes ...core.Note) *ChordElement {
+		chord := NewChordElement(core.NewChord(notes))
+		chord.Tie = tie
+		return chord
+	}
+
+	tests := []struct {
+		name string
+		root Playable
+		want string
+	}{
+		{"两个音符相连", sectionOf("s", tiedNote(core.C, 4, 1), noteElement(core.C, 4, 0.5), noteElement(core.D, 4, 1)),
+			"C4@0/1.5 D4@1.5/1"},
+		{"连续相连", sectionOf("s", tiedNote(core.C, 4, 1), tiedNote(core.C, 4, 1), noteElement(core.C, 4, 2)),
+			"C4@0/4"},
+		{"不同音高不相连", sectionOf("s", tiedNote(core.C, 4, 1), noteElement(core.D, 4, 1)),
+			"C4@0/1 D4@1/1"},
+		{"中间有休止符不相连", sectionOf("s", tiedNote(core.C, 4, 1), restElement(1), noteElement(core.C, 4, 1)),
+			"C4@0/1 C4@2/1"},
+		{"和弦中相同的音高相连", sectionOf("s",
+			tiedChord(true, testNote(core.C, 4, 2), testNote(core.E, 4, 2)),
+			tiedChord(false, testNote(core.C, 4, 1), testNote(core.G, 4, 1))),
+			"C4@0/3 E4@0/2 G4@2/1"},
+		{"跨段落", sectionOf("s", sectionOf("verse", tiedNote(core.C, 4, 2)), sectionOf("chorus", noteElement(core.C, 4, 1))),
+			"C4@0/3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeNotes(t, scoreOf(tt.root)); got != tt.want {
+				t.Errorf("得到 %s，期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeTiesSeparatesTracks(t *testing.T) {
+	// 并行轨道中同一时刻的同音高音符不会被另一个轨道的延音线接上
+	upper := NewTrack("upper")
+	upper.AddElement(sectionOf("s", tiedNote(core.C, 4, 1)))
+	lower := NewTrack("lower")
+	lower.AddElement(sectionOf("s", restElement(1), noteElement(core.C, 4, 1)))
+
+	root := NewTrack("root")
+	root.AddElement(upper)
+	root.AddElement(lower)
+
+	if got, want := describeNotes(t, scoreOf(root)), "C4@0/1 C4@1/1"; got != want {
+		t.Errorf("得到 %s，期望 %s", got, want)
+	}
+}
+
+func TestMergeTiesEventPairs(t *testing.T) {
+	events, err := NewPlayEngine(scoreOf(sectionOf("s", tiedNote(core.C, 4, 1), tiedNote(core.C, 4, 1), noteElement(core.C, 4, 1)))).GenerateEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 相连的音符只留下一对 NOTE_ON/NOTE_OFF
+	var actions []string
+	for _, event := range events {
+		if event.Type == NOTE_EVENT {
+			actions = append(actions, fmt.Sprintf("%s@%g", map[EventAction]string{NOTE_ON: "on", NOTE_OFF: "off"}[event.Action], event.Time))
+		}
+	}
+	if got, want := strings.Join(actions, " "), "on@0 off@3"; got != want {
+		t.Errorf("得到 %s，期望 %s", got, want)
+	}
+}
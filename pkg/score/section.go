@@ -94,13 +94,19 @@ func (s *Section) GenerateEvents(startTime float64, context PlayContext) []Event
 		events = append(events, volumeChangeEvent)
 	}
 
+	// 段落的速度只在段落内生效，结束后恢复外层速度
+	restoreTempo := context.enterTempo(startTime, s.ContainerParams)
+
 	// 顺序播放：每个元素依次开始
 	for _, element := range s.Elements {
 		elementEvents := element.GenerateEvents(currentTime, sectionContext)
 		events = append(events, elementEvents...)
 		currentTime += element.Duration(sectionContext)
+		sectionContext = afterElement(element, sectionContext)
 	}
 
+	restoreTempo(currentTime)
+
 	return events
 }
 
@@ -111,6 +117,12 @@ func (s *Section) AddElement(element Playable) {
 
 func (s *Section) SetBPM(bpm float64) {
 	s.BPM = &bpm
+	s.BPMRamp = nil
+}
+
+func (s *Section) SetTempoRamp(from, to, beats float64) {
+	s.BPM = &to
+	s.BPMRamp = &TempoRamp{From: from, Beats: beats}
 }
 
 func (s *Section) SetVolume(volume int) {
@@ -126,6 +138,18 @@ func (s *Section) SetChannel(channel int) {
 	s.Channel = &channel
 }
 
+func (s *Section) SetVoicing(voicing core.Voicing) {
+	s.Voicing = &voicing
+}
+
+func (s *Section) SetKey(key core.Key) {
+	s.Key = &key
+}
+
+func (s *Section) SetDynamicsMode(mode core.DynamicsMode) {
+	s.Dynamics = &mode
+}
+
 // 构造函数
 func NewSection(name string) *Section {
 	return &Section{
@@ -141,9 +165,11 @@ func (s *Section) DetailedString(indent string) string {
 	result += fmt.Sprintf("%s  时长: %.3f拍\n", indent, s.Duration(PlayContext{}))
 
 	// 显示容器参数
-	if s.BPM != nil || s.Volume != nil || s.Instrument != nil || s.Channel != nil {
+	if s.BPM != nil || s.Volume != nil || s.Instrument != nil || s.Channel != nil || s.Voicing != nil || s.Key != nil || s.Dynamics != nil {
 		result += fmt.Sprintf("%s  容器参数:\n", indent)
-		if s.BPM != nil {
+		if s.BPMRamp != nil {
+			result += fmt.Sprintf("%s    BPM: %.1f -> %.1f (%g拍)\n", indent, s.BPMRamp.From, *s.BPM, s.BPMRamp.Beats)
+		} else if s.BPM != nil {
 			result += fmt.Sprintf("%s    BPM: %.1f\n", indent, *s.BPM)
 		}
 		if s.Volume != nil {
@@ -155,6 +181,15 @@ func (s *Section) DetailedString(indent string) string {
 		if s.Channel != nil {
 			result += fmt.Sprintf("%s    通道: %d\n", indent, *s.Channel)
 		}
+		if s.Voicing != nil {
+			result += fmt.Sprintf("%s    和弦排列: %s\n", indent, *s.Voicing)
+		}
+		if s.Key != nil {
+			result += fmt.Sprintf("%s    调号: %s\n", indent, *s.Key)
+		}
+		if s.Dynamics != nil {
+			result += fmt.Sprintf("%s    力度模式: %s\n", indent, *s.Dynamics)
+		}
 	}
 
 	if len(s.Elements) > 0 {
@@ -96,12 +96,26 @@ func (t *Track) GenerateEvents(startTime float64, context PlayContext) []Event {
 		events = append(events, volumeChangeEvent)
 	}
 
+	// 轨道的速度只在轨道内生效，结束后恢复外层速度
+	restoreTempo := context.enterTempo(startTime, t.ContainerParams)
+
 	// 并行播放：所有元素同时开始
 	for _, element := range t.Elements {
 		elementEvents := element.GenerateEvents(startTime, trackContext)
 		events = append(events, elementEvents...)
 	}
 
+	if t.BPM != nil {
+		restoreTempo(startTime + t.Duration(context))
+	}
+
+	// 标记事件所属轨道，内层轨道已标记的保持不变
+	for i := range events {
+		if events[i].Track == "" {
+			events[i].Track = t.GetID()
+		}
+	}
+
 	return t.sortEventsByTime(events)
 }
 
@@ -112,6 +126,12 @@ func (t *Track) AddElement(element Playable) {
 
 func (t *Track) SetBPM(bpm float64) {
 	t.BPM = &bpm
+	t.BPMRamp = nil
+}
+
+func (t *Track) SetTempoRamp(from, to, beats float64) {
+	t.BPM = &to
+	t.BPMRamp = &TempoRamp{From: from, Beats: beats}
 }
 
 func (t *Track) SetVolume(volume int) {
@@ -127,6 +147,18 @@ func (t *Track) SetChannel(channel int) {
 	t.Channel = &channel
 }
 
+func (t *Track) SetVoicing(voicing core.Voicing) {
+	t.Voicing = &voicing
+}
+
+func (t *Track) SetKey(key core.Key) {
+	t.Key = &key
+}
+
+func (t *Track) SetDynamicsMode(mode core.DynamicsMode) {
+	t.Dynamics = &mode
+}
+
 // 辅助方法
 func (t *Track) sortEventsByTime(events []Event) []Event {
 	sort.Slice(events, func(i, j int) bool {
@@ -153,9 +185,11 @@ func (t *Track) DetailedString(indent string) string {
 	result += fmt.Sprintf("%s  时长: %.3f拍\n", indent, t.Duration(PlayContext{}))
 
 	// 显示容器参数
-	if t.BPM != nil || t.Volume != nil || t.Instrument != nil || t.Channel != nil {
+	if t.BPM != nil || t.Volume != nil || t.Instrument != nil || t.Channel != nil || t.Voicing != nil || t.Key != nil || t.Dynamics != nil {
 		result += fmt.Sprintf("%s  容器参数:\n", indent)
-		if t.BPM != nil {
+		if t.BPMRamp != nil {
+			result += fmt.Sprintf("%s    BPM: %.1f -> %.1f (%g拍)\n", indent, t.BPMRamp.From, *t.BPM, t.BPMRamp.Beats)
+		} else if t.BPM != nil {
 			result += fmt.Sprintf("%s    BPM: %.1f\n", indent, *t.BPM)
 		}
 		if t.Volume != nil {
@@ -167,6 +201,15 @@ func (t *Track) DetailedString(indent string) string {
 		if t.Channel != nil {
 			result += fmt.Sprintf("%s    通道: %d\n", indent, *t.Channel)
 		}
+		if t.Voicing != nil {
+			result += fmt.Sprintf("%s    和弦排列: %s\n", indent, *t.Voicing)
+		}
+		if t.Key != nil {
+			result += fmt.Sprintf("%s    调号: %s\n", indent, *t.Key)
+		}
+		if t.Dynamics != nil {
+			result += fmt.Sprintf("%s    力度模式: %s\n", indent, *t.Dynamics)
+		}
 	}
 
 	if len(t.Elements) > 0 {
@@ -0,0 +1,68 @@
+package score
+
+import "fmt"
+
+// 变换 - 对子元素整体移调、缩放时值和力度，用于模板的 use 修饰
+type Transform struct {
+	ID            string
+	Element       Playable
+	Transpose     int     // 移调半音数
+	TimeScale     float64 // 时值缩放比例，augment(2) 为 2，diminish(2) 为 0.5；0 视为 1
+	VelocityScale float64 // 力度缩放比例，0 视为 1
+}
+
+var _ Playable = (*Transform)(nil)
+
+func (t *Transform) GetID() string {
+	if t.ID != "" {
+		return t.ID
+	}
+	return fmt.Sprintf("transform_%s", t.Element.GetID())
+}
+
+func (t *Transform) GetType() PlayableType {
+	return TRANSFORM_TYPE
+}
+
+// 子元素使用的上下文
+func (t *Transform) childContext(context PlayContext) PlayContext {
+	childContext := context.WithTranspose(t.Transpose)
+	if t.TimeScale != 0 {
+		childContext = childContext.WithTimeScale(t.TimeScale)
+	}
+	if t.VelocityScale != 0 {
+		childContext = childContext.WithVelocityScale(t.VelocityScale)
+	}
+	return childContext
+}
+
+func (t *Transform) Duration(context PlayContext) float64 {
+	return t.Element.Duration(t.childContext(context))
+}
+
+func (t *Transform) GenerateEvents(startTime float64, context PlayContext) []Event {
+	return t.Element.GenerateEvents(startTime, t.childContext(context))
+}
+
+// 构造函数
+func NewTransform(element Playable) *Transform {
+	return &Transform{Element: element}
+}
+
+func (t *Transform) DetailedString(indent string) string {
+	result := "Transform {\n"
+	result += fmt.Sprintf("%s  ID: %s\n", indent, t.GetID())
+	result += fmt.Sprintf("%s  时长: %.3f拍\n", indent, t.Duration(PlayContext{}))
+	if t.Transpose != 0 {
+		result += fmt.Sprintf("%s  移调: %+d 半音\n", indent, t.Transpose)
+	}
+	if t.TimeScale != 0 && t.TimeScale != 1 {
+		result += fmt.Sprintf("%s  时值缩放: x%.3g\n", indent, t.TimeScale)
+	}
+	if t.VelocityScale != 0 && t.VelocityScale != 1 {
+		result += fmt.Sprintf("%s  力度缩放: x%.3g\n", indent, t.VelocityScale)
+	}
+	result += fmt.Sprintf("%s  元素: %s", indent, t.Element.DetailedString(indent+"    "))
+	result += fmt.Sprintf("%s}\n", indent)
+	return result
+}
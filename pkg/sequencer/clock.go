@@ -0,0 +1,22 @@
+package sequencer
+
+import "time"
+
+// 时钟源
+type Clock interface {
+	Now() time.Duration // 自时钟创建以来经过的时间
+}
+
+// 基于单调时钟的实现，不受系统时间调整影响
+type monotonicClock struct {
+	start time.Time
+}
+
+func NewMonotonicClock() Clock {
+	return &monotonicClock{start: time.Now()}
+}
+
+func (c *monotonicClock) Now() time.Duration {
+	// time.Since 使用 time.Now 携带的单调读数
+	return time.Since(c.start)
+}
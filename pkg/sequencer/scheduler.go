@@ -1,2 +1,116 @@
 package sequencer
 
+import (
+	"catRock/pkg/io"
+	"context"
+	"time"
+)
+
+type Options struct {
+	Lookahead     time.Duration // 提前换算发送时间并放入队列的窗口
+	SpinThreshold time.Duration // 距发送时间小于该值时不再休眠，改为让出CPU等待
+	MaxSleep      time.Duration // 单次休眠上限，保证及时响应取消
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Lookahead:     100 * time.Millisecond,
+		SpinThreshold: time.Millisecond,
+		MaxSleep:      20 * time.Millisecond,
+	}
+}
+
+// 同一时刻的一批事件
+type Batch struct {
+	Beat   float64
+	Events []io.Event
+}
+
+// 已换算发送时间的批次
+type scheduledBatch struct {
+	Batch
+	deadline time.Duration // 相对播放开始的发送时间
+}
+
+// 调度统计
+type Stats struct {
+	Batches       int
+	Events        int
+	MaxLateness   time.Duration // 最大延迟
+	TotalLateness time.Duration
+}
+
+func (s Stats) AverageLateness() time.Duration {
+	if s.Batches == 0 {
+		return 0
+	}
+	return s.TotalLateness / time.Duration(s.Batches)
+}
+
+// 事件调度器：按速度表把拍数换算为绝对时间，
+// 每批事件都以播放开始时刻为基准计算发送时间，休眠误差不会累积
+type Sequencer struct {
+	sender  io.MIDIEventSender
+	clock   Clock
+	tempo   *TempoMap
+	options Options
+	stats   Stats
+}
+
+func New(sender io.MIDIEventSender, tempo *TempoMap, options Options) *Sequencer {
+	if tempo == nil {
+		tempo = NewTempoMap(120)
+	}
+
+	return &Sequencer{
+		sender:  sender,
+		clock:   NewMonotonicClock(),
+		tempo:   tempo,
+		options: options,
+	}
+}
+
+// 替换时钟源
+func (s *Sequencer) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+func (s *Sequencer) TempoMap() *TempoMap {
+	return s.tempo
+}
+
+func (s *Sequencer) Stats() Stats {
+	return s.stats
+}
+
+// 为事件序列创建走带控制
+func (s *Sequencer) NewTransport(events []io.Event) *Transport {
+	transport := NewTransport(s.sender, s.tempo, events, s.options)
+	transport.SetClock(s.clock)
+	return transport
+}
+
+// 从头播放事件序列，直到全部发送完毕或 ctx 被取消
+func (s *Sequencer) Play(ctx context.Context, events []io.Event) error {
+	transport := s.NewTransport(events)
+	err := transport.Play(ctx)
+	s.stats = transport.Stats()
+	return err
+}
+
+// 按时间排序并把同一时刻的事件合并为批次
+func BatchEvents(events []io.Event) []Batch {
+	sorted := make([]io.Event, len(events))
+	copy(sorted, events)
+	io.SortEvents(sorted)
+
+	batches := []Batch{}
+	for _, event := range sorted {
+		if n := len(batches); n > 0 && batches[n-1].Beat == event.Time {
+			batches[n-1].Events = append(batches[n-1].Events, event)
+			continue
+		}
+		batches = append(batches, Batch{Beat: event.Time, Events: []io.Event{event}})
+	}
+	return batches
+}
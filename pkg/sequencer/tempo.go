@@ -0,0 +1,254 @@
+package sequencer
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// 速度变化点：从 Beat 拍开始使用 BPM。
+// RampEnd 大于 Beat 时为渐变：速度在 Beat 到 RampEnd 拍之间从 BPM 线性变化到 RampBPM，之后保持 RampBPM
+type TempoChange struct {
+	Beat    float64
+	BPM     float64
+	RampEnd float64
+	RampBPM float64
+}
+
+// 是否为渐变
+func (c TempoChange) IsRamp() bool {
+	return c.RampEnd > c.Beat
+}
+
+// 该变化点之后某一拍的速度 (不考虑之后的变化点)
+func (c TempoChange) tempoAt(beat float64) float64 {
+	if !c.IsRamp() {
+		return c.BPM
+	}
+	if beat >= c.RampEnd {
+		return c.RampBPM
+	}
+	return c.BPM + (c.RampBPM-c.BPM)*(beat-c.Beat)/(c.RampEnd-c.Beat)
+}
+
+// 从 beat 拍起与该变化点速度曲线一致的变化点，未走完的渐变按 beat 重新起算
+func (c TempoChange) from(beat float64) TempoChange {
+	change := TempoChange{Beat: beat, BPM: c.tempoAt(beat)}
+	if c.IsRamp() && beat < c.RampEnd {
+		change.RampEnd = c.RampEnd
+		change.RampBPM = c.RampBPM
+	}
+	return change
+}
+
+// 从 from 拍到 to 拍经过的秒数，对渐变部分按速度曲线积分
+func (c TempoChange) seconds(from, to float64) float64 {
+	if !c.IsRamp() || from >= c.RampEnd {
+		return (to - from) * 60 / c.tempoAt(from)
+	}
+
+	rampTo := math.Min(to, c.RampEnd)
+	seconds := rampSeconds(c.tempoAt(from), c.tempoAt(rampTo), rampTo-from)
+	if to > rampTo {
+		seconds += (to - rampTo) * 60 / c.RampBPM
+	}
+	return seconds
+}
+
+// 从 from 拍开始经过 seconds 秒到达的拍数，是 seconds 的反函数
+func (c TempoChange) beatAfter(from, seconds float64) float64 {
+	if !c.IsRamp() || from >= c.RampEnd {
+		return from + seconds*c.tempoAt(from)/60
+	}
+
+	rampLength := c.seconds(from, c.RampEnd)
+	if seconds >= rampLength {
+		return c.RampEnd + (seconds-rampLength)*c.RampBPM/60
+	}
+
+	// 速度随拍数线性变化：bpm(x) = start + slope*x，经过的时间为 60/slope * ln(bpm(x)/start)
+	start := c.tempoAt(from)
+	slope := (c.RampBPM - c.BPM) / (c.RampEnd - c.Beat)
+	if slope == 0 {
+		return from + seconds*start/60
+	}
+	return from + (start*math.Exp(slope*seconds/60)-start)/slope
+}
+
+// 速度从 start 线性变化到 end 的 length 拍所经过的秒数
+func rampSeconds(start, end, length float64) float64 {
+	if length <= 0 {
+		return 0
+	}
+	if math.Abs(end-start) < 1e-9 {
+		return length * 60 / start
+	}
+	slope := (end - start) / length
+	return 60 / slope * math.Log(end/start)
+}
+
+// 速度表，拍数与时间的换算
+type TempoMap struct {
+	changes []TempoChange
+}
+
+func NewTempoMap(bpm float64) *TempoMap {
+	if bpm <= 0 {
+		bpm = 120
+	}
+	return &TempoMap{changes: []TempoChange{{Beat: 0, BPM: bpm}}}
+}
+
+// 设置从某一拍开始的速度，同一拍已有变化点时覆盖
+func (tm *TempoMap) SetTempo(beat float64, bpm float64) {
+	if bpm <= 0 || beat < 0 {
+		return
+	}
+	tm.set(TempoChange{Beat: beat, BPM: bpm})
+}
+
+// 设置从某一拍开始的渐变：在 length 拍内从 from 线性变化到 to，之后保持 to
+func (tm *TempoMap) SetRamp(beat, length, from, to float64) {
+	if from <= 0 || to <= 0 || beat < 0 {
+		return
+	}
+	if length <= 0 {
+		tm.set(TempoChange{Beat: beat, BPM: to})
+		return
+	}
+	tm.set(TempoChange{Beat: beat, BPM: from, RampEnd: beat + length, RampBPM: to})
+}
+
+// 从某一拍起沿用 change 的速度曲线，如内层容器结束后恢复外层未走完的渐变
+func (tm *TempoMap) Resume(beat float64, change TempoChange) {
+	if beat < 0 {
+		return
+	}
+	tm.set(change.from(beat))
+}
+
+// 把从 beat 开始 length 拍的时间延长为 factor 倍 (如延长记号)，之后的速度保持不变
+func (tm *TempoMap) Stretch(beat, length, factor float64) {
+	if length <= 0 || factor <= 0 || beat < 0 {
+		return
+	}
+
+	end := beat + length
+	tm.split(beat)
+	tm.split(end)
+	for i := range tm.changes {
+		if tm.changes[i].Beat >= beat && tm.changes[i].Beat < end {
+			tm.changes[i].BPM /= factor
+			tm.changes[i].RampBPM /= factor
+		}
+	}
+}
+
+// 在某一拍插入与当前速度曲线一致的变化点，使之后可以单独修改该拍开始的部分
+func (tm *TempoMap) split(beat float64) {
+	segment := tm.changes[tm.segmentAt(beat)]
+	if segment.Beat == beat {
+		return
+	}
+
+	tm.set(segment.from(beat))
+}
+
+// 插入变化点，同一拍已有变化点时覆盖
+func (tm *TempoMap) set(change TempoChange) {
+	i := sort.Search(len(tm.changes), func(i int) bool { return tm.changes[i].Beat >= change.Beat })
+	if i < len(tm.changes) && tm.changes[i].Beat == change.Beat {
+		tm.changes[i] = change
+		return
+	}
+
+	tm.changes = append(tm.changes, TempoChange{})
+	copy(tm.changes[i+1:], tm.changes[i:])
+	tm.changes[i] = change
+}
+
+// 所有变化点（按拍数排序）
+func (tm *TempoMap) Changes() []TempoChange {
+	result := make([]TempoChange, len(tm.changes))
+	copy(result, tm.changes)
+	return result
+}
+
+// 用恒定速度的变化点近似速度表：渐变按 step 拍分段，每段的速度使该段经过的时间与原曲线一致。
+// 用于只支持阶梯速度的格式，如 MIDI 文件
+func (tm *TempoMap) Steps(step float64) []TempoChange {
+	var result []TempoChange
+	for i, change := range tm.changes {
+		if !change.IsRamp() || step <= 0 {
+			result = append(result, TempoChange{Beat: change.Beat, BPM: change.tempoAt(change.Beat)})
+			continue
+		}
+
+		end := change.RampEnd
+		if i+1 < len(tm.changes) && tm.changes[i+1].Beat < end {
+			end = tm.changes[i+1].Beat
+		}
+		for beat := change.Beat; beat < end-1e-9; beat += step {
+			length := math.Min(step, end-beat)
+			bpm := length * 60 / change.seconds(beat, beat+length)
+			result = append(result, TempoChange{Beat: beat, BPM: bpm})
+		}
+		if end == change.RampEnd && (i+1 >= len(tm.changes) || tm.changes[i+1].Beat > end) {
+			result = append(result, TempoChange{Beat: end, BPM: change.RampBPM})
+		}
+	}
+	return result
+}
+
+// 某一拍所在的变化点
+func (tm *TempoMap) ChangeAt(beat float64) TempoChange {
+	return tm.changes[tm.segmentAt(beat)]
+}
+
+// 某一拍的速度
+func (tm *TempoMap) TempoAt(beat float64) float64 {
+	return tm.changes[tm.segmentAt(beat)].tempoAt(beat)
+}
+
+// 拍数转换为从第0拍开始经过的时间
+func (tm *TempoMap) BeatToTime(beat float64) time.Duration {
+	seconds := 0.0
+	for i, change := range tm.changes {
+		if change.Beat >= beat {
+			break
+		}
+
+		end := beat
+		if i+1 < len(tm.changes) && tm.changes[i+1].Beat < beat {
+			end = tm.changes[i+1].Beat
+		}
+		seconds += change.seconds(change.Beat, end)
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// 时间转换为拍数
+func (tm *TempoMap) TimeToBeat(t time.Duration) float64 {
+	remaining := t.Seconds()
+	for i, change := range tm.changes {
+		if i+1 < len(tm.changes) {
+			length := change.seconds(change.Beat, tm.changes[i+1].Beat)
+			if remaining >= length {
+				remaining -= length
+				continue
+			}
+		}
+		return change.beatAfter(change.Beat, remaining)
+	}
+	return 0
+}
+
+// 包含该拍的速度段索引
+func (tm *TempoMap) segmentAt(beat float64) int {
+	i := sort.Search(len(tm.changes), func(i int) bool { return tm.changes[i].Beat > beat })
+	if i == 0 {
+		return 0
+	}
+	return i - 1
+}
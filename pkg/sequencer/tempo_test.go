@@ -0,0 +1,181 @@
+package sequencer
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const tolerance = 1e-6
+
+func seconds(d time.Duration) float64 {
+	return d.Seconds()
+}
+
+func TestBeatToTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func() *TempoMap
+		beat  float64
+		want  float64 // 秒
+	}{
+		{"恒定速度", func() *TempoMap { return NewTempoMap(120) }, 4, 2},
+		{"无效速度使用120", func() *TempoMap { return NewTempoMap(0) }, 4, 2},
+		{"变速", func() *TempoMap {
+			tm := NewTempoMap(120)
+			tm.SetTempo(4, 60)
+			return tm
+		}, 8, 6},
+		{"渐快", func() *TempoMap {
+			tm := NewTempoMap(60)
+			tm.SetRamp(0, 4, 60, 120)
+			return tm
+		}, 4, 4 * math.Ln2},
+		{"渐变之后保持目标速度", func() *TempoMap {
+			tm := NewTempoMap(60)
+			tm.SetRamp(0, 4, 60, 120)
+			return tm
+		}, 6, 4*math.Ln2 + 1},
+		{"长度为0的渐变直接变速", func() *TempoMap {
+			tm := NewTempoMap(60)
+			tm.SetRamp(2, 0, 60, 120)
+			return tm
+		}, 4, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seconds(tt.build().BeatToTime(tt.beat)); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("BeatToTime(%v) = %vs，期望 %vs", tt.beat, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeToBeatRoundTrip(t *testing.T) {
+	maps := map[string]func() *TempoMap{
+		"恒定速度": func() *TempoMap { return NewTempoMap(96) },
+		"多次变速": func() *TempoMap {
+			tm := NewTempoMap(120)
+			tm.SetTempo(3, 80)
+			tm.SetTempo(7.5, 200)
+			return tm
+		},
+		"渐快": func() *TempoMap {
+			tm := NewTempoMap(60)
+			tm.SetRamp(2, 8, 60, 180)
+			return tm
+		},
+		"渐慢后变速": func() *TempoMap {
+			tm := NewTempoMap(140)
+			tm.SetRamp(1, 6, 140, 70)
+			tm.SetTempo(10, 100)
+			return tm
+		},
+		"渐变中途被打断": func() *TempoMap {
+			tm := NewTempoMap(100)
+			tm.SetRamp(0, 10, 100, 50)
+			tm.SetTempo(4, 150)
+			return tm
+		},
+		"延长记号": func() *TempoMap {
+			tm := NewTempoMap(120)
+			tm.SetRamp(2, 4, 120, 60)
+			tm.Stretch(3, 2, 3)
+			return tm
+		},
+	}
+
+	for name, build := range maps {
+		t.Run(name, func(t *testing.T) {
+			tm := build()
+			for beat := 0.0; beat < 20; beat += 0.37 {
+				if got := tm.TimeToBeat(tm.BeatToTime(beat)); math.Abs(got-beat) > tolerance {
+					t.Fatalf("TimeToBeat(BeatToTime(%v)) = %v", beat, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStretch(t *testing.T) {
+	tm := NewTempoMap(120)
+	tm.SetRamp(4, 4, 120, 60)
+	before := NewTempoMap(120)
+	before.SetRamp(4, 4, 120, 60)
+
+	tm.Stretch(2, 1, 2)
+	tm.Stretch(5, 2, 1.5)
+
+	span := func(tm *TempoMap, from, to float64) float64 {
+		return seconds(tm.BeatToTime(to)) - seconds(tm.BeatToTime(from))
+	}
+
+	tests := []struct {
+		from, to float64
+		factor   float64
+	}{
+		{0, 2, 1},
+		{2, 3, 2},
+		{3, 5, 1},
+		{5, 7, 1.5},
+		{7, 12, 1},
+	}
+	for _, tt := range tests {
+		want := span(before, tt.from, tt.to) * tt.factor
+		if got := span(tm, tt.from, tt.to); math.Abs(got-want) > tolerance {
+			t.Errorf("第%v-%v拍用时 %vs，期望 %vs", tt.from, tt.to, got, want)
+		}
+	}
+
+	// 延长之后的速度不变
+	for _, beat := range []float64{3, 7, 8, 10} {
+		if got, want := tm.TempoAt(beat), before.TempoAt(beat); math.Abs(got-want) > tolerance {
+			t.Errorf("TempoAt(%v) = %v，期望 %v", beat, got, want)
+		}
+	}
+}
+
+func TestSteps(t *testing.T) {
+	tm := NewTempoMap(100)
+	tm.SetRamp(2, 4, 60, 120)
+	tm.SetTempo(8, 90)
+
+	steps := tm.Steps(0.25)
+	stepped := NewTempoMap(steps[0].BPM)
+	for _, step := range steps {
+		if step.IsRamp() {
+			t.Fatalf("Steps 返回了渐变: %+v", step)
+		}
+		stepped.SetTempo(step.Beat, step.BPM)
+	}
+
+	// 阶梯速度在每一级的边界上与原速度曲线的时间一致
+	for beat := 0.0; beat <= 12; beat += 0.25 {
+		if got, want := seconds(stepped.BeatToTime(beat)), seconds(tm.BeatToTime(beat)); math.Abs(got-want) > tolerance {
+			t.Errorf("第%v拍: 阶梯速度 %vs，原曲线 %vs", beat, got, want)
+		}
+	}
+	if got := stepped.TempoAt(7); got != 120 {
+		t.Errorf("渐变结束后的速度 = %v，期望 120", got)
+	}
+}
+
+func TestResume(t *testing.T) {
+	tm := NewTempoMap(120)
+	tm.SetRamp(0, 8, 60, 120)
+	outer := tm.ChangeAt(2)
+
+	tm.SetTempo(2, 200)
+	tm.Resume(4, outer)
+
+	if got := tm.TempoAt(3); got != 200 {
+		t.Errorf("内层速度 = %v，期望 200", got)
+	}
+	for _, beat := range []float64{4, 6, 8, 10} {
+		want := outer.tempoAt(beat)
+		if got := tm.TempoAt(beat); math.Abs(got-want) > tolerance {
+			t.Errorf("TempoAt(%v) = %v，期望继续外层渐变 %v", beat, got, want)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+package sequencer
+
+import (
+	"catRock/pkg/io"
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+type TransportState int
+
+const (
+	Stopped TransportState = iota // 已停止
+	Playing                       // 播放中
+	Paused                        // 已暂停
+)
+
+func (s TransportState) String() string {
+	switch s {
+	case Stopped:
+		return "stopped"
+	case Playing:
+		return "playing"
+	case Paused:
+		return "paused"
+	default:
+		return fmt.Sprintf("state_%d", int(s))
+	}
+}
+
+// 正在发声的音符
+type activeNote struct {
+	channel uint8
+	note    uint8
+}
+
+// 走带控制：在一个时间线上播放事件，支持暂停、继续、定位和循环。
+// Play 在调用方的goroutine中运行，其他方法可以从任意goroutine调用。
+type Transport struct {
+	mu sync.Mutex
+
+	sender  io.MIDIEventSender
+	clock   Clock
+	tempo   *TempoMap
+	options Options
+	batches []Batch
+	length  float64 // 最后一批事件所在的拍
+
+	state    TransportState
+	position float64       // 暂停时的位置（拍）
+	origin   time.Duration // 播放时第0拍对应的时钟时间
+	next     int           // 下一个待排队的批次
+	queue    []scheduledBatch
+
+	looping   bool
+	loopStart float64
+	loopEnd   float64
+
+	active   map[activeNote]int // 发声中的音符及重叠次数
+	channels []uint8            // 事件用到的通道
+	stats    Stats
+
+	wake chan struct{} // 状态变化时唤醒播放循环
+}
+
+func NewTransport(sender io.MIDIEventSender, tempo *TempoMap, events []io.Event, options Options) *Transport {
+	if tempo == nil {
+		tempo = NewTempoMap(120)
+	}
+	if options.MaxSleep <= 0 {
+		options.MaxSleep = DefaultOptions().MaxSleep
+	}
+
+	batches := BatchEvents(events)
+	length := 0.0
+	if len(batches) > 0 {
+		length = batches[len(batches)-1].Beat
+	}
+
+	used := map[uint8]bool{}
+	channels := []uint8{}
+	for _, event := range events {
+		if !used[event.Channel] {
+			used[event.Channel] = true
+			channels = append(channels, event.Channel)
+		}
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i] < channels[j] })
+
+	return &Transport{
+		sender:   sender,
+		clock:    NewMonotonicClock(),
+		tempo:    tempo,
+		options:  options,
+		batches:  batches,
+		length:   length,
+		active:   make(map[activeNote]int),
+		channels: channels,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// 替换时钟源（需在 Play 之前调用）
+func (t *Transport) SetClock(clock Clock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clock = clock
+}
+
+// 从当前位置开始播放，直到结束、Stop 或 ctx 被取消。
+// 无论以何种方式退出（包括发送失败和panic），都会释放发声中的音符并复位用到的通道。
+func (t *Transport) Play(ctx context.Context) (err error) {
+	t.mu.Lock()
+	if t.state != Stopped {
+		t.mu.Unlock()
+		return fmt.Errorf("走带已在运行")
+	}
+	t.state = Playing
+	t.restartLocked()
+	t.mu.Unlock()
+
+	defer func() {
+		// run 不会在持有锁时panic退出，两种情况都加锁释放音符并回到停止状态
+		r := recover()
+
+		t.mu.Lock()
+		releaseErr := t.releaseAllLocked()
+		t.state = Stopped
+		t.mu.Unlock()
+
+		if r != nil {
+			panic(r)
+		}
+		if err == nil {
+			err = releaseErr
+		}
+	}()
+
+	return t.run(ctx)
+}
+
+// 播放循环
+func (t *Transport) run(ctx context.Context) error {
+	for {
+		remaining, paused, done, err := t.step(ctx)
+		if done {
+			return err
+		}
+
+		if paused {
+			select {
+			case <-t.wake:
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		t.wait(ctx, remaining)
+	}
+}
+
+// 播放循环的一步：发送到期的批次，返回距下一次处理的时间；
+// 暂停时 paused 为 true，播放结束时 done 为 true。锁在返回时 (包括panic) 释放
+func (t *Transport) step(ctx context.Context) (remaining time.Duration, paused bool, done bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		t.stopLocked()
+		return 0, false, true, err
+	}
+
+	switch t.state {
+	case Stopped:
+		return 0, false, true, nil
+	case Paused:
+		return 0, true, false, nil
+	}
+
+	now := t.clock.Now()
+
+	// 到达循环终点时回到起点
+	if t.looping && t.beatAtLocked(now) >= t.loopEnd {
+		if err := t.seekLocked(t.loopStart); err != nil {
+			t.stopLocked()
+			return 0, false, true, err
+		}
+	}
+
+	// 前瞻：把即将到期的批次换算为发送时间放入队列
+	for t.next < len(t.batches) {
+		batch := t.batches[t.next]
+		if t.looping && batch.Beat >= t.loopEnd {
+			break
+		}
+		deadline := t.deadlineLocked(batch.Beat)
+		if deadline > now+t.options.Lookahead {
+			break
+		}
+		t.queue = append(t.queue, scheduledBatch{Batch: batch, deadline: deadline})
+		t.next++
+	}
+
+	// 发送所有已到期的批次
+	for len(t.queue) > 0 && t.queue[0].deadline <= now {
+		batch := t.queue[0]
+		t.queue = t.queue[1:]
+
+		if err := t.dispatchLocked(batch.Batch); err != nil {
+			t.stopLocked()
+			return 0, false, true, err
+		}
+		t.record(now-batch.deadline, len(batch.Events))
+	}
+
+	// 计算下一次需要处理的时间
+	var wakeAt time.Duration
+	switch {
+	case len(t.queue) > 0:
+		wakeAt = t.queue[0].deadline
+	case t.looping:
+		wakeAt = t.deadlineLocked(t.loopEnd)
+		if t.next < len(t.batches) && t.batches[t.next].Beat < t.loopEnd {
+			wakeAt = t.deadlineLocked(t.batches[t.next].Beat) - t.options.Lookahead
+		}
+	case t.next < len(t.batches):
+		wakeAt = t.deadlineLocked(t.batches[t.next].Beat) - t.options.Lookahead
+	default:
+		// 全部发送完毕
+		t.stopLocked()
+		return 0, false, true, nil
+	}
+
+	return wakeAt - t.clock.Now(), false, false, nil
+}
+
+// 暂停播放，释放所有发声中的音符
+func (t *Transport) Pause() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state != Playing {
+		return nil
+	}
+
+	t.position = t.beatAtLocked(t.clock.Now())
+	t.state = Paused
+	t.queue = nil
+	t.notify()
+	return t.silenceLocked()
+}
+
+// 从暂停位置继续播放
+func (t *Transport) Resume() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state != Paused {
+		return nil
+	}
+
+	t.state = Playing
+	t.restartLocked()
+	t.notify()
+	return nil
+}
+
+// 暂停/继续切换
+func (t *Transport) TogglePause() error {
+	if t.State() == Paused {
+		return t.Resume()
+	}
+	return t.Pause()
+}
+
+// 定位到指定拍，释放发声中的音符并重新发送该位置的音色和音量
+func (t *Transport) Seek(beat float64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := t.seekLocked(beat)
+	t.notify()
+	return err
+}
+
+// 设置循环区间 [startBeat, endBeat)
+func (t *Transport) SetLoop(startBeat float64, endBeat float64) error {
+	if startBeat < 0 || endBeat <= startBeat {
+		return fmt.Errorf("无效的循环区间: %.2f - %.2f", startBeat, endBeat)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.looping = true
+	t.loopStart = startBeat
+	t.loopEnd = endBeat
+
+	// 已排队但超出循环终点的批次放回待排队状态
+	for len(t.queue) > 0 && t.queue[len(t.queue)-1].Beat >= endBeat {
+		t.queue = t.queue[:len(t.queue)-1]
+		t.next--
+	}
+
+	t.notify()
+	return nil
+}
+
+// 取消循环
+func (t *Transport) ClearLoop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.looping = false
+	t.notify()
+}
+
+// 循环区间，未设置时 ok 为 false
+func (t *Transport) Loop() (startBeat float64, endBeat float64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.loopStart, t.loopEnd, t.looping
+}
+
+// 停止播放并释放所有发声中的音符
+func (t *Transport) Stop() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state == Stopped {
+		return nil
+	}
+	err := t.stopLocked()
+	t.notify()
+	return err
+}
+
+func (t *Transport) State() TransportState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+// 当前播放位置（拍）
+func (t *Transport) Position() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state == Playing {
+		return t.beatAtLocked(t.clock.Now())
+	}
+	return t.position
+}
+
+// 最后一个事件所在的拍
+func (t *Transport) Length() float64 {
+	return t.length
+}
+
+func (t *Transport) TempoMap() *TempoMap {
+	return t.tempo
+}
+
+func (t *Transport) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// 以当前位置为起点重新建立时间线，丢弃已排队的批次
+func (t *Transport) restartLocked() {
+	t.origin = t.clock.Now() - t.tempo.BeatToTime(t.position)
+	t.next = t.batchIndexAt(t.position)
+	t.queue = nil
+}
+
+// 第一个不早于该拍的批次
+func (t *Transport) batchIndexAt(beat float64) int {
+	return sort.Search(len(t.batches), func(i int) bool { return t.batches[i].Beat >= beat })
+}
+
+func (t *Transport) seekLocked(beat float64) error {
+	if beat < 0 {
+		beat = 0
+	}
+
+	err := t.silenceLocked()
+
+	t.position = beat
+	t.next = t.batchIndexAt(beat)
+	if t.state == Playing {
+		t.restartLocked()
+	}
+
+	if resendErr := t.resendStateLocked(); err == nil {
+		err = resendErr
+	}
+	return err
+}
+
+func (t *Transport) stopLocked() error {
+	if t.state == Playing {
+		t.position = t.beatAtLocked(t.clock.Now())
+	}
+	t.state = Stopped
+	t.queue = nil
+	return t.silenceLocked()
+}
+
+// 重新发送定位点之前最后生效的音色和控制器
+func (t *Transport) resendStateLocked() error {
+	programs := map[uint8]uint8{}
+	controls := map[[2]uint8]uint8{}
+
+	for _, batch := range t.batches[:t.next] {
+		for _, event := range batch.Events {
+			switch event.Type {
+			case io.PROGRAM_CHANGE_EVENT:
+				programs[event.Channel] = event.Data1
+			case io.CONTROL_CHANGE_EVENT:
+				controls[[2]uint8{event.Channel, event.Data1}] = event.Data2
+			}
+		}
+	}
+
+	for channel, program := range programs {
+		if err := t.sender.SendProgramChange(channel, program); err != nil {
+			return err
+		}
+	}
+	for key, value := range controls {
+		if err := t.sender.SendControlChange(key[0], key[1], value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 释放发声中的音符，并对用到的通道发送 All Notes Off 和 Reset All Controllers
+func (t *Transport) releaseAllLocked() error {
+	err := t.silenceLocked()
+	if resetErr := io.ResetChannels(t.sender, t.channels); err == nil {
+		err = resetErr
+	}
+	return err
+}
+
+// 为所有发声中的音符发送NOTE_OFF
+func (t *Transport) silenceLocked() error {
+	var firstErr error
+	for note, count := range t.active {
+		for i := 0; i < count; i++ {
+			if err := t.sender.SendNoteOff(note.channel, note.note, 0); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	t.active = make(map[activeNote]int)
+	return firstErr
+}
+
+func (t *Transport) dispatchLocked(batch Batch) error {
+	for _, event := range batch.Events {
+		if err := io.SendEvent(t.sender, event); err != nil {
+			return fmt.Errorf("第%.3f拍发送事件失败: %v", batch.Beat, err)
+		}
+
+		note := activeNote{channel: event.Channel, note: event.Data1}
+		switch event.Type {
+		case io.NOTE_ON_EVENT:
+			if event.Data2 > 0 {
+				t.active[note]++
+			} else if t.active[note] > 0 {
+				t.active[note]--
+			}
+		case io.NOTE_OFF_EVENT:
+			if t.active[note] > 0 {
+				t.active[note]--
+			}
+		}
+		if t.active[note] == 0 {
+			delete(t.active, note)
+		}
+	}
+	return nil
+}
+
+func (t *Transport) record(lateness time.Duration, events int) {
+	t.stats.Batches++
+	t.stats.Events += events
+	t.stats.TotalLateness += lateness
+	if lateness > t.stats.MaxLateness {
+		t.stats.MaxLateness = lateness
+	}
+}
+
+func (t *Transport) deadlineLocked(beat float64) time.Duration {
+	return t.origin + t.tempo.BeatToTime(beat)
+}
+
+func (t *Transport) beatAtLocked(now time.Duration) float64 {
+	return t.tempo.TimeToBeat(now - t.origin)
+}
+
+func (t *Transport) notify() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 距离较远时休眠（可被状态变化或取消打断），临近发送时间时让出CPU
+func (t *Transport) wait(ctx context.Context, remaining time.Duration) {
+	if remaining <= 0 {
+		return
+	}
+
+	if remaining <= t.options.SpinThreshold {
+		runtime.Gosched()
+		return
+	}
+
+	sleep := remaining - t.options.SpinThreshold
+	if sleep > t.options.MaxSleep {
+		sleep = t.options.MaxSleep
+	}
+
+	timer := time.NewTimer(sleep)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-t.wake:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,351 @@
+package sequencer
+
+import (
+	"catRock/pkg/io"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 由测试手动推进的时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Duration
+}
+
+func (c *fakeClock) Now() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(now time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// 记录收到的MIDI消息，如 "on 0 60 100"
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []string
+	failOn   string // 消息以此开头时返回错误
+}
+
+func (s *recordingSender) send(format string, args ...interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message := fmt.Sprintf(format, args...)
+	if s.failOn != "" && strings.HasPrefix(message, s.failOn) {
+		return errors.New("设备已断开")
+	}
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func (s *recordingSender) SendNoteOn(channel uint8, note uint8, velocity uint8) error {
+	return s.send("on %d %d %d", channel, note, velocity)
+}
+
+func (s *recordingSender) SendNoteOff(channel uint8, note uint8, velocity uint8) error {
+	return s.send("off %d %d", channel, note)
+}
+
+func (s *recordingSender) SendProgramChange(channel uint8, program uint8) error {
+	return s.send("pc %d %d", channel, program)
+}
+
+func (s *recordingSender) SendControlChange(channel uint8, controller uint8, value uint8) error {
+	return s.send("cc %d %d %d", channel, controller, value)
+}
+
+// 消息出现的次数
+func (s *recordingSender) count(message string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, m := range s.messages {
+		if m == message {
+			n++
+		}
+	}
+	return n
+}
+
+// 60 BPM（每拍1秒）下的两个音符：第0拍C、第1拍D，第2拍结束
+func newTestTransport() (*Transport, *recordingSender, *fakeClock) {
+	events := []io.Event{
+		{Time: 0, Type: io.PROGRAM_CHANGE_EVENT, Channel: 0, Data1: 5},
+		{Time: 0, Type: io.NOTE_ON_EVENT, Channel: 0, Data1: 60, Data2: 100},
+		{Time: 1, Type: io.NOTE_OFF_EVENT, Channel: 0, Data1: 60},
+		{Time: 1, Type: io.NOTE_ON_EVENT, Channel: 0, Data1: 62, Data2: 100},
+		{Time: 2, Type: io.NOTE_OFF_EVENT, Channel: 0, Data1: 62},
+	}
+
+	sender := &recordingSender{}
+	clock := &fakeClock{}
+	transport := NewTransport(sender, NewTempoMap(60), events, Options{MaxSleep: time.Millisecond})
+	transport.SetClock(clock)
+	return transport, sender, clock
+}
+
+// 在新的goroutine中播放，返回 Play 的结果
+func startPlay(ctx context.Context, transport *Transport) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- transport.Play(ctx) }()
+	return done
+}
+
+// 等待条件成立，超时则失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitDone(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(2 * time.Second):
+		t.Fatal("Play 没有返回")
+		return nil
+	}
+}
+
+func TestTransportPlaysToEnd(t *testing.T) {
+	transport, sender, clock := newTestTransport()
+	done := startPlay(context.Background(), transport)
+
+	waitFor(t, "第0拍的音符", func() bool { return sender.count("on 0 60 100") == 1 })
+	if got := transport.State(); got != Playing {
+		t.Fatalf("状态 = %v，期望 playing", got)
+	}
+	if sender.count("on 0 62 100") != 0 {
+		t.Fatal("第1拍的音符提前发送")
+	}
+
+	clock.Set(time.Second)
+	waitFor(t, "第1拍的音符", func() bool { return sender.count("on 0 62 100") == 1 })
+
+	clock.Set(2 * time.Second)
+	if err := waitDone(t, done); err != nil {
+		t.Fatalf("Play 返回错误: %v", err)
+	}
+	if got := transport.State(); got != Stopped {
+		t.Errorf("结束后状态 = %v，期望 stopped", got)
+	}
+	if sender.count("off 0 62") != 1 {
+		t.Errorf("最后的音符没有释放: %v", sender.messages)
+	}
+	if sender.count(fmt.Sprintf("cc 0 %d 0", io.CC_ALL_NOTES_OFF)) != 1 {
+		t.Errorf("结束时没有复位通道: %v", sender.messages)
+	}
+
+	// 停止后可以再次播放
+	clock.Set(0)
+	transport.Seek(0)
+	done = startPlay(context.Background(), transport)
+	waitFor(t, "再次播放", func() bool { return sender.count("on 0 60 100") == 2 })
+	transport.Stop()
+	waitDone(t, done)
+}
+
+func TestTransportPauseResume(t *testing.T) {
+	transport, sender, clock := newTestTransport()
+	done := startPlay(context.Background(), transport)
+	waitFor(t, "第0拍的音符", func() bool { return sender.count("on 0 60 100") == 1 })
+
+	clock.Set(500 * time.Millisecond)
+	if err := transport.TogglePause(); err != nil {
+		t.Fatal(err)
+	}
+	if got := transport.State(); got != Paused {
+		t.Fatalf("状态 = %v，期望 paused", got)
+	}
+	if sender.count("off 0 60") != 1 {
+		t.Errorf("暂停时没有释放发声中的音符: %v", sender.messages)
+	}
+
+	// 暂停期间时钟继续走，位置不变，也不发送事件
+	clock.Set(10 * time.Second)
+	time.Sleep(5 * time.Millisecond)
+	if got := transport.Position(); got != 0.5 {
+		t.Errorf("暂停时位置 = %v，期望 0.5", got)
+	}
+	if sender.count("on 0 62 100") != 0 {
+		t.Fatal("暂停期间发送了音符")
+	}
+
+	if err := transport.TogglePause(); err != nil {
+		t.Fatal(err)
+	}
+	if got := transport.State(); got != Playing {
+		t.Fatalf("状态 = %v，期望 playing", got)
+	}
+	if got := transport.Position(); got != 0.5 {
+		t.Errorf("继续时位置 = %v，期望 0.5", got)
+	}
+
+	clock.Set(10*time.Second + 499*time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if sender.count("on 0 62 100") != 0 {
+		t.Fatal("第1拍的音符提前发送")
+	}
+	clock.Set(10*time.Second + 500*time.Millisecond)
+	waitFor(t, "继续后第1拍的音符", func() bool { return sender.count("on 0 62 100") == 1 })
+
+	if err := transport.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitDone(t, done); err != nil {
+		t.Fatalf("Stop 后 Play 返回错误: %v", err)
+	}
+	if sender.count("off 0 62") != 1 {
+		t.Errorf("停止时没有释放发声中的音符: %v", sender.messages)
+	}
+}
+
+func TestTransportSeek(t *testing.T) {
+	transport, sender, clock := newTestTransport()
+
+	// 停止时定位，重新发送定位点之前的音色
+	if err := transport.Seek(1.5); err != nil {
+		t.Fatal(err)
+	}
+	if got := transport.Position(); got != 1.5 {
+		t.Errorf("位置 = %v，期望 1.5", got)
+	}
+	if sender.count("pc 0 5") != 1 {
+		t.Errorf("定位后没有重新发送音色: %v", sender.messages)
+	}
+
+	// 从定位点开始播放：第1拍的音符已经过去，第2拍在0.5秒后
+	done := startPlay(context.Background(), transport)
+	time.Sleep(5 * time.Millisecond)
+	if sender.count("on 0 60 100")+sender.count("on 0 62 100") != 0 {
+		t.Errorf("定位点之前的音符被发送: %v", sender.messages)
+	}
+	clock.Set(500 * time.Millisecond)
+	if err := waitDone(t, done); err != nil {
+		t.Fatal(err)
+	}
+
+	// 负数定位到开头
+	transport.Seek(-3)
+	if got := transport.Position(); got != 0 {
+		t.Errorf("位置 = %v，期望 0", got)
+	}
+}
+
+func TestTransportLoop(t *testing.T) {
+	transport, sender, clock := newTestTransport()
+
+	invalid := [][2]float64{{-1, 1}, {1, 1}, {2, 1}}
+	for _, loop := range invalid {
+		if err := transport.SetLoop(loop[0], loop[1]); err == nil {
+			t.Errorf("SetLoop(%v, %v) 没有返回错误", loop[0], loop[1])
+		}
+	}
+	if _, _, ok := transport.Loop(); ok {
+		t.Fatal("无效的区间被设置为循环")
+	}
+
+	if err := transport.SetLoop(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	done := startPlay(context.Background(), transport)
+	waitFor(t, "第0拍的音符", func() bool { return sender.count("on 0 60 100") == 1 })
+
+	// 到达循环终点回到第0拍，不发送循环区间之外的音符
+	clock.Set(time.Second)
+	waitFor(t, "循环回到起点", func() bool { return sender.count("on 0 60 100") == 2 })
+	if sender.count("on 0 62 100") != 0 {
+		t.Errorf("发送了循环区间之外的音符: %v", sender.messages)
+	}
+	if sender.count("off 0 60") != 1 {
+		t.Errorf("回到起点时没有释放音符: %v", sender.messages)
+	}
+
+	// 取消循环后继续播放到结束
+	transport.ClearLoop()
+	clock.Set(2 * time.Second)
+	waitFor(t, "取消循环后的音符", func() bool { return sender.count("on 0 62 100") == 1 })
+	clock.Set(3 * time.Second)
+	if err := waitDone(t, done); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransportPlayWhileRunning(t *testing.T) {
+	transport, sender, _ := newTestTransport()
+	done := startPlay(context.Background(), transport)
+	waitFor(t, "开始播放", func() bool { return sender.count("on 0 60 100") == 1 })
+
+	if err := transport.Play(context.Background()); err == nil {
+		t.Error("重复调用 Play 没有返回错误")
+	}
+
+	transport.Stop()
+	waitDone(t, done)
+}
+
+func TestTransportExit(t *testing.T) {
+	t.Run("取消", func(t *testing.T) {
+		transport, sender, _ := newTestTransport()
+		ctx, cancel := context.WithCancel(context.Background())
+		done := startPlay(ctx, transport)
+		waitFor(t, "开始播放", func() bool { return sender.count("on 0 60 100") == 1 })
+
+		cancel()
+		if err := waitDone(t, done); !errors.Is(err, context.Canceled) {
+			t.Errorf("Play 返回 %v，期望 context.Canceled", err)
+		}
+		if got := transport.State(); got != Stopped {
+			t.Errorf("状态 = %v，期望 stopped", got)
+		}
+		if sender.count("off 0 60") != 1 {
+			t.Errorf("取消时没有释放音符: %v", sender.messages)
+		}
+	})
+
+	t.Run("发送失败", func(t *testing.T) {
+		transport, sender, clock := newTestTransport()
+		sender.failOn = "on 0 62"
+		done := startPlay(context.Background(), transport)
+		waitFor(t, "开始播放", func() bool { return sender.count("on 0 60 100") == 1 })
+
+		clock.Set(time.Second)
+		err := waitDone(t, done)
+		if err == nil || !strings.Contains(err.Error(), "设备已断开") {
+			t.Errorf("Play 返回 %v，期望发送错误", err)
+		}
+		if got := transport.State(); got != Stopped {
+			t.Errorf("状态 = %v，期望 stopped", got)
+		}
+	})
+
+	t.Run("停止后的操作无效", func(t *testing.T) {
+		transport, _, _ := newTestTransport()
+		for _, op := range []func() error{transport.Stop, transport.Pause, transport.Resume} {
+			if err := op(); err != nil {
+				t.Error(err)
+			}
+		}
+		if got := transport.State(); got != Stopped {
+			t.Errorf("状态 = %v，期望 stopped", got)
+		}
+	})
+}